@@ -0,0 +1,107 @@
+// TradeTGBot/cmd/backtest.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"TradeTGBot/internal/analyzer"
+	"TradeTGBot/internal/backtest"
+	"TradeTGBot/internal/config"
+	"TradeTGBot/internal/db"
+	"TradeTGBot/pkg/bot"
+)
+
+const backtestUsage = `Использование:
+  backtest [флаги] alert ПРАВИЛО     - правило оповещения, например: alert SBER > 320 AND GAZP < 150
+  backtest [флаги] spike ТИКЕР [ПОРОГ] [ДЕТЕКТОР] - детектор резких изменений анализатора
+
+Флаги:
+`
+
+// runBacktest выполняет подкоманду backtest: прогоняет правило оповещения или детектор
+// анализатора по истории из БД и печатает отчёт. Возвращает код завершения процесса.
+func runBacktest(args []string) int {
+	fs := flag.NewFlagSet("backtest", flag.ContinueOnError)
+	days := fs.Int("days", 30, "сколько последних дней истории прогнать")
+	fromFlag := fs.String("from", "", "начало периода (ГГГГ-ММ-ДД), вместо -days")
+	toFlag := fs.String("to", "", "конец периода (ГГГГ-ММ-ДД), по умолчанию - сейчас")
+	horizonsFlag := fs.String("horizons", "15m,1h,1d", "горизонты доходности после сигнала")
+	rows := fs.Int("rows", 50, "сколько последних срабатываний вывести")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), backtestUsage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return 2
+	}
+
+	to := time.Now()
+	if *toFlag != "" {
+		t, err := time.ParseInLocation("2006-01-02", *toFlag, time.Local)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Неверная дата -to: %v\n", err)
+			return 2
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -*days)
+	if *fromFlag != "" {
+		t, err := time.ParseInLocation("2006-01-02", *fromFlag, time.Local)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Неверная дата -from: %v\n", err)
+			return 2
+		}
+		from = t
+	}
+	horizons, err := backtest.ParseHorizons(*horizonsFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Неверные горизонты: %v\n", err)
+		return 2
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Не удалось загрузить конфигурацию: %v\n", err)
+		return 1
+	}
+	if err := db.InitDB(cfg.DB); err != nil {
+		fmt.Fprintf(os.Stderr, "Не удалось подключиться к базе данных: %v\n", err)
+		return 1
+	}
+	defer db.CloseDB()
+
+	var result backtest.Result
+	switch kind, rest := fs.Arg(0), fs.Args()[1:]; kind {
+	case "alert":
+		result, err = bot.BacktestRule(strings.Join(rest, " "), from, to, horizons)
+	case "spike":
+		var settings config.AnalyzerTicker
+		if settings, err = bot.ParseSpikeRule(rest); err != nil {
+			break
+		}
+		// Для тикера из ANALYZER_TICKERS берём его окно и период опроса.
+		for _, t := range cfg.Analyzer.Tickers {
+			if t.Ticker == settings.Ticker {
+				settings.Interval, settings.AveragePeriod = t.Interval, t.AveragePeriod
+			}
+		}
+		result, err = analyzer.Backtest(settings, from, to, horizons)
+	default:
+		fs.Usage()
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка бэктеста: %v\n", err)
+		return 1
+	}
+	fmt.Print(result.Report(time.Local, *rows))
+	return 0
+}
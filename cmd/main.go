@@ -7,17 +7,26 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // Часовые пояса для тихих часов не зависят от системной tzdata
 
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-
+	"TradeTGBot/internal/aggregator"
 	"TradeTGBot/internal/analyzer"
 	"TradeTGBot/internal/config"
 	"TradeTGBot/internal/db"
+	"TradeTGBot/internal/notify"
+	"TradeTGBot/internal/pricebus"
+	"TradeTGBot/internal/reports"
+	"TradeTGBot/internal/repository"
 	"TradeTGBot/pkg/bot"
 	"TradeTGBot/pkg/stocks"
 )
 
 func main() {
+	// Подкоманда backtest прогоняет правила по истории из БД без запуска бота.
+	if len(os.Args) > 1 && os.Args[1] == "backtest" {
+		os.Exit(runBacktest(os.Args[2:]))
+	}
+
 	// 1. Загрузка конфигурации приложения
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -32,37 +41,48 @@ func main() {
 	}
 	defer db.CloseDB() // Гарантированное закрытие соединения с БД
 
+	err = db.Migrate()
+	if err != nil {
+		log.Fatalf("Критическая ошибка: не удалось подготовить схему БД: %v", err)
+	}
+
 	// 3. Инициализация парсера (colly.Collector)
 	collector := stocks.InitCollector()
 	log.Println("Инициализация парсера (colly.Collector)...")
 	_ = collector.Visit("https://ru.investing.com") // Первый визит для инициализации куки
 	time.Sleep(2 * time.Second)                     // Дать время для установки куки
 
-	// Инициализация экземпляра бота для анализатора
-	// ОБРАБАТЫВАЕМ ВОЗВРАЩАЕМОЕ ЗНАЧЕНИЕ tgbotapi.NewBotAPI
-	analysisBot, err := tgbotapi.NewBotAPI(cfg.BotToken)
-	if err != nil {
-		log.Fatalf("Ошибка инициализации BotAPI для анализатора: %v", err)
-	}
+	// Шина котировок: поллер запрашивает каждую цену один раз и раздаёт её подписчикам
+	// (хранилище, оповещения, анализатор).
+	bus := pricebus.New()
+	poller := pricebus.NewPoller(bus, collector)
+	go pricebus.StoreTicks(bus.Subscribe("storage", 1024, pricebus.Block), repository.SaveStockPrice)
+	go poller.Run()
 
-	// 4. Инициализация и запуск сервиса анализа цен (для LKOH)
-	// ВАЖНО: Замените YOUR_CHAT_ID на реальный ID чата, куда бот должен отправлять уведомления.
-	// Это может быть ваш личный ChatID.
-	lKohAnalyzer := analyzer.NewPriceAnalyzer(
-		analysisBot, // <-- Теперь передаем уже созданный экземпляр
-		collector,
-		int64(964949247), // <--- ЗАМЕНИТЕ НА ВАШ АЙДИ ЧАТА!
-		10*time.Second,
-		5*time.Minute,
-		0.42,
-	)
-	lKohAnalyzer.StartAnalysis() // Запускаем горутину анализа цен
+	// 4. Инициализация и запуск сервиса анализа цен. Тикеры, их параметры и чат для
+	// уведомлений задаются в ANALYZER_TICKERS и ANALYZER_CHAT_ID. Уведомления доставляются
+	// через outbox бота и поэтому учитывают тихие часы чата.
+	priceAnalyzer := analyzer.NewPriceAnalyzer(poller, cfg.Analyzer)
+	priceAnalyzer.StartAnalysis() // Запускаем горутину анализа цен
+
+	// Отчёт о гэпах открытия и уведомления подписчикам (GAP_OPEN, GAP_CLOSE, GAP_REPORT_DELAY).
+	analyzer.NewGapReporter(poller, cfg.Gaps).Start()
+
+	// Свечи 1m/5m/1h/1d из тиков: графики и индикаторы читают их вместо сырых тиков.
+	go schedule(time.Minute, func(time.Time) { aggregator.Run() })
+
+	// Ежедневные и еженедельные дайджесты по расписанию чатов (/digest).
+	go schedule(time.Minute, reports.Run)
 
 	// 5. Инициализация и запуск Telegram-бота
-	botService, err := bot.NewBotService(cfg.BotToken, collector) // Передаем collector боту
+	botService, err := bot.NewBotService(cfg.BotToken, poller) // Бот получает котировки через общий поллер
 	if err != nil {
 		log.Fatalf("Ошибка инициализации Telegram-бота: %v", err)
 	}
+	if cfg.SMTP.Host != "" {
+		botService.SetNotifier(repository.ChannelEmail, notify.NewEmail(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.SMTP.From))
+		log.Printf("Email-уведомления включены (SMTP %s:%s).", cfg.SMTP.Host, cfg.SMTP.Port)
+	}
 	go botService.StartPolling() // Запускаем опрос Telegram API в отдельной горутине
 
 	log.Println("Приложение запущено. Ожидание сигналов завершения (Ctrl+C)...")
@@ -74,3 +94,13 @@ func main() {
 
 	log.Println("Получен сигнал завершения. Завершение работы приложения...")
 }
+
+// schedule вызывает job в начале каждого периода period (например, каждой минуты)
+// с моментом начала периода. Блокирует вызывающую горутину.
+func schedule(period time.Duration, job func(now time.Time)) {
+	for {
+		next := time.Now().Truncate(period).Add(period)
+		time.Sleep(time.Until(next))
+		job(next)
+	}
+}
@@ -0,0 +1,30 @@
+// TradeTGBot/internal/aggregator/aggregator.go
+package aggregator
+
+import (
+	"TradeTGBot/internal/repository"
+	"log"
+)
+
+const batchSize = 50000 // Тиков за одну транзакцию агрегации
+
+// Run сворачивает накопившиеся тики в свечи 1m/5m/1h/1d. Вызывается планировщиком
+// раз в минуту; после простоя (или при первом запуске на старой базе) догоняет
+// историю пачками по batchSize тиков.
+func Run() {
+	total := 0
+	for {
+		n, err := repository.AggregateCandles(batchSize)
+		if err != nil {
+			log.Printf("Ошибка агрегации свечей: %v", err)
+			return
+		}
+		total += n
+		if n < batchSize {
+			break
+		}
+	}
+	if total >= batchSize {
+		log.Printf("Свечи обновлены, обработано тиков: %d", total)
+	}
+}
@@ -0,0 +1,110 @@
+// TradeTGBot/internal/alertexpr/eval.go
+package alertexpr
+
+import (
+	"fmt"
+	"math"
+)
+
+// Quotes - текущие цены по тикерам, используемые при вычислении условия.
+type Quotes map[string]float64
+
+// Eval вычисляет условие на переданных котировках.
+// Возвращает ошибку, если для какого-либо тикера нет цены или встречено деление на ноль.
+func (e *Expr) Eval(quotes Quotes) (bool, error) {
+	v, err := eval(e.root, quotes)
+	if err != nil {
+		return false, err
+	}
+	return v != 0, nil
+}
+
+// eval вычисляет значение узла; логические значения представлены как 1 и 0.
+func eval(n node, quotes Quotes) (float64, error) {
+	switch n := n.(type) {
+	case *numberNode:
+		return n.value, nil
+	case *tickerNode:
+		price, ok := quotes[n.tok.text]
+		if !ok {
+			return 0, fmt.Errorf("нет котировки для %s", n.tok.text)
+		}
+		return price, nil
+	case *unaryNode:
+		x, err := eval(n.x, quotes)
+		if err != nil {
+			return 0, err
+		}
+		if n.tok.kind == tokNot {
+			return boolValue(x == 0), nil
+		}
+		return -x, nil
+	case *binaryNode:
+		// Логические операции вычисляются лениво, чтобы не требовать лишних котировок.
+		switch n.tok.kind {
+		case tokAnd, tokOr:
+			l, err := eval(n.l, quotes)
+			if err != nil {
+				return 0, err
+			}
+			if n.tok.kind == tokAnd && l == 0 {
+				return 0, nil
+			}
+			if n.tok.kind == tokOr && l != 0 {
+				return 1, nil
+			}
+			r, err := eval(n.r, quotes)
+			if err != nil {
+				return 0, err
+			}
+			return boolValue(r != 0), nil
+		}
+
+		l, err := eval(n.l, quotes)
+		if err != nil {
+			return 0, err
+		}
+		r, err := eval(n.r, quotes)
+		if err != nil {
+			return 0, err
+		}
+		switch n.tok.kind {
+		case tokPlus:
+			return l + r, nil
+		case tokMinus:
+			return l - r, nil
+		case tokMul:
+			return l * r, nil
+		case tokDiv:
+			if r == 0 {
+				return 0, fmt.Errorf("деление на ноль в позиции %d", n.tok.pos+1)
+			}
+			return l / r, nil
+		case tokLT:
+			return boolValue(l < r), nil
+		case tokLE:
+			return boolValue(l <= r), nil
+		case tokGT:
+			return boolValue(l > r), nil
+		case tokGE:
+			return boolValue(l >= r), nil
+		case tokEQ:
+			return boolValue(almostEqual(l, r)), nil
+		case tokNE:
+			return boolValue(!almostEqual(l, r)), nil
+		}
+	}
+	return 0, fmt.Errorf("неизвестный узел выражения")
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// almostEqual сравнивает цены с точностью до копейки.
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 0.005
+}
@@ -0,0 +1,136 @@
+// TradeTGBot/internal/alertexpr/lexer.go
+package alertexpr
+
+import (
+	"strings"
+	"unicode"
+)
+
+// tokenKind - тип лексемы выражения условия.
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokTicker
+	tokAnd
+	tokOr
+	tokNot
+	tokPlus
+	tokMinus
+	tokMul
+	tokDiv
+	tokLParen
+	tokRParen
+	tokLT
+	tokLE
+	tokGT
+	tokGE
+	tokEQ
+	tokNE
+)
+
+// token - лексема с позицией (в символах, с нуля) в исходной строке.
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// keywords - ключевые слова логических операций (регистр не важен).
+var keywords = map[string]tokenKind{
+	"AND": tokAnd,
+	"OR":  tokOr,
+	"NOT": tokNot,
+	"И":   tokAnd,
+	"ИЛИ": tokOr,
+	"НЕ":  tokNot,
+}
+
+// lex разбивает строку условия на лексемы.
+func lex(src string) ([]token, error) {
+	runes := []rune(src)
+	var tokens []token
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == ',') {
+				i++
+			}
+			text := strings.ReplaceAll(string(runes[start:i]), ",", ".")
+			tokens = append(tokens, token{kind: tokNumber, text: text, pos: start})
+		case unicode.IsLetter(r):
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			word := strings.ToUpper(string(runes[start:i]))
+			if kind, ok := keywords[word]; ok {
+				tokens = append(tokens, token{kind: kind, text: word, pos: start})
+			} else {
+				tokens = append(tokens, token{kind: tokTicker, text: word, pos: start})
+			}
+		default:
+			kind, width := operator(runes[i:])
+			if width == 0 {
+				return nil, &Error{Pos: i, Token: string(r), Msg: "недопустимый символ"}
+			}
+			tokens = append(tokens, token{kind: kind, text: string(runes[i : i+width]), pos: i})
+			i += width
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokEOF, pos: len(runes)})
+	return tokens, nil
+}
+
+// operator распознаёт оператор в начале строки и возвращает его тип и длину.
+// Нулевая длина означает, что оператор не распознан.
+func operator(rs []rune) (tokenKind, int) {
+	two := ""
+	if len(rs) >= 2 {
+		two = string(rs[:2])
+	}
+	switch two {
+	case "<=":
+		return tokLE, 2
+	case ">=":
+		return tokGE, 2
+	case "==":
+		return tokEQ, 2
+	case "!=", "<>":
+		return tokNE, 2
+	case "&&":
+		return tokAnd, 2
+	case "||":
+		return tokOr, 2
+	}
+	switch rs[0] {
+	case '<':
+		return tokLT, 1
+	case '>':
+		return tokGT, 1
+	case '=':
+		return tokEQ, 1
+	case '!':
+		return tokNot, 1
+	case '+':
+		return tokPlus, 1
+	case '-':
+		return tokMinus, 1
+	case '*':
+		return tokMul, 1
+	case '/':
+		return tokDiv, 1
+	case '(':
+		return tokLParen, 1
+	case ')':
+		return tokRParen, 1
+	}
+	return tokEOF, 0
+}
@@ -0,0 +1,335 @@
+// TradeTGBot/internal/alertexpr/parser.go
+package alertexpr
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Error - ошибка разбора или проверки условия с указанием на проблемную лексему.
+type Error struct {
+	Pos   int    // Позиция лексемы в символах (с нуля)
+	Token string // Текст лексемы (пустой для конца строки)
+	Msg   string
+}
+
+func (e *Error) Error() string {
+	if e.Token == "" {
+		return fmt.Sprintf("позиция %d (конец условия): %s", e.Pos+1, e.Msg)
+	}
+	return fmt.Sprintf("позиция %d («%s»): %s", e.Pos+1, e.Token, e.Msg)
+}
+
+// Pointer возвращает исходную строку и строку с маркером ^ под ошибочной лексемой.
+// Удобно для вывода пользователю в моноширинном блоке.
+func (e *Error) Pointer(src string) string {
+	width := len([]rune(e.Token))
+	if width == 0 {
+		width = 1
+	}
+	return src + "\n" + strings.Repeat(" ", e.Pos) + strings.Repeat("^", width)
+}
+
+// valueType - тип значения узла: число или логическое значение.
+type valueType int
+
+const (
+	typeNumber valueType = iota
+	typeBool
+)
+
+// node - узел синтаксического дерева условия.
+type node interface {
+	typ() valueType
+	position() token
+}
+
+type numberNode struct {
+	tok   token
+	value float64
+}
+
+type tickerNode struct {
+	tok token
+}
+
+type unaryNode struct {
+	tok token // Оператор: "-" или NOT
+	x   node
+}
+
+type binaryNode struct {
+	tok  token // Оператор
+	l, r node
+}
+
+func (n *numberNode) typ() valueType { return typeNumber }
+func (n *tickerNode) typ() valueType { return typeNumber }
+func (n *unaryNode) typ() valueType {
+	if n.tok.kind == tokNot {
+		return typeBool
+	}
+	return typeNumber
+}
+func (n *binaryNode) typ() valueType {
+	switch n.tok.kind {
+	case tokPlus, tokMinus, tokMul, tokDiv:
+		return typeNumber
+	}
+	return typeBool
+}
+
+func (n *numberNode) position() token { return n.tok }
+func (n *tickerNode) position() token { return n.tok }
+func (n *unaryNode) position() token  { return n.tok }
+func (n *binaryNode) position() token { return n.l.position() }
+
+// Expr - разобранное и проверенное условие оповещения.
+type Expr struct {
+	Source  string
+	root    node
+	tickers []string
+}
+
+// String возвращает исходный текст условия.
+func (e *Expr) String() string {
+	return e.Source
+}
+
+// Tickers возвращает отсортированный список тикеров, участвующих в условии.
+func (e *Expr) Tickers() []string {
+	return e.tickers
+}
+
+// Parse разбирает условие вида "SBER > 320 AND GAZP < 150" или "LKOH / ROSN < 1.2".
+// Поддерживаются числа, тикеры, арифметика (+ - * /), сравнения (< <= > >= = !=),
+// логические AND/OR/NOT (а также И/ИЛИ/НЕ, &&, ||, !) и скобки.
+// Если isKnownTicker не nil, каждый тикер проверяется через эту функцию.
+// Все ошибки имеют тип *Error и указывают на проблемную лексему.
+func Parse(src string, isKnownTicker func(string) bool) (*Expr, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, &Error{Pos: tok.pos, Token: tok.text, Msg: "лишняя лексема после окончания условия"}
+	}
+	if root.typ() != typeBool {
+		tok := root.position()
+		return nil, &Error{Pos: tok.pos, Token: tok.text, Msg: "условие должно содержать сравнение (например, SBER > 320)"}
+	}
+
+	seen := make(map[string]bool)
+	var tickers []string
+	for _, tok := range p.tickers {
+		if isKnownTicker != nil && !isKnownTicker(tok.text) {
+			return nil, &Error{Pos: tok.pos, Token: tok.text, Msg: "неизвестный тикер"}
+		}
+		if !seen[tok.text] {
+			seen[tok.text] = true
+			tickers = append(tickers, tok.text)
+		}
+	}
+	sort.Strings(tickers)
+
+	return &Expr{Source: strings.TrimSpace(src), root: root, tickers: tickers}, nil
+}
+
+// parser - рекурсивный нисходящий разборщик. Приоритеты (от низшего к высшему):
+// OR, AND, NOT, сравнения, + -, * /, унарный минус.
+type parser struct {
+	tokens  []token
+	pos     int
+	tickers []token
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		op := p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = logical(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokAnd {
+		op := p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = logical(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.peek().kind == tokNot {
+		op := p.next()
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if x.typ() != typeBool {
+			return nil, &Error{Pos: op.pos, Token: op.text, Msg: "NOT применим только к условию, а не к числу"}
+		}
+		return &unaryNode{tok: op, x: x}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	switch p.peek().kind {
+	case tokLT, tokLE, tokGT, tokGE, tokEQ, tokNE:
+		op := p.next()
+		right, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if left.typ() != typeNumber || right.typ() != typeNumber {
+			return nil, &Error{Pos: op.pos, Token: op.text, Msg: "сравнивать можно только числовые значения"}
+		}
+		switch next := p.peek(); next.kind {
+		case tokLT, tokLE, tokGT, tokGE, tokEQ, tokNE:
+			return nil, &Error{Pos: next.pos, Token: next.text, Msg: "цепочки сравнений не поддерживаются, используйте AND"}
+		}
+		return &binaryNode{tok: op, l: left, r: right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseSum() (node, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokPlus || p.peek().kind == tokMinus {
+		op := p.next()
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = arithmetic(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) parseProduct() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokMul || p.peek().kind == tokDiv {
+		op := p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = arithmetic(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.peek().kind == tokMinus {
+		op := p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if x.typ() != typeNumber {
+			return nil, &Error{Pos: op.pos, Token: op.text, Msg: "унарный минус применим только к числу"}
+		}
+		return &unaryNode{tok: op, x: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		value, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, &Error{Pos: tok.pos, Token: tok.text, Msg: "неверный формат числа"}
+		}
+		return &numberNode{tok: tok, value: value}, nil
+	case tokTicker:
+		p.tickers = append(p.tickers, tok)
+		return &tickerNode{tok: tok}, nil
+	case tokLParen:
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, &Error{Pos: closing.pos, Token: closing.text, Msg: "ожидалась закрывающая скобка"}
+		}
+		return x, nil
+	case tokEOF:
+		return nil, &Error{Pos: tok.pos, Msg: "условие оборвано, ожидалось число или тикер"}
+	}
+	return nil, &Error{Pos: tok.pos, Token: tok.text, Msg: "ожидалось число, тикер или открывающая скобка"}
+}
+
+// logical проверяет типы операндов AND/OR и строит узел.
+func logical(op token, l, r node) (node, error) {
+	for _, x := range []node{l, r} {
+		if x.typ() != typeBool {
+			tok := x.position()
+			return nil, &Error{Pos: tok.pos, Token: tok.text, Msg: fmt.Sprintf("операнд %s должен быть сравнением, а не числом", op.text)}
+		}
+	}
+	return &binaryNode{tok: op, l: l, r: r}, nil
+}
+
+// arithmetic проверяет типы операндов + - * / и строит узел.
+func arithmetic(op token, l, r node) (node, error) {
+	if l.typ() != typeNumber || r.typ() != typeNumber {
+		return nil, &Error{Pos: op.pos, Token: op.text, Msg: "арифметика применима только к числам"}
+	}
+	return &binaryNode{tok: op, l: l, r: r}, nil
+}
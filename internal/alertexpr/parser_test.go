@@ -0,0 +1,132 @@
+// TradeTGBot/internal/alertexpr/parser_test.go
+package alertexpr
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		quotes Quotes
+		want   bool
+	}{
+		// При неверном приоритете получилось бы (true OR false) AND false = false.
+		{"AND связывает сильнее OR", "SBER > 300 OR GAZP > 200 AND LKOH > 1",
+			Quotes{"SBER": 310, "GAZP": 100, "LKOH": 0}, true},
+		{"скобки меняют приоритет", "(SBER > 300 OR GAZP > 200) AND LKOH > 1",
+			Quotes{"SBER": 310, "GAZP": 100, "LKOH": 0}, false},
+		{"NOT связывает сильнее AND", "NOT SBER > 300 AND GAZP > 200",
+			Quotes{"SBER": 100, "GAZP": 250}, true},
+		{"русские ключевые слова", "НЕ SBER > 300 И GAZP > 200 ИЛИ LKOH > 1",
+			Quotes{"SBER": 310, "GAZP": 250, "LKOH": 0}, false},
+		{"умножение раньше сложения", "SBER + GAZP * 2 = 500",
+			Quotes{"SBER": 100, "GAZP": 200}, true},
+		{"вычитание левоассоциативно", "SBER - GAZP - LKOH = 0",
+			Quotes{"SBER": 10, "GAZP": 5, "LKOH": 5}, true},
+		{"деление левоассоциативно", "SBER / GAZP / 2 = 1",
+			Quotes{"SBER": 8, "GAZP": 4}, true},
+		{"унарный минус", "-SBER * 2 = -20", Quotes{"SBER": 10}, true},
+		{"отношение ниже порога", "LKOH / ROSN < 1.2", Quotes{"LKOH": 5900, "ROSN": 5000}, true},
+		{"отношение на пороге не ниже", "LKOH / ROSN < 1.2", Quotes{"LKOH": 6000, "ROSN": 5000}, false},
+		{"десятичная запятая", "SBER >= 300,5", Quotes{"SBER": 300.5}, true},
+		{"= в пределах копейки", "SBER = 300", Quotes{"SBER": 300.004}, true},
+		{"= за пределами копейки", "SBER = 300", Quotes{"SBER": 300.006}, false},
+		{"== как =", "SBER == 300", Quotes{"SBER": 299.996}, true},
+		{"!= в пределах копейки", "SBER != 300", Quotes{"SBER": 300.004}, false},
+		{"<> как !=", "SBER <> 300", Quotes{"SBER": 300.01}, true},
+		{"&& и ||", "SBER > 1 && GAZP > 1 || !(LKOH > 1)", Quotes{"SBER": 2, "GAZP": 0, "LKOH": 0}, true},
+		// Правая часть AND не вычисляется, поэтому котировка GAZP не нужна.
+		{"ленивый AND", "SBER > 300 AND GAZP > 1", Quotes{"SBER": 100}, false},
+		{"ленивый OR", "SBER > 300 OR GAZP > 1", Quotes{"SBER": 310}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Parse(tt.src, nil)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.src, err)
+			}
+			got, err := expr.Eval(tt.quotes)
+			if err != nil {
+				t.Fatalf("Eval(%q): %v", tt.src, err)
+			}
+			if got != tt.want {
+				t.Errorf("Eval(%q) = %v, want %v", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	expr, err := Parse("LKOH / ROSN < 1.2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := expr.Eval(Quotes{"LKOH": 6000, "ROSN": 0}); err == nil || !strings.Contains(err.Error(), "деление на ноль в позиции 6") {
+		t.Errorf("деление на ноль: ошибка %v", err)
+	}
+	if _, err := expr.Eval(Quotes{"LKOH": 6000}); err == nil || !strings.Contains(err.Error(), "ROSN") {
+		t.Errorf("нет котировки: ошибка %v", err)
+	}
+}
+
+func TestParseTickers(t *testing.T) {
+	expr, err := Parse("  sber / gazp > 1 AND SBER < 400  ", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"GAZP", "SBER"}; !reflect.DeepEqual(expr.Tickers(), want) {
+		t.Errorf("Tickers() = %q, want %q", expr.Tickers(), want)
+	}
+	if want := "sber / gazp > 1 AND SBER < 400"; expr.String() != want {
+		t.Errorf("String() = %q, want %q", expr.String(), want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	known := func(ticker string) bool { return ticker != "XXXX" }
+	tests := []struct {
+		src     string
+		pos     int
+		msg     string // Фрагмент сообщения
+		pointer string // Вторая строка Pointer
+	}{
+		{"SBER >", 6, "условие оборвано", "      ^"},
+		{"SBER > 300 AND", 14, "условие оборвано", "              ^"},
+		{"SBER + 5", 0, "должно содержать сравнение", "^^^^"},
+		{"SBER > 1 > 2", 9, "цепочки сравнений", "         ^"},
+		{"SBER > 300 AND 5", 15, "должен быть сравнением", "               ^"},
+		{"NOT SBER", 0, "NOT применим только к условию", "^^^"},
+		{"-(SBER > 1)", 0, "унарный минус", "^"},
+		{"(SBER > 1) + 2 > 1", 11, "арифметика применима только к числам", "           ^"},
+		{"SBER > 1 < 2 = 3", 9, "цепочки сравнений", "         ^"},
+		{"(SBER > 3", 9, "закрывающая скобка", "         ^"},
+		{"SBER > 3)", 8, "лишняя лексема", "        ^"},
+		{"SBER # 3", 5, "недопустимый символ", "     ^"},
+		{"SBER > 1.2.3", 7, "неверный формат числа", "       ^^^^^"},
+		{"SBER > > 3", 7, "ожидалось число, тикер", "       ^"},
+		{"XXXX > 3", 0, "неизвестный тикер", "^^^^"},
+		// Позиции считаются в символах, а не в байтах.
+		{"SBER > 3 ИЛИ", 12, "условие оборвано", "            ^"},
+		{"НЕ XXXX > 3", 3, "неизвестный тикер", "   ^^^^"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Parse(tt.src, known)
+			var perr *Error
+			if !errors.As(err, &perr) {
+				t.Fatalf("Parse(%q) = %v, want *Error", tt.src, err)
+			}
+			if perr.Pos != tt.pos || !strings.Contains(perr.Msg, tt.msg) {
+				t.Errorf("Parse(%q): позиция %d, %q; want %d, %q", tt.src, perr.Pos, perr.Msg, tt.pos, tt.msg)
+			}
+			if want := tt.src + "\n" + tt.pointer; perr.Pointer(tt.src) != want {
+				t.Errorf("Pointer:\n%s\nwant:\n%s", perr.Pointer(tt.src), want)
+			}
+		})
+	}
+}
@@ -2,109 +2,231 @@
 package analyzer
 
 import (
+	"TradeTGBot/internal/config"
+	"TradeTGBot/internal/indicators"
+	"TradeTGBot/internal/pricebus"
 	"TradeTGBot/internal/repository"
 	"TradeTGBot/pkg/stocks"
 	"fmt"
 	"log"
+	"math"
+	"sort"
+	"sync"
 	"time"
+)
 
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"github.com/gocolly/colly" // <-- ДОБАВИТЬ ЭТОТ ИМПОРТ!
+const (
+	analyzerTickBuffer  = 64          // Буфер подписки анализатора на тики
+	subscriptionRefresh = time.Minute // Как часто перечитывать подписки чатов из БД
+	repeatThreshold     = 0.1         // Порог нового уведомления после предыдущего, см. repeated
 )
 
+// recipient - получатель уведомлений о резких изменениях тикера.
+type recipient struct {
+	ChatID    int64
+	Detector  string
+	Threshold float64
+}
+
+// recipientKey - ключ состояния защиты от повторных уведомлений.
+type recipientKey struct {
+	ChatID int64
+	Ticker string
+}
+
 // PriceAnalyzer отвечает за анализ цен и отправку уведомлений о резких изменениях.
+// Уведомления получают чат из конфигурации (по настроенным тикерам) и все чаты,
+// подписавшиеся командой /subscribe.
 type PriceAnalyzer struct {
-	Bot            *tgbotapi.BotAPI
-	Collector      *colly.Collector // <-- ИЗМЕНЕНО НА *colly.Collector
+	Poller         *pricebus.Poller // Общий источник котировок; анализатор подписывается на его шину
 	TargetChatID   int64
-	Interval       time.Duration
-	Threshold      float64 // Процент изменения для уведомления
-	AveragePeriod  time.Duration
-	lastAlertPrice float64 // Для предотвращения спама уведомлениями
-	alertThreshold float64 // Процент для отправки нового уведомления после предыдущего
+	tickers        map[string]config.AnalyzerTicker // Тикеры из конфигурации
+	alertThreshold float64                          // Процент для отправки нового уведомления после предыдущего
+
+	mu          sync.Mutex
+	subscribers map[string][]recipient // Подписки чатов по тикерам
+
+	// Состояние analyzeLoop (доступно только из неё)
+	windows        map[string]*rollingWindow // Скользящая статистика по тикерам
+	lastAlertPrice map[recipientKey]float64  // Для предотвращения спама уведомлениями
 }
 
-// NewPriceAnalyzer создает новый экземпляр PriceAnalyzer.
-func NewPriceAnalyzer(bot *tgbotapi.BotAPI, collector *colly.Collector, targetChatID int64, interval, averagePeriod time.Duration, threshold float64) *PriceAnalyzer { // <-- ИЗМЕНЕНО НА *colly.Collector
-	return &PriceAnalyzer{
-		Bot:            bot,
-		Collector:      collector,
-		TargetChatID:   targetChatID,
-		Interval:       interval,
-		Threshold:      threshold,
-		AveragePeriod:  averagePeriod,
-		lastAlertPrice: 0.0,
-		alertThreshold: 0.1, // 0.1% для нового уведомления
+// NewPriceAnalyzer создает новый экземпляр PriceAnalyzer по настройкам из конфигурации.
+func NewPriceAnalyzer(poller *pricebus.Poller, cfg config.AnalyzerConfig) *PriceAnalyzer {
+	pa := &PriceAnalyzer{
+		Poller:         poller,
+		TargetChatID:   cfg.ChatID,
+		tickers:        make(map[string]config.AnalyzerTicker, len(cfg.Tickers)),
+		alertThreshold: repeatThreshold,
+		subscribers:    make(map[string][]recipient),
+		windows:        make(map[string]*rollingWindow),
+		lastAlertPrice: make(map[recipientKey]float64),
+	}
+	for _, t := range cfg.Tickers {
+		pa.tickers[t.Ticker] = t
 	}
+	return pa
 }
 
-// StartAnalysis запускает горутину для периодического анализа цен.
+// StartAnalysis регистрирует тикеры в поллере и запускает горутины анализа тиков
+// из шины и обновления подписок.
 func (pa *PriceAnalyzer) StartAnalysis() {
-	go pa.analyzeLoop()
+	for ticker, t := range pa.tickers {
+		if _, ok := stocks.Stocks[ticker]; !ok {
+			log.Printf("Ошибка: Тикер %s не найден в списке отслеживаемых акций. Анализ не будет выполнен.", ticker)
+			delete(pa.tickers, ticker)
+			continue
+		}
+		if _, ok := detectors[t.Detector]; !ok {
+			log.Printf("%s: неизвестный детектор %q, используется %s.", ticker, t.Detector, config.DetectorPercent)
+			t.Detector, t.Threshold = config.DetectorPercent, config.DetectorThresholds[config.DetectorPercent]
+			pa.tickers[ticker] = t
+		}
+		// Каждый тикер из конфигурации опрашивается со своим периодом.
+		pa.Poller.Watch(t.Interval, func() []string { return []string{ticker} })
+	}
+	// Тикеры, на которые подписаны только чаты, опрашиваются с периодом по умолчанию.
+	pa.Poller.Watch(config.NewAnalyzerTicker("").Interval, pa.subscribedTickers)
+
+	pa.refreshSubscriptions()
+	go func() {
+		for range time.Tick(subscriptionRefresh) {
+			pa.refreshSubscriptions()
+		}
+	}()
+
+	sub := pa.Poller.Bus().Subscribe("analyzer", analyzerTickBuffer, pricebus.DropOldest)
+	go pa.analyzeLoop(sub)
 }
 
-func (pa *PriceAnalyzer) analyzeLoop() {
-	ticker := "LKOH" // Отслеживаем только LKOH
-	info, ok := stocks.Stocks[ticker]
-	if !ok {
-		log.Printf("Ошибка: Тикер %s не найден в списке отслеживаемых акций. Анализ не будет выполнен.", ticker)
+// refreshSubscriptions перечитывает подписки чатов из БД.
+func (pa *PriceAnalyzer) refreshSubscriptions() {
+	subs, err := repository.GetSpikeSubscriptions(0)
+	if err != nil {
+		log.Printf("Ошибка при загрузке подписок на резкие изменения: %v", err)
 		return
 	}
+	byTicker := make(map[string][]recipient)
+	for _, s := range subs {
+		byTicker[s.Ticker] = append(byTicker[s.Ticker], recipient{ChatID: s.ChatID, Detector: s.Detector, Threshold: s.Threshold})
+	}
+	pa.mu.Lock()
+	pa.subscribers = byTicker
+	pa.mu.Unlock()
+}
 
-	for {
-		// 1. Получаем текущую цену LKOH
-		stock, err := stocks.FetchStockData(info.URL, pa.Collector)
-		if err != nil {
-			log.Printf("Ошибка при получении данных для LKOH: %v", err)
-			time.Sleep(pa.Interval)
+// subscribedTickers возвращает тикеры с подписками, которых нет в конфигурации.
+func (pa *PriceAnalyzer) subscribedTickers() []string {
+	pa.mu.Lock()
+	defer pa.mu.Unlock()
+	var tickers []string
+	for ticker := range pa.subscribers {
+		if _, ok := pa.tickers[ticker]; !ok {
+			tickers = append(tickers, ticker)
+		}
+	}
+	sort.Strings(tickers)
+	return tickers
+}
+
+// recipients возвращает, кому сообщать о резких изменениях тикера.
+func (pa *PriceAnalyzer) recipients(ticker string) []recipient {
+	var list []recipient
+	if t, ok := pa.tickers[ticker]; ok && pa.TargetChatID != 0 {
+		list = append(list, recipient{ChatID: pa.TargetChatID, Detector: t.Detector, Threshold: t.Threshold})
+	}
+	pa.mu.Lock()
+	list = append(list, pa.subscribers[ticker]...)
+	pa.mu.Unlock()
+	return list
+}
+
+// analyzeLoop обрабатывает тики из шины. Сохранением тиков в БД занимается
+// подписчик хранилища, поэтому здесь цена только анализируется по скользящему
+// окну в памяти. Окно тикера прогревается из БД при первом тике и сбрасывается,
+// когда у тикера не остаётся получателей.
+func (pa *PriceAnalyzer) analyzeLoop(sub *pricebus.Subscription) {
+	for tick := range sub.C {
+		recipients := pa.recipients(tick.Ticker)
+		if len(recipients) == 0 {
+			delete(pa.windows, tick.Ticker)
 			continue
 		}
-		currentPrice := stock.Price
-		log.Printf("LKOH: Текущая цена: %.2f", currentPrice)
+		w, ok := pa.windows[tick.Ticker]
+		if !ok {
+			w = warmWindow(tick.Ticker, pa.settings(tick.Ticker), tick.Time)
+			pa.windows[tick.Ticker] = w
+		}
+		w.evict(tick.Time)
+		pa.analyze(tick.Ticker, tick.Price, w, recipients)
+		w.push(indicators.Point{Time: tick.Time, Value: tick.Price})
+	}
+}
+
+// settings возвращает параметры анализа тикера: из конфигурации или по умолчанию.
+func (pa *PriceAnalyzer) settings(ticker string) config.AnalyzerTicker {
+	if t, ok := pa.tickers[ticker]; ok {
+		return t
+	}
+	return config.NewAnalyzerTicker(ticker)
+}
+
+// analyze оценивает текущую цену тикера детекторами получателей и ставит
+// уведомление в очередь каждому получателю, чей порог превышен.
+func (pa *PriceAnalyzer) analyze(ticker string, currentPrice float64, w *rollingWindow, recipients []recipient) {
+	log.Printf("%s: Текущая цена: %.2f", ticker, currentPrice)
 
-		// 2. Сохраняем текущую цену в БД через репозиторий
-		err = repository.SaveStockPrice(ticker, currentPrice)
+	// 1. Считаем сигнал каждого нужного детектора один раз
+	signals := make(map[string]signal)
+	for _, r := range recipients {
+		if _, done := signals[r.Detector]; done {
+			continue
+		}
+		detect, ok := detectors[r.Detector]
+		if !ok {
+			log.Printf("%s: неизвестный детектор %q", ticker, r.Detector)
+			continue
+		}
+		sig, err := detect(currentPrice, w)
 		if err != nil {
-			log.Printf("Ошибка при сохранении цены LKOH в БД: %v", err)
+			log.Printf("%s: детектор %s: %v", ticker, r.Detector, err)
+			continue
 		}
+		log.Printf("%s: детектор %s: %+.2f%s", ticker, r.Detector, sig.Score, sig.Unit)
+		signals[r.Detector] = sig
+	}
 
-		// 3. Получаем среднюю цену за указанный период через репозиторий
-		avgPrice, err := repository.GetAveragePrice(ticker, pa.AveragePeriod)
-		if err != nil {
-			log.Printf("Ошибка при получении средней цены LKOH за %s: %v", pa.AveragePeriod, err)
-			time.Sleep(pa.Interval)
+	// 2. Проверяем порог каждого получателя
+	for _, r := range recipients {
+		sig, ok := signals[r.Detector]
+		if !ok {
 			continue
 		}
-		log.Printf("LKOH: Средняя цена за %s: %.2f", pa.AveragePeriod, avgPrice)
-
-		// 4. Вычисляем процентное изменение
-		if avgPrice > 0 { // Избегаем деления на ноль
-			percentageChange := ((currentPrice - avgPrice) / avgPrice) * 100
-			log.Printf("LKOH: Отклонение от средней за %s: %.2f%%", pa.AveragePeriod, percentageChange)
-
-			// 5. Проверяем, достигнут ли порог для уведомления
-			if percentageChange >= pa.Threshold || percentageChange <= -pa.Threshold {
-				// Проверяем, чтобы не спамить уведомлениями
-				if pa.lastAlertPrice == 0.0 || (currentPrice/pa.lastAlertPrice < (1 - pa.alertThreshold)) || (currentPrice/pa.lastAlertPrice > (1 + pa.alertThreshold)) {
-					msgText := fmt.Sprintf("🚨 **Резкое изменение цены LKOH!**\nТекущая цена: %.2f\nСредняя цена за %s: %.2f\nОтклонение: %.2f%%",
-						currentPrice, pa.AveragePeriod, avgPrice, percentageChange)
-
-					if pa.TargetChatID != 0 {
-						_, err := pa.Bot.Send(tgbotapi.NewMessage(pa.TargetChatID, msgText))
-						if err != nil {
-							log.Printf("Ошибка при отправке уведомления о резком изменении LKOH: %v", err)
-						} else {
-							log.Println("Уведомление о резком изменении LKOH отправлено.")
-							pa.lastAlertPrice = currentPrice // Обновляем цену последнего алерта
-						}
-					}
-				}
-			} else {
-				// Сбрасываем lastAlertPrice, если цена вернулась в норму
-				pa.lastAlertPrice = 0.0
-			}
+		key := recipientKey{ChatID: r.ChatID, Ticker: ticker}
+		if math.Abs(sig.Score) < r.Threshold {
+			// Сбрасываем цену последнего уведомления, если цена вернулась в норму
+			delete(pa.lastAlertPrice, key)
+			continue
 		}
-
-		time.Sleep(pa.Interval) // Ждем до следующей проверки
+		// Проверяем, чтобы не спамить уведомлениями
+		if repeated(pa.lastAlertPrice[key], currentPrice, pa.alertThreshold) {
+			continue
+		}
+		msgText := fmt.Sprintf("🚨 **Резкое изменение цены %s!**\nТекущая цена: %.2f\n%s\nПорог: %.2f%s (%s)",
+			ticker, currentPrice, sig.Explanation, r.Threshold, sig.Unit, r.Detector)
+		// Доставкой (с повторами и тихими часами) занимается outbox бота
+		_, err := repository.EnqueueNotification(repository.Notification{ChatID: r.ChatID, Text: msgText})
+		if err != nil {
+			log.Printf("Ошибка при постановке уведомления о резком изменении %s для чата %d в очередь: %v", ticker, r.ChatID, err)
+			continue
+		}
+		log.Printf("Уведомление о резком изменении %s для чата %d поставлено в очередь.", ticker, r.ChatID)
+		pa.lastAlertPrice[key] = currentPrice // Обновляем цену последнего уведомления
 	}
 }
+
+// repeated сообщает, что цена после предыдущего уведомления по цене last сдвинулась
+// меньше чем на долю threshold и новое уведомление было бы повтором.
+func repeated(last, price, threshold float64) bool {
+	return last != 0 && price/last >= 1-threshold && price/last <= 1+threshold
+}
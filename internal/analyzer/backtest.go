@@ -0,0 +1,82 @@
+// TradeTGBot/internal/analyzer/backtest.go
+package analyzer
+
+import (
+	"TradeTGBot/internal/backtest"
+	"TradeTGBot/internal/config"
+	"TradeTGBot/internal/indicators"
+	"TradeTGBot/internal/repository"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Backtest прогоняет сохранённые тики тикера за [from, to) через детектор и порог
+// из settings так же, как это делает analyzeLoop: скользящее окно, тот же детектор
+// и та же защита от повторных уведомлений. Окно прогревается историей до from.
+func Backtest(settings config.AnalyzerTicker, from, to time.Time, horizons []time.Duration) (backtest.Result, error) {
+	detect, ok := detectors[settings.Detector]
+	if !ok {
+		return backtest.Result{}, fmt.Errorf("неизвестный детектор %q", settings.Detector)
+	}
+	// Тики после to нужны только для доходности после сигналов.
+	var maxHorizon time.Duration
+	for _, h := range horizons {
+		maxHorizon = max(maxHorizon, h)
+	}
+	history, err := repository.GetPriceHistoryBetween(settings.Ticker, from.Add(-warmupSpan(settings.AveragePeriod)), to.Add(maxHorizon))
+	if err != nil {
+		return backtest.Result{}, err
+	}
+	ticks := make([]indicators.Point, len(history))
+	for i, p := range history {
+		ticks[i] = indicators.Point{Time: p.Timestamp, Value: p.Price}
+	}
+
+	result := backtest.Result{
+		Rule: fmt.Sprintf("%s: %s ≥ %.2f%s, окно %s", settings.Ticker, settings.Detector,
+			settings.Threshold, unitOf(settings.Detector), settings.AveragePeriod),
+		Ticker:   settings.Ticker,
+		From:     from,
+		To:       to,
+		Horizons: horizons,
+	}
+	w := newRollingWindow(settings)
+	var last float64 // Цена последнего срабатывания, как lastAlertPrice
+	for _, tick := range ticks {
+		if !tick.Time.Before(to) {
+			break
+		}
+		w.evict(tick.Time)
+		if !tick.Time.Before(from) {
+			result.Ticks++
+			if sig, err := detect(tick.Value, w); err == nil {
+				switch {
+				case math.Abs(sig.Score) < settings.Threshold:
+					last = 0
+				case !repeated(last, tick.Value, repeatThreshold):
+					result.Triggers = append(result.Triggers, backtest.Trigger{
+						Time:   tick.Time,
+						Price:  tick.Value,
+						Detail: fmt.Sprintf("%+.2f%s", sig.Score, sig.Unit),
+					})
+					last = tick.Value
+				}
+			}
+		}
+		w.push(tick)
+	}
+	result.ForwardReturns(ticks)
+	return result, nil
+}
+
+// unitOf возвращает единицу порога детектора для описаний.
+func unitOf(detector string) string {
+	switch detector {
+	case config.DetectorPercent:
+		return "%"
+	case config.DetectorATR:
+		return " ATR"
+	}
+	return "σ"
+}
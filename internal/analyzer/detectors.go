@@ -0,0 +1,122 @@
+// TradeTGBot/internal/analyzer/detectors.go
+package analyzer
+
+import (
+	"TradeTGBot/internal/config"
+	"fmt"
+	"time"
+)
+
+// Параметры детекторов.
+const (
+	minSamples = 3           // Минимум тиков в окне для статистики
+	atrBar     = time.Minute // Длительность бара для ATR
+	atrPeriod  = 14          // Число баров в ATR (сглаживание Уайлдера)
+)
+
+// signal - оценка отклонения текущей цены детектором.
+type signal struct {
+	Score       float64 // Сравнивается с порогом по модулю; знак - направление движения
+	Unit        string  // Единица порога: "%", "σ" или "ATR"
+	Explanation string  // Какая статистика сработала - для текста уведомления
+}
+
+// detector оценивает текущую цену по скользящей статистике окна тикера.
+// Текущий тик в окно ещё не добавлен.
+type detector func(price float64, w *rollingWindow) (signal, error)
+
+// detectors - реализации детекторов по именам из конфигурации.
+var detectors = map[string]detector{
+	config.DetectorPercent: detectPercent,
+	config.DetectorZScore:  detectZScore,
+	config.DetectorEWMA:    detectEWMA,
+	config.DetectorATR:     detectATR,
+}
+
+// checkSamples проверяет, что в окне достаточно тиков для статистики.
+func checkSamples(w *rollingWindow) error {
+	if w.Len() < minSamples {
+		return fmt.Errorf("недостаточно данных за %s: %d тиков", w.window, w.Len())
+	}
+	return nil
+}
+
+// detectPercent - отклонение от средней за окно в процентах.
+func detectPercent(price float64, w *rollingWindow) (signal, error) {
+	if err := checkSamples(w); err != nil {
+		return signal{}, err
+	}
+	mean, _ := w.MeanStdDev()
+	if mean <= 0 { // Избегаем деления на ноль
+		return signal{}, fmt.Errorf("нулевая средняя цена")
+	}
+	low, high := w.MinMax()
+	pct := (price - mean) / mean * 100
+	return signal{
+		Score: pct,
+		Unit:  "%",
+		Explanation: fmt.Sprintf("Средняя цена за %s: %.2f (диапазон %.2f–%.2f)\nОтклонение: %+.2f%%",
+			w.window, mean, low, high, pct),
+	}, nil
+}
+
+// detectZScore - отклонение от средней за окно в стандартных отклонениях цены за то же окно.
+func detectZScore(price float64, w *rollingWindow) (signal, error) {
+	if err := checkSamples(w); err != nil {
+		return signal{}, err
+	}
+	mean, sd := w.MeanStdDev()
+	if sd == 0 {
+		return signal{}, fmt.Errorf("цена за %s не менялась", w.window)
+	}
+	z := (price - mean) / sd
+	return signal{
+		Score: z,
+		Unit:  "σ",
+		Explanation: fmt.Sprintf("Z-оценка: %+.2fσ (средняя за %s: %.2f, σ: %.2f)",
+			z, w.window, mean, sd),
+	}, nil
+}
+
+// detectEWMA - отклонение от экспоненциально взвешенной средней в её стандартных
+// отклонениях. Свежие тики весят больше, поэтому детектор быстрее подстраивается
+// под смену режима волатильности. Коэффициент сглаживания - см. newRollingWindow.
+func detectEWMA(price float64, w *rollingWindow) (signal, error) {
+	if err := checkSamples(w); err != nil {
+		return signal{}, err
+	}
+	mean, sd := w.EWMA()
+	if sd == 0 {
+		return signal{}, fmt.Errorf("цена за %s не менялась", w.window)
+	}
+	z := (price - mean) / sd
+	return signal{
+		Score: z,
+		Unit:  "σ",
+		Explanation: fmt.Sprintf("Отклонение от EWMA: %+.2fσ (EWMA за %s: %.2f, σ: %.2f)",
+			z, w.window, mean, sd),
+	}, nil
+}
+
+// detectATR - движение цены за окно, нормированное на средний истинный диапазон
+// минутных баров. Так одинаковый порог подходит и спокойным, и волатильным бумагам.
+func detectATR(price float64, w *rollingWindow) (signal, error) {
+	if err := checkSamples(w); err != nil {
+		return signal{}, err
+	}
+	atr, ok := w.ATR()
+	if !ok {
+		return signal{}, fmt.Errorf("недостаточно баров для ATR(%d): %d", atrPeriod, w.trCount)
+	}
+	if atr == 0 {
+		return signal{}, fmt.Errorf("нулевой ATR")
+	}
+	move := price - w.First()
+	score := move / atr
+	return signal{
+		Score: score,
+		Unit:  "ATR",
+		Explanation: fmt.Sprintf("Движение за %s: %+.2f = %+.2f ATR (ATR(%d, %s): %.2f)",
+			w.window, move, score, atrPeriod, atrBar, atr),
+	}, nil
+}
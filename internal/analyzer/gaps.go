@@ -0,0 +1,254 @@
+// TradeTGBot/internal/analyzer/gaps.go
+package analyzer
+
+import (
+	"TradeTGBot/internal/config"
+	"TradeTGBot/internal/pricebus"
+	"TradeTGBot/internal/repository"
+	"TradeTGBot/pkg/stocks"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	gapCheckInterval = time.Minute      // Как часто проверять, пора ли считать гэпы
+	gapPollInterval  = time.Minute      // Период опроса тикеров, на гэпы которых подписаны чаты
+	gapReportSize    = 5                // Сколько наибольших гэпов вверх и вниз показывать в отчёте
+	gapCloseWindow   = 15 * time.Minute // Сколько до и после окончания торгов опрашивать все тикеры для отчёта
+)
+
+// market - часовой пояс MOEX: по нему определяются торговый день и время открытия.
+var market = loadMarketLocation()
+
+func loadMarketLocation() *time.Location {
+	loc, err := time.LoadLocation(repository.DefaultTimezone)
+	if err != nil {
+		log.Printf("Ошибка загрузки часового пояса %s: %v", repository.DefaultTimezone, err)
+		return time.FixedZone("MSK", 3*60*60)
+	}
+	return loc
+}
+
+// MarketDay возвращает начало торгового дня (полночь по Москве), которому принадлежит t.
+func MarketDay(t time.Time) time.Time {
+	local := t.In(market)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, market)
+}
+
+// GapReporter после открытия основной сессии MOEX вычисляет гэпы относительно
+// закрытия предыдущего дня, рассылает отчёт о наибольших гэпах и уведомления
+// подписчикам на гэпы отдельных тикеров.
+type GapReporter struct {
+	Poller *pricebus.Poller
+	cfg    config.GapConfig
+
+	mu      sync.Mutex
+	tickers []string // Тикеры подписок на гэпы; перечитываются в run
+	report  bool     // Есть подписки на отчёт по всем тикерам
+
+	lastDay time.Time // Последний обработанный торговый день; только в run
+}
+
+// NewGapReporter создаёт GapReporter по настройкам из конфигурации.
+func NewGapReporter(poller *pricebus.Poller, cfg config.GapConfig) *GapReporter {
+	return &GapReporter{Poller: poller, cfg: cfg}
+}
+
+// Start регистрирует тикеры подписок в поллере (для гэпа нужны и закрытие,
+// и открытие, поэтому они опрашиваются постоянно) и запускает проверку по расписанию.
+// Если кто-то подписан на отчёт, около окончания торгов и открытия опрашиваются
+// все известные тикеры, чтобы отчёт охватывал весь список, а не только тикеры,
+// которые опрашиваются для других целей.
+func (gr *GapReporter) Start() {
+	gr.refreshTickers()
+	gr.Poller.Watch(gapPollInterval, gr.subscribedTickers)
+	go gr.run()
+}
+
+// refreshTickers перечитывает из БД тикеры, на гэпы которых подписаны чаты.
+func (gr *GapReporter) refreshTickers() {
+	subs, err := repository.GetGapSubscriptions(0)
+	if err != nil {
+		log.Printf("Ошибка при загрузке подписок на гэпы: %v", err)
+		return
+	}
+	var tickers []string
+	report := false
+	for _, s := range subs {
+		if s.Ticker == repository.GapReport {
+			report = true
+			continue
+		}
+		tickers = append(tickers, s.Ticker)
+	}
+	gr.mu.Lock()
+	gr.tickers, gr.report = tickers, report
+	gr.mu.Unlock()
+}
+
+// subscribedTickers возвращает тикеры подписок на гэпы для опроса, а в окна
+// вокруг окончания торгов и открытия при подписке на отчёт - все тикеры.
+func (gr *GapReporter) subscribedTickers() []string {
+	gr.mu.Lock()
+	defer gr.mu.Unlock()
+	if gr.report && gr.inReportWindow(time.Now()) {
+		tickers := make([]string, 0, len(stocks.Stocks))
+		for ticker := range stocks.Stocks {
+			tickers = append(tickers, ticker)
+		}
+		sort.Strings(tickers)
+		return tickers
+	}
+	return append([]string(nil), gr.tickers...)
+}
+
+// inReportWindow проверяет, нужны ли сейчас цены всех тикеров для отчёта:
+// в торговый день около окончания торгов (цена закрытия) и от открытия до
+// расчёта гэпов (цена открытия).
+func (gr *GapReporter) inReportWindow(now time.Time) bool {
+	day := MarketDay(now)
+	if wd := day.Weekday(); wd == time.Saturday || wd == time.Sunday {
+		return false
+	}
+	open, close := day.Add(gr.cfg.Open), day.Add(gr.cfg.Close)
+	inOpen := !now.Before(open) && now.Before(open.Add(gr.cfg.ReportDelay))
+	inClose := !now.Before(close.Add(-gapCloseWindow)) && now.Before(close.Add(gapCloseWindow))
+	return inOpen || inClose
+}
+
+func (gr *GapReporter) run() {
+	for {
+		gr.refreshTickers()
+		gr.check(time.Now())
+		time.Sleep(gapCheckInterval)
+	}
+}
+
+// check один раз за торговый день, после открытия и задержки отчёта, вычисляет
+// гэпы и рассылает уведомления. Если гэпы дня уже сохранены (например, до
+// перезапуска), повторно ничего не отправляется.
+func (gr *GapReporter) check(now time.Time) {
+	day := MarketDay(now)
+	if !gr.lastDay.Before(day) {
+		return
+	}
+	if wd := day.Weekday(); wd == time.Saturday || wd == time.Sunday {
+		gr.lastDay = day
+		return
+	}
+	open := day.Add(gr.cfg.Open)
+	reportAt := open.Add(gr.cfg.ReportDelay)
+	if now.Before(reportAt) {
+		return
+	}
+
+	gaps, err := repository.FindOpeningGaps(day, open, reportAt)
+	if err != nil {
+		log.Printf("Ошибка при вычислении гэпов за %s: %v", day.Format("02.01.2006"), err)
+		return // Повторим на следующей проверке
+	}
+	gr.lastDay = day
+	if len(gaps) == 0 {
+		log.Printf("Гэпы за %s: нет цен закрытия и открытия (выходной или нет данных).", day.Format("02.01.2006"))
+		return
+	}
+	saved, err := repository.SaveOpeningGaps(day, gaps)
+	if err != nil {
+		log.Printf("Ошибка при сохранении гэпов за %s: %v", day.Format("02.01.2006"), err)
+		return
+	}
+	if !saved {
+		return
+	}
+	log.Printf("Гэпы за %s вычислены по %d тикерам.", day.Format("02.01.2006"), len(gaps))
+	gr.notify(day, gaps)
+}
+
+// notify ставит в очередь отчёт подписчикам отчёта и уведомления подписчикам тикеров.
+func (gr *GapReporter) notify(day time.Time, gaps []repository.OpeningGap) {
+	subs, err := repository.GetGapSubscriptions(0)
+	if err != nil {
+		log.Printf("Ошибка при загрузке подписок на гэпы: %v", err)
+		return
+	}
+	byTicker := make(map[string]repository.OpeningGap, len(gaps))
+	for _, g := range gaps {
+		byTicker[g.Ticker] = g
+	}
+
+	for _, s := range subs {
+		var text string
+		if s.Ticker == repository.GapReport {
+			text = FormatGapReport(day, gaps, s.MinGap)
+		} else if g, ok := byTicker[s.Ticker]; ok && math.Abs(g.Gap) >= s.MinGap {
+			text = fmt.Sprintf("⚡ Гэп %s на открытии: %+.2f%%\nЗакрытие: %.2f, открытие: %.2f\nПорог: %.2f%%",
+				g.Ticker, g.Gap, g.PrevClose, g.Open, s.MinGap)
+		} else {
+			continue
+		}
+		// Доставкой (с повторами и тихими часами) занимается outbox бота
+		if _, err := repository.EnqueueNotification(repository.Notification{ChatID: s.ChatID, Text: text}); err != nil {
+			log.Printf("Ошибка при постановке уведомления о гэпах для чата %d в очередь: %v", s.ChatID, err)
+		}
+	}
+}
+
+// FormatGapReport формирует отчёт о наибольших гэпах вверх и вниз за день.
+// Гэпы меньше minGap по модулю в отчёт не попадают. В отчёте указано, по скольким
+// тикерам нашлись цены закрытия и открытия: тикеры без них в отчёт не попадают.
+func FormatGapReport(day time.Time, gaps []repository.OpeningGap, minGap float64) string {
+	var up, down []repository.OpeningGap
+	for _, g := range gaps {
+		switch {
+		case math.Abs(g.Gap) < minGap || g.Gap == 0:
+		case g.Gap > 0:
+			up = append(up, g)
+		default:
+			down = append(down, g)
+		}
+	}
+	sort.Slice(up, func(i, j int) bool { return up[i].Gap > up[j].Gap })
+	sort.Slice(down, func(i, j int) bool { return down[i].Gap < down[j].Gap })
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📊 Гэпы открытия %s (к закрытию предыдущего дня)\n", day.Format("02.01.2006")))
+	sb.WriteString(fmt.Sprintf("Охват: %d из %d тикеров", len(gaps), len(stocks.Stocks)))
+	if missing := missingTickers(gaps); len(missing) > 0 && len(missing) <= gapReportSize*2 {
+		sb.WriteString(", нет данных: " + strings.Join(missing, ", "))
+	}
+	sb.WriteString("\n")
+	writeGaps := func(title string, list []repository.OpeningGap) {
+		sb.WriteString("\n" + title + ":\n")
+		if len(list) == 0 {
+			sb.WriteString("нет\n")
+			return
+		}
+		for _, g := range list[:min(len(list), gapReportSize)] {
+			sb.WriteString(fmt.Sprintf("%s %+.2f%% (%.2f → %.2f)\n", g.Ticker, g.Gap, g.PrevClose, g.Open))
+		}
+	}
+	writeGaps("⬆️ Вверх", up)
+	writeGaps("⬇️ Вниз", down)
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// missingTickers возвращает известные тикеры, для которых гэп не вычислен.
+func missingTickers(gaps []repository.OpeningGap) []string {
+	have := make(map[string]bool, len(gaps))
+	for _, g := range gaps {
+		have[g.Ticker] = true
+	}
+	var missing []string
+	for ticker := range stocks.Stocks {
+		if !have[ticker] {
+			missing = append(missing, ticker)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
@@ -0,0 +1,182 @@
+// TradeTGBot/internal/analyzer/window.go
+package analyzer
+
+import (
+	"TradeTGBot/internal/config"
+	"TradeTGBot/internal/indicators"
+	"TradeTGBot/internal/repository"
+	"log"
+	"math"
+	"time"
+)
+
+// ohlc - бар цены для ATR.
+type ohlc struct{ high, low, close float64 }
+
+// rollingWindow - скользящая статистика тиков одного тикера за окно анализа.
+// Каждый тик обновляет её за амортизированное O(1), поэтому анализ не делает
+// агрегирующих запросов к БД: история читается один раз при прогреве.
+type rollingWindow struct {
+	window time.Duration
+	points []indicators.Point // Тики окна по возрастанию времени
+
+	// Среднее и дисперсия: суммы отклонений от опорной цены ref, чтобы
+	// не терять точность на вычитании больших квадратов цен.
+	ref, sum, sumSq float64
+
+	// Монотонные очереди для минимума и максимума окна.
+	minQ, maxQ []indicators.Point
+
+	// EWMA и её дисперсия по всем тикам с коэффициентом alpha.
+	alpha, ewma, ewmaVar float64
+	ewmaN                int
+
+	// ATR по закрытым барам длительностью atrBar.
+	bar       ohlc
+	barStart  time.Time
+	prevClose float64
+	trCount   int
+	atr       float64
+}
+
+// newRollingWindow создаёт окно по параметрам тикера. Коэффициент EWMA - 2/(n+1),
+// где n - число тиков, помещающихся в окно при периоде опроса тикера.
+func newRollingWindow(settings config.AnalyzerTicker) *rollingWindow {
+	n := 1
+	if settings.Interval > 0 {
+		n = max(1, int(settings.AveragePeriod/settings.Interval))
+	}
+	return &rollingWindow{window: settings.AveragePeriod, alpha: 2 / float64(n+1)}
+}
+
+// warmupSpan возвращает, за какой период нужна история для прогрева окна:
+// само окно и достаточно баров для ATR.
+func warmupSpan(window time.Duration) time.Duration {
+	return max(window, atrBar*(atrPeriod+1))
+}
+
+// warmWindow создаёт окно тикера и заполняет его тиками из БД до момента before.
+// Если историю получить не удалось, окно наполнится свежими тиками.
+func warmWindow(ticker string, settings config.AnalyzerTicker, before time.Time) *rollingWindow {
+	w := newRollingWindow(settings)
+	prices, err := repository.GetPriceHistory(ticker, before.Add(-warmupSpan(settings.AveragePeriod)))
+	if err != nil {
+		log.Printf("Ошибка при прогреве окна %s: %v", ticker, err)
+		return w
+	}
+	for _, p := range prices {
+		if p.Timestamp.Before(before) {
+			w.push(indicators.Point{Time: p.Timestamp, Value: p.Price})
+		}
+	}
+	w.evict(before)
+	log.Printf("%s: окно %s прогрето, тиков: %d", ticker, w.window, w.Len())
+	return w
+}
+
+// push добавляет тик в окно и обновляет всю статистику.
+func (w *rollingWindow) push(p indicators.Point) {
+	if n := len(w.points); n > 0 && !p.Time.After(w.points[n-1].Time) {
+		return // Тик уже учтён (например, попал и в прогрев, и в шину)
+	}
+	w.evict(p.Time)
+
+	if len(w.points) == 0 {
+		w.ref, w.sum, w.sumSq = p.Value, 0, 0
+	}
+	w.points = append(w.points, p)
+	d := p.Value - w.ref
+	w.sum += d
+	w.sumSq += d * d
+
+	for len(w.minQ) > 0 && w.minQ[len(w.minQ)-1].Value >= p.Value {
+		w.minQ = w.minQ[:len(w.minQ)-1]
+	}
+	w.minQ = append(w.minQ, p)
+	for len(w.maxQ) > 0 && w.maxQ[len(w.maxQ)-1].Value <= p.Value {
+		w.maxQ = w.maxQ[:len(w.maxQ)-1]
+	}
+	w.maxQ = append(w.maxQ, p)
+
+	if w.ewmaN == 0 {
+		w.ewma = p.Value
+	} else {
+		diff := p.Value - w.ewma
+		w.ewma += w.alpha * diff
+		w.ewmaVar = (1 - w.alpha) * (w.ewmaVar + w.alpha*diff*diff)
+	}
+	w.ewmaN++
+
+	w.pushBar(p)
+}
+
+// pushBar обновляет текущий бар; при переходе в новый бар закрывает
+// предыдущий и обновляет ATR сглаживанием Уайлдера.
+func (w *rollingWindow) pushBar(p indicators.Point) {
+	bucket := p.Time.Truncate(atrBar)
+	if w.barStart.IsZero() {
+		w.bar, w.barStart = ohlc{high: p.Value, low: p.Value, close: p.Value}, bucket
+		return
+	}
+	if bucket.Equal(w.barStart) {
+		w.bar.high, w.bar.low, w.bar.close = max(w.bar.high, p.Value), min(w.bar.low, p.Value), p.Value
+		return
+	}
+
+	if w.prevClose != 0 {
+		tr := max(w.bar.high-w.bar.low, math.Abs(w.bar.high-w.prevClose), math.Abs(w.bar.low-w.prevClose))
+		w.trCount++
+		if w.trCount <= atrPeriod {
+			w.atr += tr / atrPeriod
+		} else {
+			w.atr = (w.atr*(atrPeriod-1) + tr) / atrPeriod
+		}
+	}
+	w.prevClose = w.bar.close
+	w.bar, w.barStart = ohlc{high: p.Value, low: p.Value, close: p.Value}, bucket
+}
+
+// evict удаляет тики старше окна относительно момента now.
+func (w *rollingWindow) evict(now time.Time) {
+	from := now.Add(-w.window)
+	for len(w.points) > 0 && w.points[0].Time.Before(from) {
+		d := w.points[0].Value - w.ref
+		w.sum -= d
+		w.sumSq -= d * d
+		w.points = w.points[1:]
+	}
+	for len(w.minQ) > 0 && w.minQ[0].Time.Before(from) {
+		w.minQ = w.minQ[1:]
+	}
+	for len(w.maxQ) > 0 && w.maxQ[0].Time.Before(from) {
+		w.maxQ = w.maxQ[1:]
+	}
+}
+
+// Len возвращает число тиков в окне.
+func (w *rollingWindow) Len() int { return len(w.points) }
+
+// First возвращает самую старую цену окна.
+func (w *rollingWindow) First() float64 { return w.points[0].Value }
+
+// MeanStdDev возвращает среднее и стандартное отклонение цены за окно.
+func (w *rollingWindow) MeanStdDev() (mean, sd float64) {
+	n := float64(len(w.points))
+	avg := w.sum / n
+	return w.ref + avg, math.Sqrt(max(0, w.sumSq/n-avg*avg))
+}
+
+// MinMax возвращает минимальную и максимальную цену за окно.
+func (w *rollingWindow) MinMax() (low, high float64) {
+	return w.minQ[0].Value, w.maxQ[0].Value
+}
+
+// EWMA возвращает экспоненциальную среднюю и её стандартное отклонение.
+func (w *rollingWindow) EWMA() (mean, sd float64) {
+	return w.ewma, math.Sqrt(w.ewmaVar)
+}
+
+// ATR возвращает средний истинный диапазон; false, если закрытых баров ещё мало.
+func (w *rollingWindow) ATR() (float64, bool) {
+	return w.atr, w.trCount >= atrPeriod
+}
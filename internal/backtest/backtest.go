@@ -0,0 +1,170 @@
+// TradeTGBot/internal/backtest/backtest.go
+package backtest
+
+import (
+	"TradeTGBot/internal/indicators"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultHorizons - горизонты, через которые считается доходность после сигнала.
+var DefaultHorizons = []time.Duration{15 * time.Minute, time.Hour, 24 * time.Hour}
+
+// Trigger represents one firing of a rule during a replay of stored prices.
+type Trigger struct {
+	Time    time.Time
+	Price   float64
+	Detail  string    // Краткое пояснение сигнала, например "+3.10σ"; может быть пустым
+	Returns []float64 // Доходность в % по горизонтам Result.Horizons; NaN - история не дошла до горизонта
+}
+
+// Result represents the outcome of a backtest of one rule over a period.
+type Result struct {
+	Rule     string // Описание правила для отчёта
+	Ticker   string // Тикер, по которому считается доходность после сигнала
+	From, To time.Time
+	Ticks    int // Сколько тиков прогнано через правило
+	Bars     int // Сколько минутных свечей первого тикера прогнано; 0 - прогон по тикам
+	Horizons []time.Duration
+	Triggers []Trigger
+}
+
+// ForwardReturns заполняет доходность каждого срабатывания через горизонты:
+// изменение цены первого тика не раньше Time+горизонт относительно цены срабатывания.
+// Тики должны быть отсортированы по времени.
+func (r *Result) ForwardReturns(ticks []indicators.Point) {
+	for i := range r.Triggers {
+		t := &r.Triggers[i]
+		t.Returns = make([]float64, len(r.Horizons))
+		for j, h := range r.Horizons {
+			t.Returns[j] = math.NaN()
+			at := t.Time.Add(h)
+			k := sort.Search(len(ticks), func(k int) bool { return !ticks[k].Time.Before(at) })
+			if k < len(ticks) && t.Price != 0 {
+				t.Returns[j] = (ticks[k].Value/t.Price - 1) * 100
+			}
+		}
+	}
+}
+
+// HorizonStats - сводка доходности после сигналов на одном горизонте.
+type HorizonStats struct {
+	Count   int     // Срабатываний, для которых есть цена через горизонт
+	Mean    float64 // Средняя доходность, %
+	UpShare float64 // Доля срабатываний с ростом цены, %
+}
+
+// Stats возвращает сводку доходности по каждому горизонту.
+func (r Result) Stats() []HorizonStats {
+	stats := make([]HorizonStats, len(r.Horizons))
+	for j := range r.Horizons {
+		var sum float64
+		var up int
+		for _, t := range r.Triggers {
+			if j >= len(t.Returns) || math.IsNaN(t.Returns[j]) {
+				continue
+			}
+			stats[j].Count++
+			sum += t.Returns[j]
+			if t.Returns[j] > 0 {
+				up++
+			}
+		}
+		if stats[j].Count > 0 {
+			stats[j].Mean = sum / float64(stats[j].Count)
+			stats[j].UpShare = float64(up) / float64(stats[j].Count) * 100
+		}
+	}
+	return stats
+}
+
+// Report формирует текстовый отчёт: число срабатываний, доходность после них
+// и последние maxRows срабатываний. Время выводится в часовом поясе loc.
+func (r Result) Report(loc *time.Location, maxRows int) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Бэктест: %s\n", r.Rule))
+	volume := fmt.Sprintf("тиков: %d", r.Ticks)
+	if r.Bars > 0 {
+		volume = fmt.Sprintf("минутных свечей: %d", r.Bars)
+	}
+	sb.WriteString(fmt.Sprintf("Период: %s – %s, %s\n",
+		r.From.In(loc).Format("02.01.2006 15:04"), r.To.In(loc).Format("02.01.2006 15:04"), volume))
+	sb.WriteString(fmt.Sprintf("Срабатываний: %d\n", len(r.Triggers)))
+	if len(r.Triggers) == 0 {
+		return sb.String()
+	}
+
+	sb.WriteString(fmt.Sprintf("\nДоходность %s после сигнала (средняя, доля роста):\n", r.Ticker))
+	for j, s := range r.Stats() {
+		if s.Count == 0 {
+			sb.WriteString(fmt.Sprintf("через %s: нет данных\n", FormatDuration(r.Horizons[j])))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("через %s: %+.2f%%, %.0f%% (из %d)\n", FormatDuration(r.Horizons[j]), s.Mean, s.UpShare, s.Count))
+	}
+
+	shown := r.Triggers
+	if len(shown) > maxRows {
+		shown = shown[len(shown)-maxRows:]
+		sb.WriteString(fmt.Sprintf("\nПоследние %d срабатываний:\n", maxRows))
+	} else {
+		sb.WriteString("\nСрабатывания:\n")
+	}
+	for _, t := range shown {
+		sb.WriteString(fmt.Sprintf("%s  %.2f", t.Time.In(loc).Format("02.01 15:04"), t.Price))
+		if t.Detail != "" {
+			sb.WriteString("  " + t.Detail)
+		}
+		for j, ret := range t.Returns {
+			if math.IsNaN(ret) {
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("  %s %+.2f%%", FormatDuration(r.Horizons[j]), ret))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// ParseHorizons разбирает список горизонтов через запятую, например "15m,1h,1d".
+func ParseHorizons(s string) ([]time.Duration, error) {
+	var horizons []time.Duration
+	for _, part := range strings.Split(s, ",") {
+		d, err := ParseDuration(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		horizons = append(horizons, d)
+	}
+	return horizons, nil
+}
+
+// ParseDuration разбирает длительность Go (15m, 4h) или дни (5d).
+func ParseDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(strings.ToLower(s), "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("неверная длительность %q", s)
+	}
+	return d, nil
+}
+
+// FormatDuration записывает длительность коротко: 15m, 4h, 1d.
+func FormatDuration(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	default:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+}
@@ -0,0 +1,292 @@
+// TradeTGBot/internal/chart/chart.go
+package chart
+
+import (
+	"TradeTGBot/internal/indicators"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Style - вид графика цены.
+type Style int
+
+const (
+	Candlestick Style = iota // Японские свечи
+	Line                     // Линия цен закрытия
+)
+
+// MA - скользящая средняя на графике.
+type MA struct {
+	Period      int
+	Exponential bool // EMA вместо SMA
+}
+
+// Label возвращает подпись средней для легенды, например "SMA20".
+func (m MA) Label() string {
+	if m.Exponential {
+		return fmt.Sprintf("EMA%d", m.Period)
+	}
+	return fmt.Sprintf("SMA%d", m.Period)
+}
+
+// Options - параметры построения графика.
+type Options struct {
+	Title    string
+	Style    Style
+	MAs      []MA
+	Warmup   int            // Сколько первых баров используется только для расчёта средних
+	Location *time.Location // Часовой пояс подписей времени; nil - UTC
+	Width    int            // 0 - defaultWidth
+	Height   int            // 0 - defaultHeight
+}
+
+// Размеры и отступы графика по умолчанию, в пикселях.
+const (
+	defaultWidth  = 1000
+	defaultHeight = 600
+	marginLeft    = 10
+	marginRight   = 75 // Шкала цен
+	marginTop     = 28 // Заголовок и легенда
+	marginBottom  = 22 // Шкала времени
+	panelGap      = 8
+	volumeShare   = 0.2 // Доля высоты под панель объёма
+	priceTicks    = 5
+	timeTicks     = 6
+)
+
+var (
+	background = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	gridColor  = color.RGBA{0xe6, 0xe6, 0xe6, 0xff}
+	textColor  = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	upColor    = color.RGBA{0x26, 0xa6, 0x9a, 0xff}
+	downColor  = color.RGBA{0xef, 0x53, 0x50, 0xff}
+	lineColor  = color.RGBA{0x1e, 0x88, 0xe5, 0xff}
+	volumeUp   = color.NRGBA{0x26, 0xa6, 0x9a, 0x80}
+	volumeDown = color.NRGBA{0xef, 0x53, 0x50, 0x80}
+	maColors   = []color.RGBA{
+		{0xff, 0x98, 0x00, 0xff},
+		{0x8e, 0x24, 0xaa, 0xff},
+		{0x43, 0xa0, 0x47, 0xff},
+		{0x79, 0x55, 0x48, 0xff},
+	}
+)
+
+// Render рисует график баров в PNG: цены (свечи или линия) со скользящими
+// средними и панель объёма под ними. Подписи - только ASCII (встроенный шрифт).
+func Render(w io.Writer, candles []indicators.Candle, opts Options) error {
+	if len(candles) <= opts.Warmup {
+		return fmt.Errorf("нет данных для графика")
+	}
+	width, height := opts.Width, opts.Height
+	if width <= 0 {
+		width = defaultWidth
+	}
+	if height <= 0 {
+		height = defaultHeight
+	}
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{background}, image.Point{}, draw.Src)
+
+	plotHeight := height - marginTop - marginBottom
+	volumeHeight := int(float64(plotHeight) * volumeShare)
+	price := image.Rect(marginLeft, marginTop, width-marginRight, marginTop+plotHeight-volumeHeight-panelGap)
+	volume := image.Rect(marginLeft, price.Max.Y+panelGap, width-marginRight, marginTop+plotHeight)
+
+	// Скользящие средние выравниваются по концу ряда баров.
+	closes := indicators.CandleCloses(candles)
+	averages := make([][]float64, len(opts.MAs))
+	for i, ma := range opts.MAs {
+		var series []float64
+		var err error
+		if ma.Exponential {
+			series, err = indicators.EMASeries(closes, ma.Period)
+		} else {
+			series, err = indicators.SMASeries(closes, ma.Period)
+		}
+		if err == nil {
+			averages[i] = series
+		}
+	}
+	candles = candles[opts.Warmup:]
+	for i, series := range averages {
+		if len(series) > len(candles) {
+			averages[i] = series[len(series)-len(candles):]
+		}
+	}
+
+	low, high := candles[0].Low, candles[0].High
+	maxVolume := 0.0
+	for _, c := range candles {
+		low, high = min(low, c.Low), max(high, c.High)
+		maxVolume = max(maxVolume, c.Volume)
+	}
+	for _, series := range averages {
+		for _, v := range series {
+			low, high = min(low, v), max(high, v)
+		}
+	}
+	if pad := (high - low) * 0.05; pad > 0 {
+		low, high = low-pad, high+pad
+	} else {
+		low, high = low-1, high+1
+	}
+
+	step := float64(price.Dx()) / float64(len(candles))
+	x := func(i int) int { return price.Min.X + int(step*(float64(i)+0.5)) }
+	y := func(v float64) int {
+		return price.Max.Y - int((v-low)/(high-low)*float64(price.Dy()))
+	}
+
+	// Сетка и шкала цен
+	for i := 0; i <= priceTicks; i++ {
+		v := low + (high-low)*float64(i)/priceTicks
+		py := y(v)
+		hline(img, price.Min.X, price.Max.X, py, gridColor)
+		drawText(img, price.Max.X+6, py+4, formatPrice(v), textColor)
+	}
+	hline(img, volume.Min.X, volume.Max.X, volume.Max.Y, gridColor)
+
+	// Шкала времени
+	span := candles[len(candles)-1].Time.Sub(candles[0].Time)
+	layout := "15:04"
+	if span > 24*time.Hour {
+		layout = "02.01"
+	}
+	for i := 0; i < timeTicks; i++ {
+		idx := i * (len(candles) - 1) / max(timeTicks-1, 1)
+		px := x(idx)
+		vline(img, px, price.Min.Y, volume.Max.Y, gridColor)
+		label := candles[idx].Time.In(loc).Format(layout)
+		lx := min(max(px-len(label)*7/2, 0), width-len(label)*7)
+		drawText(img, lx, height-6, label, textColor)
+	}
+
+	// Объём
+	for i, c := range candles {
+		if maxVolume == 0 {
+			break
+		}
+		top := volume.Max.Y - int(c.Volume/maxVolume*float64(volume.Dy()))
+		col := volumeUp
+		if c.Close < c.Open {
+			col = volumeDown
+		}
+		half := max(int(step*0.35), 1)
+		fillRect(img, image.Rect(x(i)-half, top, x(i)+half+1, volume.Max.Y), col)
+	}
+
+	// Цена
+	if opts.Style == Line {
+		for i := 1; i < len(candles); i++ {
+			drawLine(img, x(i-1), y(candles[i-1].Close), x(i), y(candles[i].Close), lineColor, 2)
+		}
+	} else {
+		half := max(int(step*0.35), 1)
+		for i, c := range candles {
+			col := upColor
+			if c.Close < c.Open {
+				col = downColor
+			}
+			vline(img, x(i), y(c.High), y(c.Low), col)
+			top, bottom := y(max(c.Open, c.Close)), y(min(c.Open, c.Close))
+			fillRect(img, image.Rect(x(i)-half, top, x(i)+half+1, max(bottom, top+1)), col)
+		}
+	}
+
+	// Скользящие средние
+	legendX := marginLeft + len(opts.Title)*7 + 16
+	for i, series := range averages {
+		if series == nil {
+			continue
+		}
+		col := maColors[i%len(maColors)]
+		offset := len(candles) - len(series)
+		for j := 1; j < len(series); j++ {
+			drawLine(img, x(offset+j-1), y(series[j-1]), x(offset+j), y(series[j]), col, 1)
+		}
+		label := opts.MAs[i].Label()
+		drawText(img, legendX, 18, label, col)
+		legendX += (len(label) + 2) * 7
+	}
+
+	drawText(img, marginLeft, 18, opts.Title, textColor)
+	return png.Encode(w, img)
+}
+
+// formatPrice подписывает уровень шкалы цен с точностью по величине цены.
+func formatPrice(v float64) string {
+	switch {
+	case math.Abs(v) >= 1000:
+		return fmt.Sprintf("%.0f", v)
+	case math.Abs(v) >= 10:
+		return fmt.Sprintf("%.2f", v)
+	default:
+		return fmt.Sprintf("%.4f", v)
+	}
+}
+
+func fillRect(img *image.RGBA, r image.Rectangle, c color.Color) {
+	draw.Draw(img, r.Intersect(img.Bounds()), &image.Uniform{c}, image.Point{}, draw.Over)
+}
+
+func hline(img *image.RGBA, x0, x1, y int, c color.RGBA) {
+	for x := x0; x <= x1; x++ {
+		img.SetRGBA(x, y, c)
+	}
+}
+
+func vline(img *image.RGBA, x, y0, y1 int, c color.RGBA) {
+	if y0 > y1 {
+		y0, y1 = y1, y0
+	}
+	for y := y0; y <= y1; y++ {
+		img.SetRGBA(x, y, c)
+	}
+}
+
+// drawLine рисует отрезок толщиной thickness пикселей.
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA, thickness int) {
+	steps := max(abs(x1-x0), abs(y1-y0), 1)
+	for i := 0; i <= steps; i++ {
+		px := x0 + (x1-x0)*i/steps
+		py := y0 + (y1-y0)*i/steps
+		for dx := 0; dx < thickness; dx++ {
+			for dy := 0; dy < thickness; dy++ {
+				img.SetRGBA(px+dx, py+dy, c)
+			}
+		}
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// drawText выводит строку встроенным шрифтом 7x13; (x, y) - начало базовой линии.
+func drawText(img *image.RGBA, x, y int, s string, c color.Color) {
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(s)
+}
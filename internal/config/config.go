@@ -4,6 +4,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -12,6 +15,9 @@ import (
 type Config struct {
 	BotToken string
 	DB       DBConfig
+	SMTP     SMTPConfig
+	Analyzer AnalyzerConfig
+	Gaps     GapConfig
 }
 
 // DBConfig хранит конфигурацию для подключения к базе данных
@@ -23,6 +29,150 @@ type DBConfig struct {
 	Name     string
 }
 
+// SMTPConfig хранит настройки SMTP-сервера для email-уведомлений.
+// Если Host пуст, email-уведомления отключены.
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// AnalyzerConfig хранит настройки анализатора резких изменений цены.
+type AnalyzerConfig struct {
+	ChatID  int64 // Чат для уведомлений; 0 - изменения только пишутся в лог
+	Tickers []AnalyzerTicker
+}
+
+// AnalyzerTicker - параметры анализа одного тикера.
+type AnalyzerTicker struct {
+	Ticker        string
+	Interval      time.Duration // Период опроса цены
+	AveragePeriod time.Duration // Окно статистики (средней, отклонения, движения)
+	Detector      string        // Детектор аномалий, см. DetectorThresholds
+	Threshold     float64       // Порог детектора: проценты для pct, σ для zscore и ewma, ATR для atr
+}
+
+// Детекторы аномалий анализатора.
+const (
+	DetectorPercent = "pct"    // Отклонение от средней за окно, %
+	DetectorZScore  = "zscore" // Отклонение от средней в стандартных отклонениях за окно
+	DetectorEWMA    = "ewma"   // Отклонение от экспоненциальной средней в её стандартных отклонениях
+	DetectorATR     = "atr"    // Движение за окно в единицах ATR
+)
+
+// DetectorThresholds - пороги детекторов по умолчанию.
+var DetectorThresholds = map[string]float64{
+	DetectorPercent: 0.42,
+	DetectorZScore:  3,
+	DetectorEWMA:    3,
+	DetectorATR:     2,
+}
+
+// GapConfig хранит настройки отчёта о гэпах открытия.
+type GapConfig struct {
+	Open        time.Duration // Время открытия основной сессии MOEX от полуночи по Москве
+	Close       time.Duration // Время окончания торгов от полуночи по Москве: около него берётся цена закрытия
+	ReportDelay time.Duration // Через сколько после открытия считать гэпы и рассылать отчёт
+}
+
+// Параметры по умолчанию (если в ANALYZER_TICKERS, GAP_OPEN, GAP_CLOSE и GAP_REPORT_DELAY они не указаны).
+const (
+	defaultAnalyzerTickers  = "LKOH"
+	defaultAnalyzerInterval = 10 * time.Second
+	defaultAnalyzerAverage  = 5 * time.Minute
+
+	defaultGapOpen        = "10:00"
+	defaultGapClose       = "23:50" // Конец вечерней сессии MOEX
+	defaultGapReportDelay = 15 * time.Minute
+)
+
+// NewAnalyzerTicker возвращает параметры анализа тикера по умолчанию.
+func NewAnalyzerTicker(ticker string) AnalyzerTicker {
+	return AnalyzerTicker{
+		Ticker:        ticker,
+		Interval:      defaultAnalyzerInterval,
+		AveragePeriod: defaultAnalyzerAverage,
+		Detector:      DetectorPercent,
+		Threshold:     DetectorThresholds[DetectorPercent],
+	}
+}
+
+// parseAnalyzerTickers разбирает список вида "LKOH:10s:5m:0.42,SBER:30s:1h:3:zscore".
+// Для каждого тикера можно указать период опроса, окно статистики, порог и детектор;
+// пропущенные значения берутся по умолчанию (порог - по умолчанию для детектора).
+func parseAnalyzerTickers(s string) ([]AnalyzerTicker, error) {
+	var tickers []AnalyzerTicker
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.Split(item, ":")
+		if len(parts) > 5 {
+			return nil, fmt.Errorf("ANALYZER_TICKERS: неверный формат %q, ожидается ТИКЕР[:ОПРОС[:ОКНО[:ПОРОГ[:ДЕТЕКТОР]]]]", item)
+		}
+		t := NewAnalyzerTicker(strings.ToUpper(parts[0]))
+		var err error
+		if len(parts) > 1 && parts[1] != "" {
+			if t.Interval, err = time.ParseDuration(parts[1]); err != nil || t.Interval <= 0 {
+				return nil, fmt.Errorf("ANALYZER_TICKERS: неверный период опроса %q для %s", parts[1], t.Ticker)
+			}
+		}
+		if len(parts) > 2 && parts[2] != "" {
+			if t.AveragePeriod, err = time.ParseDuration(parts[2]); err != nil || t.AveragePeriod <= 0 {
+				return nil, fmt.Errorf("ANALYZER_TICKERS: неверное окно %q для %s", parts[2], t.Ticker)
+			}
+		}
+		// Детектор разбираем раньше порога: явный порог заменяет порог детектора по умолчанию.
+		if len(parts) > 4 && parts[4] != "" {
+			threshold, ok := DetectorThresholds[parts[4]]
+			if !ok {
+				return nil, fmt.Errorf("ANALYZER_TICKERS: неизвестный детектор %q для %s", parts[4], t.Ticker)
+			}
+			t.Detector, t.Threshold = parts[4], threshold
+		}
+		if len(parts) > 3 && parts[3] != "" {
+			if t.Threshold, err = strconv.ParseFloat(parts[3], 64); err != nil || t.Threshold <= 0 {
+				return nil, fmt.Errorf("ANALYZER_TICKERS: неверный порог %q для %s", parts[3], t.Ticker)
+			}
+		}
+		tickers = append(tickers, t)
+	}
+	return tickers, nil
+}
+
+// loadGapConfig разбирает настройки отчёта о гэпах; пустые значения заменяются значениями по умолчанию.
+func loadGapConfig(open, close, delay string) (GapConfig, error) {
+	cfg := GapConfig{ReportDelay: defaultGapReportDelay}
+	var err error
+	if cfg.Open, err = parseClock("GAP_OPEN", open, defaultGapOpen); err != nil {
+		return GapConfig{}, err
+	}
+	if cfg.Close, err = parseClock("GAP_CLOSE", close, defaultGapClose); err != nil {
+		return GapConfig{}, err
+	}
+	if delay != "" {
+		if cfg.ReportDelay, err = time.ParseDuration(delay); err != nil || cfg.ReportDelay <= 0 {
+			return GapConfig{}, fmt.Errorf("GAP_REPORT_DELAY: неверная задержка %q", delay)
+		}
+	}
+	return cfg, nil
+}
+
+// parseClock разбирает время суток ЧЧ:ММ переменной name в смещение от полуночи.
+func parseClock(name, value, fallback string) (time.Duration, error) {
+	if value == "" {
+		value = fallback
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: неверное время %q, ожидается ЧЧ:ММ", name, value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
 // LoadConfig загружает конфигурацию из переменных окружения и .env файла
 func LoadConfig() (*Config, error) {
 	// Загружаем переменные из .env файла.
@@ -43,6 +193,40 @@ func LoadConfig() (*Config, error) {
 			Port:     os.Getenv("DB_PORT"),
 			Name:     os.Getenv("DB_NAME"),
 		},
+		SMTP: SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USER"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+		},
+	}
+	if cfg.SMTP.Port == "" {
+		cfg.SMTP.Port = "587"
+	}
+
+	// Анализатор: ANALYZER_CHAT_ID - куда слать уведомления,
+	// ANALYZER_TICKERS - тикеры с параметрами (см. parseAnalyzerTickers).
+	if chatID := os.Getenv("ANALYZER_CHAT_ID"); chatID != "" {
+		cfg.Analyzer.ChatID, err = strconv.ParseInt(chatID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("ANALYZER_CHAT_ID должен быть числовым ID чата: %w", err)
+		}
+	}
+	tickers, ok := os.LookupEnv("ANALYZER_TICKERS")
+	if !ok {
+		tickers = defaultAnalyzerTickers
+	}
+	cfg.Analyzer.Tickers, err = parseAnalyzerTickers(tickers)
+	if err != nil {
+		return nil, err
+	}
+
+	// Гэпы: GAP_OPEN и GAP_CLOSE - время открытия и окончания торгов по Москве (ЧЧ:ММ),
+	// GAP_REPORT_DELAY - задержка отчёта после открытия.
+	cfg.Gaps, err = loadGapConfig(os.Getenv("GAP_OPEN"), os.Getenv("GAP_CLOSE"), os.Getenv("GAP_REPORT_DELAY"))
+	if err != nil {
+		return nil, err
 	}
 
 	// Проверяем, что все критически важные переменные загружены
@@ -53,5 +237,9 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("одна или несколько переменных окружения БД не установлены (DB_USER, DB_PASSWORD, DB_HOST, DB_PORT, DB_NAME)")
 	}
 
+	if cfg.SMTP.Host != "" && cfg.SMTP.From == "" {
+		return nil, fmt.Errorf("SMTP_FROM не установлен, хотя задан SMTP_HOST")
+	}
+
 	return cfg, nil
 }
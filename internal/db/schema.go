@@ -0,0 +1,198 @@
+// TradeTGBot/internal/db/schema.go
+package db
+
+import (
+	"fmt"
+	"log"
+)
+
+// schema - DDL таблиц приложения. Все выражения идемпотентны,
+// поэтому Migrate можно безопасно вызывать при каждом запуске.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS stock_prices (
+		id        SERIAL PRIMARY KEY,
+		ticker    TEXT NOT NULL,
+		price     DOUBLE PRECISION NOT NULL,
+		timestamp TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`,
+	`CREATE INDEX IF NOT EXISTS stock_prices_ticker_timestamp_idx ON stock_prices (ticker, timestamp)`,
+	`CREATE TABLE IF NOT EXISTS alerts (
+		id         SERIAL PRIMARY KEY,
+		chat_id    BIGINT NOT NULL,
+		kind       TEXT NOT NULL,
+		ticker     TEXT NOT NULL DEFAULT '',
+		target     DOUBLE PRECISION NOT NULL DEFAULT 0,
+		direction  TEXT NOT NULL DEFAULT '',
+		params     TEXT NOT NULL DEFAULT '{}',
+		peak       DOUBLE PRECISION NOT NULL DEFAULT 0,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`,
+	`CREATE INDEX IF NOT EXISTS alerts_chat_id_idx ON alerts (chat_id)`,
+	`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active'`,
+	`CREATE TABLE IF NOT EXISTS notification_outbox (
+		id              SERIAL PRIMARY KEY,
+		chat_id         BIGINT NOT NULL,
+		alert_id        INTEGER,
+		text            TEXT NOT NULL,
+		parse_mode      TEXT NOT NULL DEFAULT '',
+		status          TEXT NOT NULL DEFAULT 'pending',
+		attempts        INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		last_error      TEXT NOT NULL DEFAULT '',
+		created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+		delivered_at    TIMESTAMPTZ
+	)`,
+	`CREATE INDEX IF NOT EXISTS notification_outbox_due_idx ON notification_outbox (next_attempt_at) WHERE status = 'pending'`,
+	`CREATE TABLE IF NOT EXISTS alert_history (
+		id              SERIAL PRIMARY KEY,
+		alert_id        INTEGER NOT NULL,
+		chat_id         BIGINT NOT NULL,
+		ticker          TEXT NOT NULL DEFAULT '',
+		kind            TEXT NOT NULL,
+		definition      TEXT NOT NULL,
+		params          TEXT NOT NULL DEFAULT '{}',
+		trigger_price   DOUBLE PRECISION NOT NULL DEFAULT 0,
+		triggered_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+		notification_id INTEGER
+	)`,
+	`CREATE INDEX IF NOT EXISTS alert_history_chat_idx ON alert_history (chat_id, triggered_at DESC)`,
+	`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS snoozed_until TIMESTAMPTZ`,
+	`ALTER TABLE alert_history ADD COLUMN IF NOT EXISTS target DOUBLE PRECISION NOT NULL DEFAULT 0`,
+	`ALTER TABLE alert_history ADD COLUMN IF NOT EXISTS direction TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE alert_history ADD COLUMN IF NOT EXISTS action TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE alert_history ADD COLUMN IF NOT EXISTS action_at TIMESTAMPTZ`,
+	`CREATE INDEX IF NOT EXISTS alert_history_notification_idx ON alert_history (notification_id)`,
+	`CREATE TABLE IF NOT EXISTS chat_settings (
+		chat_id       BIGINT PRIMARY KEY,
+		timezone      TEXT NOT NULL DEFAULT 'Europe/Moscow',
+		quiet_enabled BOOLEAN NOT NULL DEFAULT false,
+		quiet_start   INTEGER NOT NULL DEFAULT 0,
+		quiet_end     INTEGER NOT NULL DEFAULT 0
+	)`,
+	`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS urgent BOOLEAN NOT NULL DEFAULT false`,
+	`ALTER TABLE notification_outbox ADD COLUMN IF NOT EXISTS urgent BOOLEAN NOT NULL DEFAULT false`,
+	`ALTER TABLE notification_outbox ADD COLUMN IF NOT EXISTS deferred BOOLEAN NOT NULL DEFAULT false`,
+	`CREATE TABLE IF NOT EXISTS notification_routes (
+		id         SERIAL PRIMARY KEY,
+		chat_id    BIGINT NOT NULL,
+		channel    TEXT NOT NULL,
+		target     TEXT NOT NULL DEFAULT '',
+		secret     TEXT NOT NULL DEFAULT '',
+		filter     TEXT NOT NULL DEFAULT 'all',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		UNIQUE (chat_id, channel, target)
+	)`,
+	`ALTER TABLE notification_outbox ADD COLUMN IF NOT EXISTS channel TEXT NOT NULL DEFAULT 'telegram'`,
+	`ALTER TABLE notification_outbox ADD COLUMN IF NOT EXISTS route_id INTEGER`,
+	`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS user_id BIGINT NOT NULL DEFAULT 0`,
+	`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS user_name TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE alert_history ADD COLUMN IF NOT EXISTS user_id BIGINT NOT NULL DEFAULT 0`,
+	`ALTER TABLE alert_history ADD COLUMN IF NOT EXISTS user_name TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS alerts_admin_only BOOLEAN NOT NULL DEFAULT false`,
+	`ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS settings_admin_only BOOLEAN NOT NULL DEFAULT false`,
+	`ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS summary_threshold INTEGER NOT NULL DEFAULT 5`,
+	`CREATE TABLE IF NOT EXISTS spike_subscriptions (
+		id         SERIAL PRIMARY KEY,
+		chat_id    BIGINT NOT NULL,
+		ticker     TEXT NOT NULL,
+		threshold  DOUBLE PRECISION NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		UNIQUE (chat_id, ticker)
+	)`,
+	`ALTER TABLE spike_subscriptions ADD COLUMN IF NOT EXISTS detector TEXT NOT NULL DEFAULT 'pct'`,
+	`CREATE TABLE IF NOT EXISTS gap_subscriptions (
+		id         SERIAL PRIMARY KEY,
+		chat_id    BIGINT NOT NULL,
+		ticker     TEXT NOT NULL DEFAULT '',
+		min_gap    DOUBLE PRECISION NOT NULL DEFAULT 0,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		UNIQUE (chat_id, ticker)
+	)`,
+	`CREATE TABLE IF NOT EXISTS opening_gaps (
+		day        DATE NOT NULL,
+		ticker     TEXT NOT NULL,
+		prev_close DOUBLE PRECISION NOT NULL,
+		open_price DOUBLE PRECISION NOT NULL,
+		gap        DOUBLE PRECISION NOT NULL,
+		PRIMARY KEY (day, ticker)
+	)`,
+	`CREATE TABLE IF NOT EXISTS digest_settings (
+		chat_id        BIGINT PRIMARY KEY,
+		daily_enabled  BOOLEAN NOT NULL DEFAULT false,
+		daily_time     INTEGER NOT NULL DEFAULT 1140,
+		weekly_enabled BOOLEAN NOT NULL DEFAULT false,
+		weekly_day     INTEGER NOT NULL DEFAULT 5,
+		weekly_time    INTEGER NOT NULL DEFAULT 1140,
+		sections       TEXT NOT NULL DEFAULT 'prices,alerts,movers',
+		tickers        TEXT NOT NULL DEFAULT '',
+		last_daily     DATE,
+		last_weekly    DATE
+	)`,
+	`CREATE TABLE IF NOT EXISTS candles_1m (
+		ticker TEXT NOT NULL,
+		bucket TIMESTAMPTZ NOT NULL,
+		open   DOUBLE PRECISION NOT NULL,
+		high   DOUBLE PRECISION NOT NULL,
+		low    DOUBLE PRECISION NOT NULL,
+		close  DOUBLE PRECISION NOT NULL,
+		ticks  INTEGER NOT NULL,
+		PRIMARY KEY (ticker, bucket)
+	)`,
+	`CREATE TABLE IF NOT EXISTS candles_5m (
+		ticker TEXT NOT NULL,
+		bucket TIMESTAMPTZ NOT NULL,
+		open   DOUBLE PRECISION NOT NULL,
+		high   DOUBLE PRECISION NOT NULL,
+		low    DOUBLE PRECISION NOT NULL,
+		close  DOUBLE PRECISION NOT NULL,
+		ticks  INTEGER NOT NULL,
+		PRIMARY KEY (ticker, bucket)
+	)`,
+	`CREATE TABLE IF NOT EXISTS candles_1h (
+		ticker TEXT NOT NULL,
+		bucket TIMESTAMPTZ NOT NULL,
+		open   DOUBLE PRECISION NOT NULL,
+		high   DOUBLE PRECISION NOT NULL,
+		low    DOUBLE PRECISION NOT NULL,
+		close  DOUBLE PRECISION NOT NULL,
+		ticks  INTEGER NOT NULL,
+		PRIMARY KEY (ticker, bucket)
+	)`,
+	`CREATE TABLE IF NOT EXISTS candles_1d (
+		ticker TEXT NOT NULL,
+		bucket TIMESTAMPTZ NOT NULL,
+		open   DOUBLE PRECISION NOT NULL,
+		high   DOUBLE PRECISION NOT NULL,
+		low    DOUBLE PRECISION NOT NULL,
+		close  DOUBLE PRECISION NOT NULL,
+		ticks  INTEGER NOT NULL,
+		PRIMARY KEY (ticker, bucket)
+	)`,
+	`CREATE TABLE IF NOT EXISTS candle_progress (
+		id      BOOLEAN PRIMARY KEY DEFAULT true CHECK (id),
+		last_id BIGINT NOT NULL
+	)`,
+	`ALTER TABLE alert_history ADD COLUMN IF NOT EXISTS tickers TEXT[] NOT NULL DEFAULT '{}'`,
+	`UPDATE alert_history SET tickers = ARRAY[ticker] WHERE tickers = '{}' AND ticker <> ''`,
+	`CREATE TABLE IF NOT EXISTS bot_prompts (
+		chat_id    BIGINT NOT NULL,
+		message_id INTEGER NOT NULL,
+		action     TEXT NOT NULL,
+		trigger_id INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		PRIMARY KEY (chat_id, message_id)
+	)`,
+	`ALTER TABLE alerts ADD COLUMN IF NOT EXISTS data_warned BOOLEAN NOT NULL DEFAULT false`,
+	`ALTER TABLE alert_history ADD COLUMN IF NOT EXISTS urgent BOOLEAN NOT NULL DEFAULT false`,
+}
+
+// Migrate создаёт недостающие таблицы и индексы.
+func Migrate() error {
+	for _, stmt := range schema {
+		if _, err := GlobalDB.Exec(stmt); err != nil {
+			return fmt.Errorf("ошибка при применении схемы БД: %w", err)
+		}
+	}
+	log.Println("Схема базы данных актуальна.")
+	return nil
+}
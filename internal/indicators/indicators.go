@@ -0,0 +1,318 @@
+// TradeTGBot/internal/indicators/indicators.go
+package indicators
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Point - значение цены в момент времени (тик из stock_prices).
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Resample группирует тики по интервалам timeframe и возвращает для каждого
+// интервала его начало и цену закрытия (последний тик) в хронологическом порядке.
+// Интервалы без тиков пропускаются. Точки должны быть отсортированы по времени.
+func Resample(points []Point, timeframe time.Duration) []Point {
+	var bars []Point
+	for _, p := range points {
+		bucket := p.Time.Truncate(timeframe)
+		if len(bars) > 0 && bars[len(bars)-1].Time.Equal(bucket) {
+			bars[len(bars)-1].Value = p.Value
+			continue
+		}
+		bars = append(bars, Point{Time: bucket, Value: p.Value})
+	}
+	return bars
+}
+
+// Closes возвращает цены закрытия интервалов timeframe (см. Resample).
+func Closes(points []Point, timeframe time.Duration) []float64 {
+	bars := Resample(points, timeframe)
+	closes := make([]float64, len(bars))
+	for i, b := range bars {
+		closes[i] = b.Value
+	}
+	return closes
+}
+
+// Candle - бар OHLC. Volume - число тиков за бар: объёма торгов в истории нет,
+// поэтому активность оценивается частотой обновления цены.
+type Candle struct {
+	Time                   time.Time
+	Open, High, Low, Close float64
+	Volume                 float64
+}
+
+// Candles группирует тики в бары длительностью timeframe в хронологическом порядке.
+// Интервалы без тиков пропускаются. Точки должны быть отсортированы по времени.
+func Candles(points []Point, timeframe time.Duration) []Candle {
+	var candles []Candle
+	for _, p := range points {
+		bucket := p.Time.Truncate(timeframe)
+		if n := len(candles); n > 0 && candles[n-1].Time.Equal(bucket) {
+			c := &candles[n-1]
+			c.High, c.Low, c.Close = max(c.High, p.Value), min(c.Low, p.Value), p.Value
+			c.Volume++
+			continue
+		}
+		candles = append(candles, Candle{Time: bucket, Open: p.Value, High: p.Value, Low: p.Value, Close: p.Value, Volume: 1})
+	}
+	return candles
+}
+
+// MergeCandles объединяет бары меньшего таймфрейма в бары длительностью timeframe,
+// которая должна быть кратна исходной. Бары должны быть отсортированы по времени.
+func MergeCandles(candles []Candle, timeframe time.Duration) []Candle {
+	var merged []Candle
+	for _, c := range candles {
+		bucket := c.Time.Truncate(timeframe)
+		if n := len(merged); n > 0 && merged[n-1].Time.Equal(bucket) {
+			m := &merged[n-1]
+			m.High, m.Low, m.Close = max(m.High, c.High), min(m.Low, c.Low), c.Close
+			m.Volume += c.Volume
+			continue
+		}
+		c.Time = bucket
+		merged = append(merged, c)
+	}
+	return merged
+}
+
+// CandleCloses возвращает цены закрытия баров.
+func CandleCloses(candles []Candle) []float64 {
+	closes := make([]float64, len(candles))
+	for i, c := range candles {
+		closes[i] = c.Close
+	}
+	return closes
+}
+
+// MeanStdDev возвращает среднее и стандартное отклонение (по генеральной совокупности).
+func MeanStdDev(values []float64) (mean, sd float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	for _, v := range values {
+		sd += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(sd / float64(len(values)))
+}
+
+// SMA возвращает простую скользящую среднюю последних period значений.
+func SMA(values []float64, period int) (float64, error) {
+	if err := checkPeriod(values, period); err != nil {
+		return 0, err
+	}
+	sum := 0.0
+	for _, v := range values[len(values)-period:] {
+		sum += v
+	}
+	return sum / float64(period), nil
+}
+
+// SMASeries возвращает ряд SMA, начиная с индекса period-1 исходного ряда.
+func SMASeries(values []float64, period int) ([]float64, error) {
+	if err := checkPeriod(values, period); err != nil {
+		return nil, err
+	}
+	sum := 0.0
+	for _, v := range values[:period] {
+		sum += v
+	}
+	series := make([]float64, 0, len(values)-period+1)
+	series = append(series, sum/float64(period))
+	for i := period; i < len(values); i++ {
+		sum += values[i] - values[i-period]
+		series = append(series, sum/float64(period))
+	}
+	return series, nil
+}
+
+// EMA возвращает экспоненциальную скользящую среднюю с коэффициентом 2/(period+1).
+// Начальное значение - SMA первых period значений.
+func EMA(values []float64, period int) (float64, error) {
+	series, err := EMASeries(values, period)
+	if err != nil {
+		return 0, err
+	}
+	return series[len(series)-1], nil
+}
+
+// EMASeries возвращает ряд EMA, начиная с индекса period-1 исходного ряда.
+func EMASeries(values []float64, period int) ([]float64, error) {
+	if err := checkPeriod(values, period); err != nil {
+		return nil, err
+	}
+	k := 2.0 / float64(period+1)
+	ema, _ := SMA(values[:period], period)
+	series := make([]float64, 0, len(values)-period+1)
+	series = append(series, ema)
+	for _, v := range values[period:] {
+		ema = v*k + ema*(1-k)
+		series = append(series, ema)
+	}
+	return series, nil
+}
+
+// RSI возвращает индекс относительной силы по Уайлдеру за period интервалов.
+func RSI(values []float64, period int) (float64, error) {
+	if period <= 0 || len(values) < period+1 {
+		return 0, fmt.Errorf("недостаточно данных для RSI(%d): нужно %d значений, есть %d", period, period+1, len(values))
+	}
+	var gain, loss float64
+	for i := 1; i <= period; i++ {
+		change := values[i] - values[i-1]
+		if change > 0 {
+			gain += change
+		} else {
+			loss -= change
+		}
+	}
+	gain /= float64(period)
+	loss /= float64(period)
+	for i := period + 1; i < len(values); i++ {
+		change := values[i] - values[i-1]
+		up, down := 0.0, 0.0
+		if change > 0 {
+			up = change
+		} else {
+			down = -change
+		}
+		gain = (gain*float64(period-1) + up) / float64(period)
+		loss = (loss*float64(period-1) + down) / float64(period)
+	}
+	if loss == 0 {
+		if gain == 0 {
+			return 50, nil // Цена не менялась - ни роста, ни падения
+		}
+		return 100, nil
+	}
+	rs := gain / loss
+	return 100 - 100/(1+rs), nil
+}
+
+// Bollinger возвращает среднюю линию и границы полос Боллинджера
+// (SMA ± k стандартных отклонений) по последним period значениям.
+func Bollinger(values []float64, period int, k float64) (middle, upper, lower float64, err error) {
+	middle, err = SMA(values, period)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	_, sd := MeanStdDev(values[len(values)-period:])
+	return middle, middle + k*sd, middle - k*sd, nil
+}
+
+func checkPeriod(values []float64, period int) error {
+	if period <= 0 {
+		return fmt.Errorf("период должен быть положительным, получено %d", period)
+	}
+	if len(values) < period {
+		return fmt.Errorf("недостаточно данных: нужно %d значений, есть %d", period, len(values))
+	}
+	return nil
+}
+
+// MACD возвращает линию MACD (EMA(fast) - EMA(slow)), сигнальную линию (EMA(signal)
+// от MACD) и гистограмму (их разность) для последнего значения ряда.
+func MACD(values []float64, fast, slow, signal int) (macd, signalLine, histogram float64, err error) {
+	if fast <= 0 || fast >= slow {
+		return 0, 0, 0, fmt.Errorf("быстрый период MACD должен быть меньше медленного: %d, %d", fast, slow)
+	}
+	if len(values) < slow+signal-1 {
+		return 0, 0, 0, fmt.Errorf("недостаточно данных для MACD(%d,%d,%d): нужно %d значений, есть %d",
+			fast, slow, signal, slow+signal-1, len(values))
+	}
+	fastEMA, err := EMASeries(values, fast)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	slowEMA, err := EMASeries(values, slow)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	// Ряды EMA начинаются с индексов fast-1 и slow-1 - выравниваем по концу.
+	line := make([]float64, len(slowEMA))
+	for i := range slowEMA {
+		line[i] = fastEMA[i+slow-fast] - slowEMA[i]
+	}
+	signalLine, err = EMA(line, signal)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	macd = line[len(line)-1]
+	return macd, signalLine, macd - signalLine, nil
+}
+
+// ATR возвращает средний истинный диапазон баров за period со сглаживанием Уайлдера.
+func ATR(candles []Candle, period int) (float64, error) {
+	if period <= 0 || len(candles) < period+1 {
+		return 0, fmt.Errorf("недостаточно данных для ATR(%d): нужно %d баров, есть %d", period, period+1, len(candles))
+	}
+	var atr float64
+	for i := 1; i < len(candles); i++ {
+		prev := candles[i-1].Close
+		c := candles[i]
+		tr := max(c.High-c.Low, math.Abs(c.High-prev), math.Abs(c.Low-prev))
+		if i <= period {
+			atr += tr / float64(period)
+			continue
+		}
+		atr = (atr*float64(period-1) + tr) / float64(period)
+	}
+	return atr, nil
+}
+
+// Stochastic возвращает стохастический осциллятор: %K за kPeriod баров и %D -
+// SMA последних dPeriod значений %K.
+func Stochastic(candles []Candle, kPeriod, dPeriod int) (k, d float64, err error) {
+	if kPeriod <= 0 || dPeriod <= 0 || len(candles) < kPeriod+dPeriod-1 {
+		return 0, 0, fmt.Errorf("недостаточно данных для Stochastic(%d,%d): нужно %d баров, есть %d",
+			kPeriod, dPeriod, kPeriod+dPeriod-1, len(candles))
+	}
+	ks := make([]float64, 0, dPeriod)
+	for end := len(candles) - dPeriod + 1; end <= len(candles); end++ {
+		window := candles[end-kPeriod : end]
+		low, high := window[0].Low, window[0].High
+		for _, c := range window {
+			low, high = min(low, c.Low), max(high, c.High)
+		}
+		value := 50.0 // Цена не менялась - осциллятор посередине
+		if high > low {
+			value = (window[len(window)-1].Close - low) / (high - low) * 100
+		}
+		ks = append(ks, value)
+	}
+	d, _ = SMA(ks, dPeriod)
+	return ks[len(ks)-1], d, nil
+}
+
+// HistoricalVolatility возвращает историческую волатильность: стандартное отклонение
+// (выборочное) логарифмических доходностей последних period интервалов, приведённое
+// к году умножением на sqrt(periodsPerYear), в процентах.
+func HistoricalVolatility(values []float64, period int, periodsPerYear float64) (float64, error) {
+	if period < 2 || len(values) < period+1 {
+		return 0, fmt.Errorf("недостаточно данных для HV(%d): нужно %d значений, есть %d", period, period+1, len(values))
+	}
+	returns := make([]float64, 0, period)
+	for i := len(values) - period; i < len(values); i++ {
+		if values[i-1] <= 0 || values[i] <= 0 {
+			return 0, fmt.Errorf("неположительная цена в ряду")
+		}
+		returns = append(returns, math.Log(values[i]/values[i-1]))
+	}
+	mean, _ := MeanStdDev(returns)
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	variance /= float64(period - 1)
+	return math.Sqrt(variance*periodsPerYear) * 100, nil
+}
@@ -0,0 +1,295 @@
+// TradeTGBot/internal/indicators/indicators_test.go
+package indicators
+
+import (
+	"math"
+	"testing"
+)
+
+// approx сравнивает значения с точностью tol.
+func approx(got, want, tol float64) bool {
+	return math.Abs(got-want) <= tol
+}
+
+// wilderCloses - пример расчёта RSI(14) из StockCharts (ChartSchool, «Relative Strength Index»).
+var wilderCloses = []float64{
+	44.34, 44.09, 44.15, 43.61, 44.33, 44.83, 45.10, 45.42, 45.84, 46.08,
+	45.89, 46.03, 45.61, 46.28, 46.28, 46.00, 46.03, 46.41, 46.22, 45.64,
+	46.21, 46.25, 45.71, 46.45, 45.78, 45.35, 44.03, 44.18, 44.22, 44.57,
+	43.42, 42.66, 43.13,
+}
+
+func TestRSIWilderExample(t *testing.T) {
+	// В таблице StockCharts средние округлены до сотых, поэтому там 70.53, 66.32, ...;
+	// без округления первое значение: средний рост 3.34/14, среднее падение 1.40/14,
+	// RS = 2.3857, RSI = 70.46.
+	want := []float64{
+		70.46, 66.25, 66.48, 69.35, 66.29, 57.92, 62.88, 63.21, 56.01, 62.34,
+		54.67, 50.39, 40.02, 41.49, 41.90, 45.50, 37.32, 33.09, 37.79,
+	}
+	for i, w := range want {
+		n := 15 + i
+		got, err := RSI(wilderCloses[:n], 14)
+		if err != nil {
+			t.Fatalf("RSI по %d значениям: %v", n, err)
+		}
+		if !approx(got, w, 0.005) {
+			t.Errorf("RSI по %d значениям = %.4f, want %.2f", n, got, w)
+		}
+	}
+}
+
+// emaCloses - пример расчёта EMA(10) из StockCharts (ChartSchool, «Moving Averages - Simple and Exponential»).
+var emaCloses = []float64{
+	22.27, 22.19, 22.08, 22.17, 22.18, 22.13, 22.23, 22.43, 22.24, 22.29,
+	22.15, 22.39, 22.38, 22.61, 23.36, 24.05, 23.75, 23.83, 23.95, 23.63,
+	23.82, 23.87, 23.65, 23.19, 23.10, 23.33, 22.68, 23.10, 22.40, 22.17,
+}
+
+func TestEMAExample(t *testing.T) {
+	// Первое значение - SMA(10) = 22.22, далее EMA с коэффициентом 2/11.
+	want := []float64{
+		22.22, 22.21, 22.24, 22.27, 22.33, 22.52, 22.80, 22.97, 23.13, 23.28,
+		23.34, 23.43, 23.51, 23.53, 23.47, 23.40, 23.39, 23.26, 23.23, 23.08, 22.92,
+	}
+	series, err := EMASeries(emaCloses, 10)
+	if err != nil {
+		t.Fatalf("EMASeries: %v", err)
+	}
+	if len(series) != len(want) {
+		t.Fatalf("длина ряда EMA = %d, want %d", len(series), len(want))
+	}
+	for i, w := range want {
+		if !approx(series[i], w, 0.005) {
+			t.Errorf("EMA(10) на %d-м значении = %.4f, want %.2f", 10+i, series[i], w)
+		}
+	}
+	if got, err := EMA(emaCloses, 10); err != nil || got != series[len(series)-1] {
+		t.Errorf("EMA(10) = %v (%v), want последнее значение ряда %v", got, err, series[len(series)-1])
+	}
+}
+
+func TestBollinger(t *testing.T) {
+	// Пример стандартного отклонения из Википедии: у 2, 4, 4, 4, 5, 5, 7, 9 среднее 5,
+	// стандартное отклонение совокупности ровно 2 (выборочное - 2.14). Полосы
+	// Боллинджера строятся по отклонению совокупности: 5 ± 2·2.
+	m, u, l, err := Bollinger([]float64{2, 4, 4, 4, 5, 5, 7, 9}, 8, 2)
+	if err != nil || !approx(m, 5, 1e-12) || !approx(u, 9, 1e-12) || !approx(l, 1, 1e-12) {
+		t.Errorf("Bollinger(8, 2) = %v/%v/%v (%v), want 5/9/1", m, u, l, err)
+	}
+
+	// Bollinger(20, 2) на данных примера EMA: отклонение совокупности, посчитанное
+	// независимо (Python statistics.pstdev); с выборочным верхняя полоса была бы 24.1627 и 24.4683.
+	tests := []struct {
+		n                    int // Сколько первых значений берётся
+		middle, upper, lower float64
+	}{
+		{20, 22.7155, 24.1261, 21.3049},
+		{25, 23.0525, 24.4676, 21.6374},
+		{30, 23.1705, 24.4355, 21.9055},
+	}
+	for _, tt := range tests {
+		m, u, l, err := Bollinger(emaCloses[:tt.n], 20, 2)
+		if err != nil || !approx(m, tt.middle, 5e-5) || !approx(u, tt.upper, 5e-5) || !approx(l, tt.lower, 5e-5) {
+			t.Errorf("Bollinger(20, 2) по %d значениям = %.4f/%.4f/%.4f (%v), want %.4f/%.4f/%.4f",
+				tt.n, m, u, l, err, tt.middle, tt.upper, tt.lower)
+		}
+	}
+}
+
+func TestRSIEdgeCases(t *testing.T) {
+	if _, err := RSI(wilderCloses[:14], 14); err == nil {
+		t.Error("RSI(14) по 14 значениям: нужна ошибка, для 14 изменений нужно 15 цен")
+	}
+	if _, err := RSI(wilderCloses, 0); err == nil {
+		t.Error("RSI с нулевым периодом: нужна ошибка")
+	}
+	tests := []struct {
+		name   string
+		values []float64
+		want   float64
+	}{
+		{"цена не менялась", []float64{100, 100, 100, 100, 100}, 50},
+		{"только рост", []float64{100, 101, 102, 103, 104}, 100},
+		{"только падение", []float64{104, 103, 102, 101, 100}, 0},
+	}
+	for _, tt := range tests {
+		got, err := RSI(tt.values, 4)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: RSI = %v (%v), want %v", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestMACD(t *testing.T) {
+	// На линейном ряду EMA с начальным значением SMA отстаёт ровно на (period-1)/2 шага,
+	// поэтому MACD(12,26,9) = (26-1)/2 - (12-1)/2 = 7 шагов, сигнальная линия - тоже 7.
+	ramp := make([]float64, 60)
+	for i := range ramp {
+		ramp[i] = 100 + 0.5*float64(i)
+	}
+	macd, signal, hist, err := MACD(ramp, 12, 26, 9)
+	if err != nil {
+		t.Fatalf("MACD: %v", err)
+	}
+	if !approx(macd, 3.5, 1e-9) || !approx(signal, 3.5, 1e-9) || !approx(hist, 0, 1e-9) {
+		t.Errorf("MACD на ряду с шагом 0.5 = %v/%v/%v, want 3.5/3.5/0", macd, signal, hist)
+	}
+
+	// Произвольный ряд сверяем с прямым расчётом по определению.
+	values := append(append([]float64{}, wilderCloses...), wilderCloses...)
+	ema := func(values []float64, period int) []float64 {
+		k := 2 / float64(period+1)
+		out := make([]float64, len(values))
+		sum := 0.0
+		for i, v := range values {
+			switch {
+			case i < period-1:
+				sum += v
+				out[i] = math.NaN()
+			case i == period-1:
+				out[i] = (sum + v) / float64(period)
+			default:
+				out[i] = v*k + out[i-1]*(1-k)
+			}
+		}
+		return out
+	}
+	fast, slow := ema(values, 12), ema(values, 26)
+	line := make([]float64, 0, len(values))
+	for i := 25; i < len(values); i++ {
+		line = append(line, fast[i]-slow[i])
+	}
+	sig := ema(line, 9)
+	wantMACD, wantSignal := line[len(line)-1], sig[len(sig)-1]
+	macd, signal, hist, err = MACD(values, 12, 26, 9)
+	if err != nil {
+		t.Fatalf("MACD: %v", err)
+	}
+	if !approx(macd, wantMACD, 1e-9) || !approx(signal, wantSignal, 1e-9) || !approx(hist, wantMACD-wantSignal, 1e-9) {
+		t.Errorf("MACD = %v/%v/%v, want %v/%v/%v", macd, signal, hist, wantMACD, wantSignal, wantMACD-wantSignal)
+	}
+
+	// Минимум данных - slow+signal-1 значений.
+	if _, _, _, err := MACD(ramp[:33], 12, 26, 9); err == nil {
+		t.Error("MACD по 33 значениям: нужна ошибка")
+	}
+	if _, _, _, err := MACD(ramp[:34], 12, 26, 9); err != nil {
+		t.Errorf("MACD по 34 значениям: %v", err)
+	}
+	if _, _, _, err := MACD(ramp, 26, 12, 9); err == nil {
+		t.Error("MACD с быстрым периодом больше медленного: нужна ошибка")
+	}
+	flat := make([]float64, 40)
+	for i := range flat {
+		flat[i] = 250
+	}
+	if macd, signal, hist, err := MACD(flat, 12, 26, 9); err != nil || macd != 0 || signal != 0 || hist != 0 {
+		t.Errorf("MACD на постоянном ряду = %v/%v/%v (%v), want 0/0/0", macd, signal, hist, err)
+	}
+}
+
+func TestATR(t *testing.T) {
+	candles := []Candle{
+		{High: 10.5, Low: 9.5, Close: 10},
+		{High: 11, Low: 10, Close: 10.5},     // TR = 1
+		{High: 12, Low: 10.5, Close: 11.5},   // TR = 1.5
+		{High: 11.8, Low: 11, Close: 11.2},   // TR = 0.8, ATR = (1+1.5+0.8)/3 = 1.1
+		{High: 14, Low: 13, Close: 13.5},     // Гэп: TR = 14-11.2 = 2.8, ATR = (1.1*2+2.8)/3
+		{High: 13.6, Low: 12.9, Close: 13.0}, // TR = 0.7
+	}
+	tests := []struct {
+		n    int
+		want float64
+	}{
+		{4, 1.1},
+		{5, 5.0 / 3},
+		{6, (5.0/3*2 + 0.7) / 3},
+	}
+	for _, tt := range tests {
+		got, err := ATR(candles[:tt.n], 3)
+		if err != nil || !approx(got, tt.want, 1e-9) {
+			t.Errorf("ATR(3) по %d барам = %v (%v), want %v", tt.n, got, err, tt.want)
+		}
+	}
+	if _, err := ATR(candles[:3], 3); err == nil {
+		t.Error("ATR(3) по 3 барам: нужна ошибка, нужен ещё бар для первого закрытия")
+	}
+	flat := []Candle{{High: 5, Low: 5, Close: 5}, {High: 5, Low: 5, Close: 5}, {High: 5, Low: 5, Close: 5}}
+	if got, err := ATR(flat, 2); err != nil || got != 0 {
+		t.Errorf("ATR на постоянной цене = %v (%v), want 0", got, err)
+	}
+}
+
+func TestStochastic(t *testing.T) {
+	candles := []Candle{
+		{High: 10, Low: 8, Close: 9},
+		{High: 11, Low: 9, Close: 10},
+		{High: 12, Low: 10, Close: 11}, // %K = (11-8)/(12-8) = 75
+		{High: 11, Low: 9, Close: 9.5}, // %K = (9.5-9)/(12-9) = 16.67
+		{High: 13, Low: 10, Close: 12}, // %K = (12-9)/(13-9) = 75
+	}
+	k, d, err := Stochastic(candles, 3, 3)
+	if err != nil {
+		t.Fatalf("Stochastic: %v", err)
+	}
+	if !approx(k, 75, 1e-9) || !approx(d, (75+50.0/3+75)/3, 1e-9) {
+		t.Errorf("Stochastic(3,3) = %v/%v, want 75/%v", k, d, (75+50.0/3+75)/3)
+	}
+
+	if _, _, err := Stochastic(candles[:4], 3, 3); err == nil {
+		t.Error("Stochastic(3,3) по 4 барам: нужна ошибка")
+	}
+
+	// High равен Low на всём окне: деления на ноль нет, %K посередине.
+	flat := []Candle{{High: 7, Low: 7, Close: 7}, {High: 7, Low: 7, Close: 7}, {High: 7, Low: 7, Close: 7}}
+	k, d, err = Stochastic(flat, 2, 2)
+	if err != nil || k != 50 || d != 50 {
+		t.Errorf("Stochastic на постоянной цене = %v/%v (%v), want 50/50", k, d, err)
+	}
+	// Окно без диапазона в середине ряда: только этот %K равен 50.
+	mixed := []Candle{{High: 7, Low: 7, Close: 7}, {High: 7, Low: 7, Close: 7}, {High: 9, Low: 7, Close: 9}}
+	k, d, err = Stochastic(mixed, 2, 2)
+	if err != nil || k != 100 || d != 75 {
+		t.Errorf("Stochastic с пустым диапазоном в первом окне = %v/%v (%v), want 100/75", k, d, err)
+	}
+}
+
+func TestHistoricalVolatility(t *testing.T) {
+	// Доходности r, -r, r: среднее r/3, выборочная дисперсия 4r²/3.
+	r := math.Log(1.1)
+	got, err := HistoricalVolatility([]float64{100, 110, 100, 110}, 3, 252)
+	want := 2 * r / math.Sqrt(3) * math.Sqrt(252) * 100
+	if err != nil || !approx(got, want, 1e-9) {
+		t.Errorf("HV = %v (%v), want %v", got, err, want)
+	}
+	if got, err := HistoricalVolatility([]float64{100, 100, 100, 100}, 3, 252); err != nil || got != 0 {
+		t.Errorf("HV на постоянной цене = %v (%v), want 0", got, err)
+	}
+	if _, err := HistoricalVolatility([]float64{100, 110, 100}, 3, 252); err == nil {
+		t.Error("HV(3) по 3 значениям: нужна ошибка")
+	}
+	if _, err := HistoricalVolatility([]float64{100, 0, 100, 110}, 3, 252); err == nil {
+		t.Error("HV с нулевой ценой: нужна ошибка")
+	}
+}
+
+func TestMovingAveragesTooFewValues(t *testing.T) {
+	values := []float64{1, 2, 3}
+	if _, err := SMA(values, 4); err == nil {
+		t.Error("SMA(4) по 3 значениям: нужна ошибка")
+	}
+	if _, err := EMA(values, 4); err == nil {
+		t.Error("EMA(4) по 3 значениям: нужна ошибка")
+	}
+	if _, _, _, err := Bollinger(values, 4, 2); err == nil {
+		t.Error("Bollinger(4) по 3 значениям: нужна ошибка")
+	}
+	if got, err := SMA(values, 3); err != nil || got != 2 {
+		t.Errorf("SMA(3) = %v (%v), want 2", got, err)
+	}
+	// На постоянном ряду полосы Боллинджера сходятся к средней.
+	if m, u, l, err := Bollinger([]float64{5, 5, 5}, 3, 2); err != nil || m != 5 || u != 5 || l != 5 {
+		t.Errorf("Bollinger на постоянном ряду = %v/%v/%v (%v), want 5/5/5", m, u, l, err)
+	}
+}
@@ -0,0 +1,103 @@
+// TradeTGBot/internal/notify/email.go
+package notify
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"mime"
+	"net"
+	"net/smtp"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// emailSubjectLimit - максимальная длина темы письма в символах.
+const emailSubjectLimit = 80
+
+// htmlTag - тег разметки Telegram HTML (<b>, </i>, <a href="...">).
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// Email доставляет уведомления письмом через SMTP-сервер.
+type Email struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewEmail создаёт email-канал. Если username пуст, авторизация не используется
+// (например, для локального relay).
+func NewEmail(host, port, username, password, from string) *Email {
+	e := &Email{addr: net.JoinHostPort(host, port), from: from}
+	if username != "" {
+		e.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return e
+}
+
+// Send отправляет письмо на dest.Target. Тема - первая строка уведомления.
+func (e *Email) Send(dest Destination, msg Message) error {
+	if err := smtp.SendMail(e.addr, e.auth, e.from, []string{dest.Target}, e.compose(dest.Target, msg)); err != nil {
+		return fmt.Errorf("ошибка отправки письма на %s: %w", dest.Target, err)
+	}
+	return nil
+}
+
+// compose собирает письмо в формате RFC 5322 с телом в base64.
+func (e *Email) compose(to string, msg Message) []byte {
+	contentType := "text/plain"
+	if strings.EqualFold(msg.ParseMode, "HTML") {
+		contentType = "text/html"
+	}
+	date := msg.CreatedAt
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", e.from)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", emailSubject(msg)))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: %s; charset=utf-8\r\n", contentType)
+	b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+	body := msg.Text
+	if contentType == "text/html" {
+		// Разметка Telegram сохраняет переводы строк, HTML письма - нет.
+		body = `<div style="white-space: pre-wrap">` + body + `</div>`
+	}
+	encoded := base64.StdEncoding.EncodeToString([]byte(body))
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded + "\r\n")
+	return b.Bytes()
+}
+
+// emailSubject возвращает первую непустую строку уведомления без разметки.
+// В HTML-уведомлениях теги убираются, а сущности (&lt; и т.п.) раскрываются.
+func emailSubject(msg Message) string {
+	subject := "Уведомление TradeTGBot"
+	for _, line := range strings.Split(msg.Text, "\n") {
+		if strings.EqualFold(msg.ParseMode, "HTML") {
+			line = html.UnescapeString(htmlTag.ReplaceAllString(line, ""))
+		}
+		line = strings.Trim(strings.TrimSpace(line), "*_`")
+		if line != "" {
+			subject = line
+			break
+		}
+	}
+	if msg.Urgent {
+		subject = "[срочно] " + subject
+	}
+	if runes := []rune(subject); len(runes) > emailSubjectLimit {
+		subject = string(runes[:emailSubjectLimit-1]) + "…"
+	}
+	return subject
+}
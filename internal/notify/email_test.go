@@ -0,0 +1,214 @@
+// TradeTGBot/internal/notify/email_test.go
+package notify
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpSession - то, что фейковый SMTP-сервер получил за одно соединение.
+type smtpSession struct {
+	auth string // Декодированные данные AUTH PLAIN: "\x00user\x00password"
+	from string
+	rcpt []string
+	data string
+}
+
+// fakeSMTP принимает одно соединение на 127.0.0.1 и отвечает на команды,
+// которые использует smtp.SendMail. Результат сессии приходит в канал.
+func fakeSMTP(t *testing.T) (host, port string, session <-chan smtpSession) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	ch := make(chan smtpSession, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		r := bufio.NewReader(conn)
+		reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+		var s smtpSession
+		reply("220 localhost ESMTP test")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			cmd := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"):
+				reply("250-localhost")
+				reply("250 AUTH PLAIN")
+			case strings.HasPrefix(cmd, "AUTH PLAIN "):
+				decoded, _ := base64.StdEncoding.DecodeString(line[len("AUTH PLAIN "):])
+				s.auth = string(decoded)
+				reply("235 2.7.0 Authentication successful")
+			case strings.HasPrefix(cmd, "MAIL FROM:"):
+				s.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+				reply("250 OK")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				s.rcpt = append(s.rcpt, strings.Trim(line[len("RCPT TO:"):], "<>"))
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				s.data = data.String()
+				reply("250 OK")
+			case cmd == "QUIT":
+				reply("221 Bye")
+				ch <- s
+				return
+			default:
+				reply("502 Command not implemented")
+			}
+		}
+	}()
+
+	host, port, _ = net.SplitHostPort(ln.Addr().String())
+	return host, port, ch
+}
+
+func TestEmailSend(t *testing.T) {
+	tests := []struct {
+		name        string
+		msg         Message
+		subject     string
+		contentType string
+		body        string
+	}{
+		{
+			name:        "plain",
+			msg:         Message{Text: "📈 SBER достиг 320.00\nТекущая цена: 321.50"},
+			subject:     "📈 SBER достиг 320.00",
+			contentType: "text/plain; charset=utf-8",
+			body:        "📈 SBER достиг 320.00\nТекущая цена: 321.50",
+		},
+		{
+			name:        "urgent html",
+			msg:         Message{Text: "<b>GAZP</b> упал ниже 150", ParseMode: "HTML", Urgent: true},
+			subject:     "[срочно] GAZP упал ниже 150",
+			contentType: "text/html; charset=utf-8",
+			body:        `<div style="white-space: pre-wrap"><b>GAZP</b> упал ниже 150</div>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, port, session := fakeSMTP(t)
+			created := time.Date(2026, 3, 2, 10, 30, 0, 0, time.UTC)
+			tt.msg.CreatedAt = created
+
+			e := NewEmail(host, port, "bot", "secret", "bot@example.com")
+			if err := e.Send(Destination{Target: "user@example.com"}, tt.msg); err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+
+			var s smtpSession
+			select {
+			case s = <-session:
+			case <-time.After(5 * time.Second):
+				t.Fatal("SMTP-сессия не завершилась")
+			}
+			if s.auth != "\x00bot\x00secret" {
+				t.Errorf("AUTH PLAIN = %q", s.auth)
+			}
+			if s.from != "bot@example.com" {
+				t.Errorf("MAIL FROM = %q", s.from)
+			}
+			if len(s.rcpt) != 1 || s.rcpt[0] != "user@example.com" {
+				t.Errorf("RCPT TO = %q", s.rcpt)
+			}
+
+			m, err := mail.ReadMessage(strings.NewReader(s.data))
+			if err != nil {
+				t.Fatalf("разбор письма: %v", err)
+			}
+			if got := m.Header.Get("From"); got != "bot@example.com" {
+				t.Errorf("From = %q", got)
+			}
+			if got := m.Header.Get("To"); got != "user@example.com" {
+				t.Errorf("To = %q", got)
+			}
+			subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+			if err != nil || subject != tt.subject {
+				t.Errorf("Subject = %q (%v), want %q", subject, err, tt.subject)
+			}
+			if got, err := m.Header.Date(); err != nil || !got.Equal(created) {
+				t.Errorf("Date = %v (%v), want %v", got, err, created)
+			}
+			if got := m.Header.Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.contentType)
+			}
+			if got := m.Header.Get("Content-Transfer-Encoding"); got != "base64" {
+				t.Errorf("Content-Transfer-Encoding = %q", got)
+			}
+			body, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, m.Body))
+			if err != nil {
+				t.Fatalf("декодирование тела: %v", err)
+			}
+			if string(body) != tt.body {
+				t.Errorf("тело = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestEmailSendError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	ln.Close() // Порт закрыт - соединение не установится
+
+	e := NewEmail(host, port, "", "", "bot@example.com")
+	if err := e.Send(Destination{Target: "user@example.com"}, Message{Text: "test"}); err == nil {
+		t.Fatal("Send без сервера должен вернуть ошибку")
+	}
+}
+
+func TestEmailSubject(t *testing.T) {
+	long := strings.Repeat("я", 100)
+	tests := []struct {
+		msg  Message
+		want string
+	}{
+		{Message{Text: "\n  *Заголовок*  \nтело"}, "Заголовок"},
+		{Message{Text: ""}, "Уведомление TradeTGBot"},
+		{Message{Text: long}, strings.Repeat("я", emailSubjectLimit-1) + "…"},
+		{Message{Text: "SBER", Urgent: true}, "[срочно] SBER"},
+		{Message{Text: "<b>LKOH</b> &lt; 7000 &amp; RSI &gt; 70", ParseMode: "HTML"}, "LKOH < 7000 & RSI > 70"},
+		{Message{Text: "<i></i>\n🔔 <a href=\"tg://user?id=42\">Анна</a>, SBER", ParseMode: "HTML"}, "🔔 Анна, SBER"},
+		{Message{Text: "a <b> b", ParseMode: ""}, "a <b> b"},
+	}
+	for _, tt := range tests {
+		if got := emailSubject(tt.msg); got != tt.want {
+			t.Errorf("emailSubject(%q) = %q, want %q", tt.msg.Text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+// TradeTGBot/internal/notify/notify.go
+package notify
+
+import "time"
+
+// Message - уведомление, независимое от канала доставки.
+type Message struct {
+	NotificationID int
+	ChatID         int64 // Чат, к которому относится уведомление
+	AlertID        int   // 0, если уведомление не связано с оповещением
+	Text           string
+	ParseMode      string // Разметка Telegram ("HTML", "Markdown"); email с HTML отправляется как text/html
+	Urgent         bool
+	CreatedAt      time.Time
+	ReplyMarkup    interface{} // Кнопки Telegram; другими каналами игнорируются
+}
+
+// Destination - адрес доставки в конкретном канале.
+type Destination struct {
+	ChatID   int64  // Чат или канал Telegram
+	ThreadID int    // Тема форума в супергруппе; 0 - общий поток
+	Target   string // Адрес email или URL вебхука
+	Secret   string // Ключ подписи вебхука
+}
+
+// Notifier доставляет уведомление по одному каналу. Ошибка означает, что
+// доставка не состоялась и её нужно повторить.
+type Notifier interface {
+	Send(dest Destination, msg Message) error
+}
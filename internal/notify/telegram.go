@@ -0,0 +1,52 @@
+// TradeTGBot/internal/notify/telegram.go
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Telegram доставляет уведомления сообщением в чат бота.
+type Telegram struct {
+	bot *tgbotapi.BotAPI
+}
+
+// NewTelegram создаёт Telegram-канал поверх уже инициализированного бота.
+func NewTelegram(bot *tgbotapi.BotAPI) *Telegram {
+	return &Telegram{bot: bot}
+}
+
+// Send отправляет сообщение в dest.ChatID (и тему dest.ThreadID) вместе с кнопками, если они есть.
+func (t *Telegram) Send(dest Destination, msg Message) error {
+	if dest.ThreadID != 0 {
+		return t.sendToThread(dest, msg)
+	}
+	m := tgbotapi.NewMessage(dest.ChatID, msg.Text)
+	m.ParseMode = msg.ParseMode
+	if msg.ReplyMarkup != nil {
+		m.ReplyMarkup = msg.ReplyMarkup
+	}
+	_, err := t.bot.Send(m)
+	return err
+}
+
+// sendToThread отправляет сообщение в тему форума. Библиотека не знает о
+// message_thread_id, поэтому запрос собирается вручную.
+func (t *Telegram) sendToThread(dest Destination, msg Message) error {
+	params := tgbotapi.Params{}
+	params.AddNonZero64("chat_id", dest.ChatID)
+	params.AddNonZero("message_thread_id", dest.ThreadID)
+	params["text"] = msg.Text
+	params.AddNonEmpty("parse_mode", msg.ParseMode)
+	if err := params.AddInterface("reply_markup", msg.ReplyMarkup); err != nil {
+		return fmt.Errorf("ошибка кодирования кнопок: %w", err)
+	}
+	resp, err := t.bot.MakeRequest("sendMessage", params)
+	if err != nil {
+		return err
+	}
+	var sent tgbotapi.Message
+	return json.Unmarshal(resp.Result, &sent)
+}
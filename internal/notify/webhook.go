@@ -0,0 +1,131 @@
+// TradeTGBot/internal/notify/webhook.go
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Заголовки запроса вебхука. Получатель проверяет подпись так:
+// Sign(secret, значение SignatureTimestampHeader, тело запроса) == значение SignatureHeader.
+const (
+	SignatureHeader          = "X-TradeTGBot-Signature"
+	SignatureTimestampHeader = "X-TradeTGBot-Timestamp"
+)
+
+// webhookPayload - JSON, который получает вебхук.
+type webhookPayload struct {
+	NotificationID int       `json:"notification_id"`
+	ChatID         int64     `json:"chat_id"`
+	AlertID        int       `json:"alert_id,omitempty"`
+	Text           string    `json:"text"`
+	ParseMode      string    `json:"parse_mode,omitempty"` // "HTML", если текст размечен
+	Urgent         bool      `json:"urgent"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Webhook доставляет уведомления POST-запросом с JSON, подписанным HMAC-SHA256.
+type Webhook struct {
+	client *http.Client
+}
+
+// NewWebhook создаёт канал вебхуков с ограничением времени на запрос. Соединения
+// с непубличными адресами (loopback, частные сети, link-local) запрещены, чтобы
+// вебхук нельзя было направить на внутренние сервисы, в том числе через редирект
+// или смену DNS-записи после проверки адреса.
+func NewWebhook(timeout time.Duration) *Webhook {
+	dialer := &net.Dialer{Timeout: timeout, Control: publicOnly}
+	transport := &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: timeout}
+	return &Webhook{client: &http.Client{Timeout: timeout, Transport: transport}}
+}
+
+// publicOnly запрещает соединение с непубличным адресом; вызывается после разрешения имени.
+func publicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("адрес %s не публичный", host)
+	}
+	return nil
+}
+
+// isPublicIP сообщает, что адрес не loopback, не из частной сети, не link-local,
+// не multicast и не нулевой.
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
+
+// CheckWebhookHost проверяет, что имя хоста вебхука разрешается только в публичные адреса.
+func CheckWebhookHost(ctx context.Context, host string) error {
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("не удалось разрешить %s: %w", host, err)
+	}
+	for _, ip := range ips {
+		if !isPublicIP(ip.IP) {
+			return fmt.Errorf("%s указывает на непубличный адрес %s", host, ip.IP)
+		}
+	}
+	return nil
+}
+
+// Send отправляет уведомление на dest.Target. Доставка считается успешной
+// только при ответе 2xx.
+func (w *Webhook) Send(dest Destination, msg Message) error {
+	body, err := json.Marshal(webhookPayload{
+		NotificationID: msg.NotificationID,
+		ChatID:         msg.ChatID,
+		AlertID:        msg.AlertID,
+		Text:           msg.Text,
+		ParseMode:      msg.ParseMode,
+		Urgent:         msg.Urgent,
+		CreatedAt:      msg.CreatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации уведомления: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, dest.Target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("ошибка создания запроса к вебхуку: %w", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureTimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(dest.Secret, timestamp, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ошибка запроса к вебхуку: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("вебхук ответил %s: %s", resp.Status, bytes.TrimSpace(snippet))
+	}
+	return nil
+}
+
+// Sign вычисляет подпись "sha256=<hex>" от строки "<timestamp>.<body>".
+// Метка времени входит в подпись, чтобы старый запрос нельзя было повторить.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,160 @@
+// TradeTGBot/internal/notify/webhook_test.go
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// localWebhook возвращает канал вебхуков без запрета непубличных адресов,
+// чтобы достучаться до тестового сервера на 127.0.0.1.
+func localWebhook(srv *httptest.Server) *Webhook {
+	return &Webhook{client: srv.Client()}
+}
+
+func TestWebhookSend(t *testing.T) {
+	type request struct {
+		method, contentType, timestamp, signature string
+		body                                      []byte
+	}
+	requests := make(chan request, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- request{
+			method:      r.Method,
+			contentType: r.Header.Get("Content-Type"),
+			timestamp:   r.Header.Get(SignatureTimestampHeader),
+			signature:   r.Header.Get(SignatureHeader),
+			body:        body,
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	created := time.Date(2026, 3, 2, 10, 30, 0, 0, time.UTC)
+	msg := Message{NotificationID: 7, ChatID: -100123, AlertID: 42, Text: "SBER достиг 320.00", ParseMode: "HTML", Urgent: true, CreatedAt: created}
+	before := time.Now().Unix()
+	if err := localWebhook(srv).Send(Destination{Target: srv.URL, Secret: "s3cret"}, msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	after := time.Now().Unix()
+	req := <-requests
+
+	if req.method != http.MethodPost {
+		t.Errorf("метод = %s, want POST", req.method)
+	}
+	if req.contentType != "application/json" {
+		t.Errorf("Content-Type = %q", req.contentType)
+	}
+	ts, err := strconv.ParseInt(req.timestamp, 10, 64)
+	if err != nil || ts < before || ts > after {
+		t.Errorf("%s = %q, want Unix-время между %d и %d", SignatureTimestampHeader, req.timestamp, before, after)
+	}
+	if want := Sign("s3cret", req.timestamp, req.body); req.signature != want {
+		t.Errorf("%s = %q, want %q", SignatureHeader, req.signature, want)
+	}
+	if Sign("other", req.timestamp, req.body) == req.signature {
+		t.Error("подпись не зависит от ключа")
+	}
+
+	var payload webhookPayload
+	if err := json.Unmarshal(req.body, &payload); err != nil {
+		t.Fatalf("разбор JSON: %v", err)
+	}
+	want := webhookPayload{NotificationID: 7, ChatID: -100123, AlertID: 42, Text: msg.Text, ParseMode: "HTML", Urgent: true, CreatedAt: created}
+	if !payload.CreatedAt.Equal(want.CreatedAt) {
+		t.Errorf("created_at = %v, want %v", payload.CreatedAt, want.CreatedAt)
+	}
+	payload.CreatedAt = want.CreatedAt
+	if payload != want {
+		t.Errorf("payload = %+v, want %+v", payload, want)
+	}
+}
+
+func TestWebhookSendNon2xx(t *testing.T) {
+	for _, status := range []int{http.StatusMovedPermanently, http.StatusBadRequest, http.StatusInternalServerError} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if status == http.StatusMovedPermanently {
+				// Редирект без Location клиент не выполняет и возвращает как есть.
+				w.WriteHeader(status)
+				return
+			}
+			http.Error(w, "broken", status)
+		}))
+		err := localWebhook(srv).Send(Destination{Target: srv.URL}, Message{Text: "test"})
+		srv.Close()
+		if err == nil {
+			t.Errorf("ответ %d: ошибки нет", status)
+			continue
+		}
+		if !strings.Contains(err.Error(), strconv.Itoa(status)) {
+			t.Errorf("ответ %d: ошибка %q не содержит статус", status, err)
+		}
+	}
+}
+
+func TestSign(t *testing.T) {
+	// Значение посчитано независимо: printf '1700000000.{"a":1}' | openssl dgst -sha256 -hmac key
+	got := Sign("key", "1700000000", []byte(`{"a":1}`))
+	want := "sha256=a438e398bfafc57e4396bb7fc2304422f0f768e965d073ca313cb52e22e6ad03"
+	if got != want {
+		t.Errorf("Sign = %q, want %q", got, want)
+	}
+}
+
+func TestWebhookRefusesPrivateAddress(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	err := NewWebhook(5*time.Second).Send(Destination{Target: srv.URL}, Message{Text: "test"})
+	if err == nil || !strings.Contains(err.Error(), "не публичный") {
+		t.Errorf("запрос на %s: ошибка %v, want отказ по адресу", srv.URL, err)
+	}
+	if called {
+		t.Error("запрос дошёл до сервера на loopback")
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	tests := map[string]bool{
+		"8.8.8.8":         true,
+		"2a00:1450::1":    true,
+		"127.0.0.1":       false,
+		"::1":             false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false, // Метаданные облачных провайдеров
+		"fe80::1":         false,
+		"fd00::1":         false,
+		"0.0.0.0":         false,
+		"224.0.0.1":       false,
+	}
+	for addr, want := range tests {
+		if got := isPublicIP(net.ParseIP(addr)); got != want {
+			t.Errorf("isPublicIP(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestCheckWebhookHost(t *testing.T) {
+	for _, host := range []string{"127.0.0.1", "10.0.0.5", "::1", "localhost"} {
+		if err := CheckWebhookHost(context.Background(), host); err == nil {
+			t.Errorf("CheckWebhookHost(%q): ошибки нет", host)
+		}
+	}
+	if err := CheckWebhookHost(context.Background(), "93.184.215.14"); err != nil {
+		t.Errorf("CheckWebhookHost(публичный IP): %v", err)
+	}
+}
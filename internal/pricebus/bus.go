@@ -0,0 +1,213 @@
+// TradeTGBot/internal/pricebus/bus.go
+package pricebus
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Tick - событие новой котировки.
+type Tick struct {
+	Ticker string
+	Name   string
+	Price  float64
+	Time   time.Time
+}
+
+// Overflow определяет, что делать с тиком, если буфер подписчика заполнен.
+type Overflow int
+
+const (
+	// DropOldest вытесняет самый старый тик из буфера: подписчик всегда получает свежие цены.
+	DropOldest Overflow = iota
+	// DropNewest отбрасывает новый тик, сохраняя уже накопленные.
+	DropNewest
+	// Block ждёт освобождения буфера не дольше blockTimeout, затем отбрасывает тик.
+	Block
+)
+
+// blockTimeout ограничивает ожидание медленного подписчика с политикой Block,
+// чтобы он не остановил публикацию для остальных.
+const blockTimeout = 5 * time.Second
+
+// Subscription - подписка на тики. Тики читаются из канала C.
+type Subscription struct {
+	C <-chan Tick
+
+	name     string
+	ch       chan Tick
+	tickers  map[string]bool // nil - все тикеры
+	overflow Overflow
+	mu       sync.Mutex // Сериализует вытеснение при DropOldest
+	dropped  atomic.Int64
+
+	// Тики доставляются вне блокировки шины, поэтому закрытие канала
+	// согласуется с доставкой отдельно: done прерывает ожидание при Block,
+	// closeMu не даёт закрыть ch во время отправки.
+	done    chan struct{}
+	closeMu sync.RWMutex
+	closed  bool
+}
+
+// Name возвращает имя подписчика (для логов).
+func (s *Subscription) Name() string {
+	return s.name
+}
+
+// Dropped возвращает число тиков, потерянных из-за переполнения буфера.
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+func (s *Subscription) wants(ticker string) bool {
+	return s.tickers == nil || s.tickers[ticker]
+}
+
+// deliver передаёт тик подписчику согласно его политике переполнения.
+// После отмены подписки тики молча отбрасываются.
+func (s *Subscription) deliver(t Tick) {
+	s.closeMu.RLock()
+	defer s.closeMu.RUnlock()
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.ch <- t:
+		return
+	default:
+	}
+
+	switch s.overflow {
+	case DropOldest:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		select {
+		case <-s.ch:
+			s.drop()
+		default:
+		}
+		select {
+		case s.ch <- t:
+		default:
+			s.drop()
+		}
+	case Block:
+		timer := time.NewTimer(blockTimeout)
+		defer timer.Stop()
+		select {
+		case s.ch <- t:
+		case <-timer.C:
+			s.drop()
+		case <-s.done:
+		}
+	default:
+		s.drop()
+	}
+}
+
+func (s *Subscription) drop() {
+	// Логируем первую потерю и далее каждую сотую, чтобы не засорять лог.
+	if n := s.dropped.Add(1); n == 1 || n%100 == 0 {
+		log.Printf("Подписчик %s не успевает обрабатывать тики, потеряно: %d", s.name, n)
+	}
+}
+
+// Bus - внутренняя шина публикации тиков с подписками по тикерам или на все тикеры.
+type Bus struct {
+	mu   sync.RWMutex
+	subs []*Subscription
+	last map[string]Tick
+}
+
+// New создаёт пустую шину.
+func New() *Bus {
+	return &Bus{last: make(map[string]Tick)}
+}
+
+// Subscribe создаёт подписку с буфером buffer. Если tickers пуст, подписка получает
+// тики всех тикеров, иначе - только перечисленных.
+func (b *Bus) Subscribe(name string, buffer int, overflow Overflow, tickers ...string) *Subscription {
+	ch := make(chan Tick, buffer)
+	s := &Subscription{C: ch, name: name, ch: ch, overflow: overflow, done: make(chan struct{})}
+	if len(tickers) > 0 {
+		s.tickers = make(map[string]bool, len(tickers))
+		for _, t := range tickers {
+			s.tickers[t] = true
+		}
+	}
+
+	b.mu.Lock()
+	b.subs = append(b.subs, s)
+	b.mu.Unlock()
+	return s
+}
+
+// Unsubscribe отменяет подписку и закрывает её канал.
+func (b *Bus) Unsubscribe(s *Subscription) {
+	b.mu.Lock()
+	found := false
+	for i, x := range b.subs {
+		if x == s {
+			b.subs = append(b.subs[:i:i], b.subs[i+1:]...)
+			found = true
+			break
+		}
+	}
+	b.mu.Unlock()
+	if !found {
+		return
+	}
+
+	// Публикация, начатая до удаления из списка, может ещё доставлять тик:
+	// прерываем её ожидание и закрываем канал, когда отправка завершится.
+	close(s.done)
+	s.closeMu.Lock()
+	s.closed = true
+	close(s.ch)
+	s.closeMu.Unlock()
+}
+
+// Publish запоминает тик как последнюю котировку тикера и рассылает его подписчикам.
+// Список подписчиков копируется под блокировкой, а доставка идёт после её снятия,
+// чтобы медленный подписчик с политикой Block не останавливал Subscribe, Unsubscribe и Last.
+func (b *Bus) Publish(t Tick) {
+	b.mu.Lock()
+	b.rememberLocked(t)
+	subs := make([]*Subscription, 0, len(b.subs))
+	for _, s := range b.subs {
+		if s.wants(t.Ticker) {
+			subs = append(subs, s)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, s := range subs {
+		s.deliver(t)
+	}
+}
+
+// remember запоминает тик как последнюю котировку тикера, не рассылая его.
+func (b *Bus) remember(t Tick) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rememberLocked(t)
+}
+
+// rememberLocked - remember под уже взятой b.mu. Более старый тик не вытесняет известный.
+func (b *Bus) rememberLocked(t Tick) {
+	if prev, ok := b.last[t.Ticker]; ok && t.Time.Before(prev.Time) {
+		return
+	}
+	b.last[t.Ticker] = t
+}
+
+// Last возвращает последний опубликованный тик тикера.
+func (b *Bus) Last(ticker string) (Tick, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	t, ok := b.last[ticker]
+	return t, ok
+}
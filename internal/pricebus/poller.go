@@ -0,0 +1,147 @@
+// TradeTGBot/internal/pricebus/poller.go
+package pricebus
+
+import (
+	"TradeTGBot/pkg/stocks"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gocolly/colly"
+)
+
+// pollGranularity - как часто поллер проверяет, каким тикерам пора обновиться.
+const pollGranularity = time.Second
+
+// watch - набор тикеров, которые нужно опрашивать с заданным интервалом.
+type watch struct {
+	interval time.Duration
+	tickers  func() []string
+}
+
+// Poller - единственный источник котировок: опрашивает сайт и публикует тики в шину,
+// так что каждая цена запрашивается один раз и раздаётся всем подписчикам.
+type Poller struct {
+	bus       *Bus
+	collector *colly.Collector
+
+	mu      sync.Mutex
+	watches []watch
+	polled  map[string]time.Time // Время последней попытки запроса по тикеру
+
+	fetchMu sync.Mutex // Запросы к сайту выполняются последовательно
+}
+
+// NewPoller создаёт поллер, публикующий тики в bus.
+func NewPoller(bus *Bus, collector *colly.Collector) *Poller {
+	return &Poller{
+		bus:       bus,
+		collector: collector,
+		polled:    make(map[string]time.Time),
+	}
+}
+
+// Bus возвращает шину, в которую публикуются тики.
+func (p *Poller) Bus() *Bus {
+	return p.bus
+}
+
+// Watch регистрирует тикеры для регулярного опроса. Функция tickers вызывается
+// на каждом проходе, поэтому набор может меняться (например, по мере добавления
+// оповещений). Если тикер нужен нескольким потребителям, берётся наименьший интервал.
+func (p *Poller) Watch(interval time.Duration, tickers func() []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.watches = append(p.watches, watch{interval: interval, tickers: tickers})
+}
+
+// Run запускает цикл опроса. Блокирует вызывающую горутину.
+func (p *Poller) Run() {
+	for {
+		for _, ticker := range p.dueTickers(time.Now()) {
+			if _, err := p.fetch(ticker, 0); err != nil {
+				log.Printf("Ошибка получения котировки %s: %v", ticker, err)
+			}
+		}
+		time.Sleep(pollGranularity)
+	}
+}
+
+// dueTickers возвращает тикеры, интервал опроса которых истёк.
+func (p *Poller) dueTickers(now time.Time) []string {
+	p.mu.Lock()
+	watches := append([]watch(nil), p.watches...)
+	p.mu.Unlock()
+
+	intervals := make(map[string]time.Duration)
+	for _, w := range watches {
+		for _, ticker := range w.tickers() {
+			if cur, ok := intervals[ticker]; !ok || w.interval < cur {
+				intervals[ticker] = w.interval
+			}
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var due []string
+	for ticker, interval := range intervals {
+		if now.Sub(p.polled[ticker]) >= interval {
+			due = append(due, ticker)
+		}
+	}
+	return due
+}
+
+// Quote возвращает котировку тикера не старше maxAge: из последнего тика шины,
+// а если он устарел - запрашивает сайт и публикует свежий тик всем подписчикам.
+func (p *Poller) Quote(ticker string, maxAge time.Duration) (Tick, error) {
+	if t, ok := p.bus.Last(ticker); ok && time.Since(t.Time) <= maxAge {
+		return t, nil
+	}
+	return p.fetch(ticker, maxAge)
+}
+
+// fetch запрашивает котировку тикера и публикует её в шину. Если maxAge > 0 и,
+// пока ждали своей очереди, тикер уже обновил кто-то другой, запрос не повторяется.
+// Публикация выполняется после снятия fetchMu, чтобы медленные подписчики
+// не задерживали следующие запросы к сайту.
+func (p *Poller) fetch(ticker string, maxAge time.Duration) (Tick, error) {
+	t, fresh, err := p.request(ticker, maxAge)
+	if err != nil {
+		return Tick{}, err
+	}
+	if fresh {
+		p.bus.Publish(t)
+	}
+	return t, nil
+}
+
+// request выполняет запрос котировки под fetchMu. Полученный тик сразу запоминается
+// в шине как последняя котировка, чтобы ожидающие своей очереди не повторяли запрос.
+// Возвращает false, если подошла уже известная котировка.
+func (p *Poller) request(ticker string, maxAge time.Duration) (Tick, bool, error) {
+	p.fetchMu.Lock()
+	defer p.fetchMu.Unlock()
+
+	if t, ok := p.bus.Last(ticker); ok && maxAge > 0 && time.Since(t.Time) <= maxAge {
+		return t, false, nil
+	}
+
+	p.mu.Lock()
+	p.polled[ticker] = time.Now()
+	p.mu.Unlock()
+
+	info, ok := stocks.Stocks[ticker]
+	if !ok {
+		return Tick{}, false, fmt.Errorf("тикер %s не найден в базе", ticker)
+	}
+	data, err := stocks.FetchStockData(info.URL, p.collector)
+	if err != nil {
+		return Tick{}, false, err
+	}
+	t := Tick{Ticker: ticker, Name: data.Name, Price: data.Price, Time: time.Now()}
+	p.bus.remember(t)
+	return t, true, nil
+}
@@ -0,0 +1,14 @@
+// TradeTGBot/internal/pricebus/storage.go
+package pricebus
+
+import "log"
+
+// StoreTicks сохраняет каждый тик подписки через save. Предназначена для запуска
+// в отдельной горутине; завершается при отмене подписки.
+func StoreTicks(sub *Subscription, save func(ticker string, price float64) error) {
+	for t := range sub.C {
+		if err := save(t.Ticker, t.Price); err != nil {
+			log.Printf("Ошибка при сохранении тика %s: %v", t.Ticker, err)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// TradeTGBot/internal/reports/reports.go
+package reports
+
+import (
+	"TradeTGBot/internal/repository"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	maxDigestTickers = 30 // Больше тикеров в дайджест не попадает, чтобы он уместился в одно сообщение
+	moversCount      = 3  // Сколько лучших и худших тикеров показывать
+)
+
+// tickerLine - строка тикера в дайджесте.
+type tickerLine struct {
+	Ticker                   string
+	Close, Change, Low, High float64
+}
+
+// alertLine - строка сработавшего оповещения в дайджесте.
+type alertLine struct {
+	Time       string
+	Definition string
+	Price      float64
+}
+
+// digestView - данные шаблонов "daily" и "weekly".
+type digestView struct {
+	Date, From, To string
+	Prices         []tickerLine
+	Best, Worst    []tickerLine
+	Alerts         []alertLine
+
+	ShowPrices, ShowAlerts, ShowMovers bool
+}
+
+// chatLocation возвращает часовой пояс чата из его настроек.
+func chatLocation(chatID int64) *time.Location {
+	settings, err := repository.GetChatSettings(chatID)
+	if err != nil {
+		log.Printf("Ошибка получения настроек чата %d: %v", chatID, err)
+	}
+	if loc, err := time.LoadLocation(settings.Timezone); err == nil {
+		return loc
+	}
+	if loc, err := time.LoadLocation(repository.DefaultTimezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// Build формирует дайджест вида kind (repository.DigestDaily или DigestWeekly)
+// по состоянию на now: ежедневный - с начала дня, еженедельный - с понедельника
+// (по часовому поясу чата). Возвращает пустую строку, если показывать нечего.
+func Build(s repository.DigestSettings, kind string, now time.Time) (string, error) {
+	loc := chatLocation(s.ChatID)
+	local := now.In(loc)
+	from := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	if kind == repository.DigestWeekly {
+		from = from.AddDate(0, 0, -(int(from.Weekday())+6)%7)
+	}
+
+	view := digestView{
+		Date:       local.Format("02.01.2006"),
+		From:       from.Format("02.01"),
+		To:         local.Format("02.01.2006"),
+		ShowPrices: s.Has(repository.DigestPrices),
+		ShowAlerts: s.Has(repository.DigestAlerts),
+		ShowMovers: s.Has(repository.DigestMovers),
+	}
+
+	if view.ShowPrices || view.ShowMovers {
+		tickers := s.Tickers
+		if len(tickers) == 0 {
+			var err error
+			if tickers, err = repository.GetWatchedTickers(s.ChatID); err != nil {
+				return "", err
+			}
+		}
+		if len(tickers) > maxDigestTickers {
+			tickers = tickers[:maxDigestTickers]
+		}
+		if len(tickers) > 0 {
+			summaries, err := repository.GetPriceSummaries(tickers, from, now)
+			if err != nil {
+				return "", err
+			}
+			for _, p := range summaries {
+				view.Prices = append(view.Prices, tickerLine{Ticker: p.Ticker, Close: p.Close, Change: p.Change(), Low: p.Low, High: p.High})
+			}
+		}
+		view.Best, view.Worst = movers(view.Prices)
+	}
+
+	if view.ShowAlerts {
+		triggers, err := repository.GetAlertTriggersBetween(s.ChatID, from, now)
+		if err != nil {
+			return "", err
+		}
+		for _, t := range triggers {
+			view.Alerts = append(view.Alerts, alertLine{
+				Time:       t.TriggeredAt.In(loc).Format("15:04"),
+				Definition: t.Definition,
+				Price:      t.TriggerPrice,
+			})
+		}
+	}
+
+	if len(view.Prices) == 0 && len(view.Alerts) == 0 {
+		return "", nil
+	}
+	var sb strings.Builder
+	if err := templates.ExecuteTemplate(&sb, kind, view); err != nil {
+		return "", fmt.Errorf("ошибка при формировании дайджеста: %w", err)
+	}
+	return sb.String(), nil
+}
+
+// movers возвращает лучшие и худшие по изменению тикеры. Если тикеров меньше
+// двух, сравнивать не с чем и оба списка пусты.
+func movers(lines []tickerLine) (best, worst []tickerLine) {
+	sorted := append([]tickerLine(nil), lines...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Change > sorted[j].Change })
+	n := min(moversCount, len(sorted)/2)
+	best = sorted[:n]
+	for i := len(sorted) - 1; i >= len(sorted)-n; i-- {
+		worst = append(worst, sorted[i])
+	}
+	return best, worst
+}
+
+// Run рассылает дайджесты, время которых наступило. Вызывается планировщиком
+// раз в минуту. Ежедневный дайджест приходит по будним (торговым) дням,
+// еженедельный - в выбранный день недели; каждый не чаще раза в день.
+func Run(now time.Time) {
+	list, err := repository.GetScheduledDigests()
+	if err != nil {
+		log.Printf("Ошибка при получении расписания дайджестов: %v", err)
+		return
+	}
+	for _, s := range list {
+		local := now.In(chatLocation(s.ChatID))
+		day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+		minute := local.Hour()*60 + local.Minute()
+
+		weekday := local.Weekday()
+		if s.DailyEnabled && minute >= s.DailyTime && weekday != time.Saturday && weekday != time.Sunday {
+			send(s, repository.DigestDaily, day, now)
+		}
+		if s.WeeklyEnabled && minute >= s.WeeklyTime && weekday == s.WeeklyDay {
+			send(s, repository.DigestWeekly, day, now)
+		}
+	}
+}
+
+// send отправляет дайджест через outbox, если за этот день он ещё не отправлялся.
+// День отмечается до формирования, чтобы параллельный запуск не отправил дайджест
+// дважды; если сформировать или поставить его в очередь не удалось, отметка
+// снимается и следующий запуск повторит попытку.
+func send(s repository.DigestSettings, kind string, day, now time.Time) {
+	claimed, err := repository.ClaimDigest(s.ChatID, kind, day)
+	if err != nil {
+		log.Printf("Ошибка отметки дайджеста для чата %d: %v", s.ChatID, err)
+		return
+	}
+	if !claimed {
+		return
+	}
+	release := func() {
+		if err := repository.ReleaseDigest(s.ChatID, kind, day); err != nil {
+			log.Printf("%v", err)
+		}
+	}
+	text, err := Build(s, kind, now)
+	if err != nil {
+		log.Printf("Ошибка формирования дайджеста для чата %d: %v", s.ChatID, err)
+		release()
+		return
+	}
+	if text == "" {
+		log.Printf("Дайджест (%s) для чата %d пропущен: нет данных.", kind, s.ChatID)
+		return
+	}
+	// Доставкой (с повторами и тихими часами) занимается outbox бота
+	if _, err := repository.EnqueueNotification(repository.Notification{ChatID: s.ChatID, Text: text}); err != nil {
+		log.Printf("Ошибка при постановке дайджеста для чата %d в очередь: %v", s.ChatID, err)
+		release()
+	}
+}
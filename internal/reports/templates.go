@@ -0,0 +1,67 @@
+// TradeTGBot/internal/reports/templates.go
+package reports
+
+import (
+	"fmt"
+	"text/template"
+)
+
+// templates - шаблоны текстов дайджестов "daily" и "weekly" (данные - digestView).
+var templates = template.Must(template.New("reports").Funcs(template.FuncMap{
+	"price": func(v float64) string { return fmt.Sprintf("%.2f", v) },
+	"pct":   func(v float64) string { return fmt.Sprintf("%+.2f%%", v) },
+}).Parse(`
+{{- define "tickers" -}}
+{{- range . }}
+{{ .Ticker }}: {{ price .Close }} ({{ pct .Change }}), диапазон {{ price .Low }}–{{ price .High }}
+{{- else }}
+нет цен за период
+{{- end }}
+{{- end -}}
+
+{{- define "movers" -}}
+{{- if .Best }}
+Лучшие: {{ range $i, $m := .Best }}{{ if $i }}, {{ end }}{{ $m.Ticker }} {{ pct $m.Change }}{{ end }}
+Худшие: {{ range $i, $m := .Worst }}{{ if $i }}, {{ end }}{{ $m.Ticker }} {{ pct $m.Change }}{{ end }}
+{{- else }}
+нет данных
+{{- end }}
+{{- end -}}
+
+{{- define "daily" -}}
+📰 Итоги дня {{ .Date }}
+{{- if .ShowPrices }}
+
+Тикеры:{{ template "tickers" .Prices }}
+{{- end }}
+{{- if .ShowMovers }}
+
+Лидеры дня:{{ template "movers" . }}
+{{- end }}
+{{- if .ShowAlerts }}
+
+Сработавшие оповещения:
+{{- range .Alerts }}
+{{ .Time }} {{ .Definition }}{{ if .Price }} по {{ price .Price }}{{ end }}
+{{- else }}
+не было
+{{- end }}
+{{- end }}
+{{- end -}}
+
+{{- define "weekly" -}}
+📅 Итоги недели {{ .From }}–{{ .To }}
+{{- if .ShowMovers }}
+
+Лидеры недели:{{ template "movers" . }}
+{{- end }}
+{{- if .ShowPrices }}
+
+Тикеры за неделю:{{ template "tickers" .Prices }}
+{{- end }}
+{{- if .ShowAlerts }}
+
+Сработало оповещений: {{ len .Alerts }}
+{{- end }}
+{{- end -}}
+`))
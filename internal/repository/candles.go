@@ -0,0 +1,252 @@
+// TradeTGBot/internal/repository/candles.go
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"TradeTGBot/internal/db"
+	"TradeTGBot/internal/indicators"
+)
+
+// CandleTimeframe represents a timeframe of stored candles and its table.
+type CandleTimeframe struct {
+	Name     string
+	Duration time.Duration
+}
+
+// CandleTimeframes - таймфреймы, в которые сворачиваются тики, по возрастанию.
+var CandleTimeframes = []CandleTimeframe{
+	{Name: "1m", Duration: time.Minute},
+	{Name: "5m", Duration: 5 * time.Minute},
+	{Name: "1h", Duration: time.Hour},
+	{Name: "1d", Duration: 24 * time.Hour},
+}
+
+func (tf CandleTimeframe) table() string { return "candles_" + tf.Name }
+
+// aggregateCandlesQuery пересчитывает целиком каждую минутную свечу, в которую попал
+// хотя бы один тик с id из ($1, $2]: свеча строится заново по всем тикам своей минуты,
+// поэтому повторный прогон и опоздавшие тики не портят уже сохранённые свечи.
+// Начало свечи выровнено по Unix-времени, как time.Truncate для таймфреймов до суток.
+const aggregateCandlesQuery = `
+	WITH affected AS (
+		SELECT DISTINCT ticker, to_timestamp(floor(extract(epoch FROM timestamp)::float8 / $3::float8) * $3::float8) AS bucket
+		FROM stock_prices
+		WHERE id > $1 AND id <= $2
+	)
+	INSERT INTO %s (ticker, bucket, open, high, low, close, ticks)
+	SELECT a.ticker, a.bucket,
+		(array_agg(p.price ORDER BY p.timestamp, p.id))[1],
+		max(p.price), min(p.price),
+		(array_agg(p.price ORDER BY p.timestamp DESC, p.id DESC))[1],
+		count(*)
+	FROM affected a
+	JOIN stock_prices p ON p.ticker = a.ticker
+		AND p.timestamp >= a.bucket AND p.timestamp < a.bucket + make_interval(secs => $3::float8)
+	GROUP BY a.ticker, a.bucket
+	ON CONFLICT (ticker, bucket) DO UPDATE SET
+		open = EXCLUDED.open, high = EXCLUDED.high, low = EXCLUDED.low,
+		close = EXCLUDED.close, ticks = EXCLUDED.ticks`
+
+// rollupCandlesQuery пересчитывает свечу старшего таймфрейма, в которую попал хотя бы
+// один тик с id из ($1, $2], по уже пересчитанным минутным свечам её интервала,
+// а не по сырым тикам: часовой свече нужно не больше 60 строк, дневной - 1440.
+const rollupCandlesQuery = `
+	WITH affected AS (
+		SELECT DISTINCT ticker, to_timestamp(floor(extract(epoch FROM timestamp)::float8 / $3::float8) * $3::float8) AS bucket
+		FROM stock_prices
+		WHERE id > $1 AND id <= $2
+	)
+	INSERT INTO %s (ticker, bucket, open, high, low, close, ticks)
+	SELECT a.ticker, a.bucket,
+		(array_agg(c.open ORDER BY c.bucket))[1],
+		max(c.high), min(c.low),
+		(array_agg(c.close ORDER BY c.bucket DESC))[1],
+		sum(c.ticks)
+	FROM affected a
+	JOIN %s c ON c.ticker = a.ticker
+		AND c.bucket >= a.bucket AND c.bucket < a.bucket + make_interval(secs => $3::float8)
+	GROUP BY a.ticker, a.bucket
+	ON CONFLICT (ticker, bucket) DO UPDATE SET
+		open = EXCLUDED.open, high = EXCLUDED.high, low = EXCLUDED.low,
+		close = EXCLUDED.close, ticks = EXCLUDED.ticks`
+
+// AggregateCandles сворачивает в свечи всех таймфреймов не больше batch тиков,
+// записанных после отметки прогресса, и сдвигает отметку в той же транзакции.
+// Из тиков строятся только минутные свечи, старшие таймфреймы - из минутных.
+// Возвращает число обработанных тиков. Тики отбираются по id, а не по времени,
+// поэтому учитываются и тики с опоздавшей меткой времени. Полагается на то, что
+// тики пишет один поток (pricebus.StoreTicks) и id фиксируются по возрастанию.
+func AggregateCandles(batch int) (int, error) {
+	tx, err := db.GlobalDB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	var lastID int64
+	err = tx.QueryRow(`SELECT last_id FROM candle_progress FOR UPDATE`).Scan(&lastID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("ошибка при чтении прогресса свечей: %w", err)
+	}
+
+	var upTo int64
+	var count int
+	err = tx.QueryRow(`
+		SELECT COALESCE(max(id), $1), count(*)
+		FROM (SELECT id FROM stock_prices WHERE id > $1 ORDER BY id LIMIT $2) t`, lastID, batch).Scan(&upTo, &count)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при выборке новых тиков: %w", err)
+	}
+	if count == 0 {
+		return 0, nil
+	}
+
+	base := CandleTimeframes[0]
+	for i, tf := range CandleTimeframes {
+		query := fmt.Sprintf(aggregateCandlesQuery, tf.table())
+		if i > 0 {
+			query = fmt.Sprintf(rollupCandlesQuery, tf.table(), base.table())
+		}
+		if _, err := tx.Exec(query, lastID, upTo, tf.Duration.Seconds()); err != nil {
+			return 0, fmt.Errorf("ошибка при агрегации свечей %s: %w", tf.Name, err)
+		}
+	}
+	_, err = tx.Exec(`
+		INSERT INTO candle_progress (id, last_id) VALUES (true, $1)
+		ON CONFLICT (id) DO UPDATE SET last_id = EXCLUDED.last_id`, upTo)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при сохранении прогресса свечей: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("ошибка при агрегации свечей: %w", err)
+	}
+	return count, nil
+}
+
+// GetCandles возвращает бары тикера длительностью bar за [from, to). Бары собираются
+// из свечей наибольшего хранимого таймфрейма, на который делится bar; последняя
+// свеча и всё после неё (ещё не агрегированное) строится по сырым тикам. Если
+// подходящего таймфрейма или сохранённых свечей нет, все бары строятся по тикам.
+func GetCandles(ticker string, bar time.Duration, from, to time.Time) ([]indicators.Candle, error) {
+	var stored []indicators.Candle
+	tailFrom := from
+	for i := len(CandleTimeframes) - 1; i >= 0; i-- {
+		tf := CandleTimeframes[i]
+		if bar%tf.Duration != 0 {
+			continue
+		}
+		var err error
+		if stored, err = getStoredCandles(ticker, tf, from.Truncate(tf.Duration), to); err != nil {
+			return nil, err
+		}
+		if n := len(stored); n > 0 {
+			tailFrom = stored[n-1].Time
+			stored = stored[:n-1]
+		}
+		break
+	}
+
+	history, err := GetPriceHistoryBetween(ticker, tailFrom, to)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]indicators.Point, len(history))
+	for i, p := range history {
+		points[i] = indicators.Point{Time: p.Timestamp, Value: p.Price}
+	}
+	return indicators.MergeCandles(append(stored, indicators.Candles(points, bar)...), bar), nil
+}
+
+// GetLastCandles возвращает не больше n последних баров тикера длительностью bar,
+// закончившихся до to. Бары отбираются по числу, а не по времени: ночи, выходные
+// и праздники без торгов не уменьшают их количество. Бары собираются так же,
+// как в GetCandles; пока сохранённых свечей нет, используются тики за n баров до to.
+func GetLastCandles(ticker string, bar time.Duration, n int, to time.Time) ([]indicators.Candle, error) {
+	var stored []indicators.Candle
+	for i := len(CandleTimeframes) - 1; i >= 0; i-- {
+		tf := CandleTimeframes[i]
+		if bar%tf.Duration != 0 {
+			continue
+		}
+		// Берём на бар больше: самый старый бар может оказаться неполным.
+		per := int(bar / tf.Duration)
+		var err error
+		if stored, err = getLastStoredCandles(ticker, tf, (n+1)*per, to); err != nil {
+			return nil, err
+		}
+		break
+	}
+
+	tailFrom := to.Add(-bar * time.Duration(n))
+	if k := len(stored); k > 0 {
+		tailFrom = stored[k-1].Time
+		stored = stored[:k-1]
+	}
+	history, err := GetPriceHistoryBetween(ticker, tailFrom, to)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]indicators.Point, len(history))
+	for i, p := range history {
+		points[i] = indicators.Point{Time: p.Timestamp, Value: p.Price}
+	}
+	candles := indicators.MergeCandles(append(stored, indicators.Candles(points, bar)...), bar)
+	if len(candles) > n {
+		candles = candles[len(candles)-n:]
+	}
+	return candles, nil
+}
+
+// getLastStoredCandles читает limit последних сохранённых свечей таймфрейма tf
+// с началом до to в порядке возрастания времени.
+func getLastStoredCandles(ticker string, tf CandleTimeframe, limit int, to time.Time) ([]indicators.Candle, error) {
+	rows, err := db.GlobalDB.Query(fmt.Sprintf(`
+		SELECT bucket, open, high, low, close, ticks
+		FROM (
+			SELECT bucket, open, high, low, close, ticks
+			FROM %s
+			WHERE ticker = $1 AND bucket < $2
+			ORDER BY bucket DESC
+			LIMIT $3
+		) t
+		ORDER BY bucket`, tf.table()), ticker, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении свечей %s %s: %w", ticker, tf.Name, err)
+	}
+	return scanCandles(rows, ticker, tf)
+}
+
+// getStoredCandles читает сохранённые свечи таймфрейма tf с началом в [from, to).
+func getStoredCandles(ticker string, tf CandleTimeframe, from, to time.Time) ([]indicators.Candle, error) {
+	rows, err := db.GlobalDB.Query(fmt.Sprintf(`
+		SELECT bucket, open, high, low, close, ticks
+		FROM %s
+		WHERE ticker = $1 AND bucket >= $2 AND bucket < $3
+		ORDER BY bucket`, tf.table()), ticker, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении свечей %s %s: %w", ticker, tf.Name, err)
+	}
+	return scanCandles(rows, ticker, tf)
+}
+
+// scanCandles читает свечи из результата запроса и закрывает его.
+func scanCandles(rows *sql.Rows, ticker string, tf CandleTimeframe) ([]indicators.Candle, error) {
+	defer rows.Close()
+
+	var candles []indicators.Candle
+	for rows.Next() {
+		var c indicators.Candle
+		if err := rows.Scan(&c.Time, &c.Open, &c.High, &c.Low, &c.Close, &c.Volume); err != nil {
+			return nil, fmt.Errorf("ошибка при чтении свечей %s %s: %w", ticker, tf.Name, err)
+		}
+		candles = append(candles, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении свечей %s %s: %w", ticker, tf.Name, err)
+	}
+	return candles, nil
+}
@@ -0,0 +1,271 @@
+// TradeTGBot/internal/repository/digests.go
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"TradeTGBot/internal/db"
+)
+
+// Разделы дайджеста по рынку.
+const (
+	DigestPrices = "prices" // Закрытие, изменение и диапазон по тикерам
+	DigestAlerts = "alerts" // Сработавшие оповещения
+	DigestMovers = "movers" // Лучшие и худшие тикеры
+)
+
+// DigestSections - все разделы дайджеста в порядке вывода.
+var DigestSections = []string{DigestPrices, DigestAlerts, DigestMovers}
+
+// Виды дайджеста.
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// DigestSettings represents a chat's schedule and contents of market digests
+type DigestSettings struct {
+	ChatID        int64
+	DailyEnabled  bool
+	DailyTime     int // Минуты от полуночи по часовому поясу чата
+	WeeklyEnabled bool
+	WeeklyDay     time.Weekday
+	WeeklyTime    int
+	Sections      []string // Разделы из DigestSections
+	Tickers       []string // Пусто - отслеживаемые тикеры чата (см. GetWatchedTickers)
+}
+
+// Has проверяет, включён ли раздел дайджеста.
+func (s DigestSettings) Has(section string) bool {
+	for _, v := range s.Sections {
+		if v == section {
+			return true
+		}
+	}
+	return false
+}
+
+// NewDigestSettings возвращает настройки дайджеста по умолчанию: рассылка выключена,
+// ежедневный в 19:00, еженедельный в пятницу в 19:00, все разделы.
+func NewDigestSettings(chatID int64) DigestSettings {
+	return DigestSettings{
+		ChatID:     chatID,
+		DailyTime:  19 * 60,
+		WeeklyDay:  time.Friday,
+		WeeklyTime: 19 * 60,
+		Sections:   append([]string(nil), DigestSections...),
+	}
+}
+
+const digestColumns = `chat_id, daily_enabled, daily_time, weekly_enabled, weekly_day, weekly_time, sections, tickers`
+
+func scanDigestSettings(row interface{ Scan(...interface{}) error }) (DigestSettings, error) {
+	var s DigestSettings
+	var weekday int
+	var sections, tickers string
+	err := row.Scan(&s.ChatID, &s.DailyEnabled, &s.DailyTime, &s.WeeklyEnabled, &weekday, &s.WeeklyTime, &sections, &tickers)
+	if err != nil {
+		return s, err
+	}
+	s.WeeklyDay = time.Weekday(weekday)
+	s.Sections = splitList(sections)
+	s.Tickers = splitList(tickers)
+	return s, nil
+}
+
+// splitList разбирает список, сохранённый через запятую.
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// GetDigestSettings возвращает настройки дайджеста чата или настройки по умолчанию.
+func GetDigestSettings(chatID int64) (DigestSettings, error) {
+	row := db.GlobalDB.QueryRow(`SELECT `+digestColumns+` FROM digest_settings WHERE chat_id = $1`, chatID)
+	s, err := scanDigestSettings(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return NewDigestSettings(chatID), nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("ошибка при получении настроек дайджеста чата %d: %w", chatID, err)
+	}
+	return s, nil
+}
+
+// SaveDigestSettings создаёт или обновляет настройки дайджеста чата.
+func SaveDigestSettings(s DigestSettings) error {
+	_, err := db.GlobalDB.Exec(`
+		INSERT INTO digest_settings (`+digestColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (chat_id) DO UPDATE
+		SET daily_enabled = EXCLUDED.daily_enabled, daily_time = EXCLUDED.daily_time,
+		    weekly_enabled = EXCLUDED.weekly_enabled, weekly_day = EXCLUDED.weekly_day, weekly_time = EXCLUDED.weekly_time,
+		    sections = EXCLUDED.sections, tickers = EXCLUDED.tickers`,
+		s.ChatID, s.DailyEnabled, s.DailyTime, s.WeeklyEnabled, int(s.WeeklyDay), s.WeeklyTime,
+		strings.Join(s.Sections, ","), strings.Join(s.Tickers, ","))
+	if err != nil {
+		return fmt.Errorf("ошибка при сохранении настроек дайджеста чата %d: %w", s.ChatID, err)
+	}
+	return nil
+}
+
+// GetScheduledDigests возвращает настройки чатов, у которых включена хотя бы одна рассылка.
+func GetScheduledDigests() ([]DigestSettings, error) {
+	rows, err := db.GlobalDB.Query(`SELECT ` + digestColumns + ` FROM digest_settings WHERE daily_enabled OR weekly_enabled`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении расписания дайджестов: %w", err)
+	}
+	defer rows.Close()
+
+	var list []DigestSettings
+	for rows.Next() {
+		s, err := scanDigestSettings(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при чтении настроек дайджеста: %w", err)
+		}
+		list = append(list, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении настроек дайджестов: %w", err)
+	}
+	return list, nil
+}
+
+// ClaimDigest атомарно отмечает, что дайджест вида kind за день day отправлен чату.
+// Возвращает false, если он уже был отправлен (например, до перезапуска).
+func ClaimDigest(chatID int64, kind string, day time.Time) (bool, error) {
+	column := "last_daily"
+	if kind == DigestWeekly {
+		column = "last_weekly"
+	}
+	res, err := db.GlobalDB.Exec(`
+		UPDATE digest_settings SET `+column+` = $2
+		WHERE chat_id = $1 AND (`+column+` IS NULL OR `+column+` < $2)`, chatID, day.Format("2006-01-02"))
+	if err != nil {
+		return false, fmt.Errorf("ошибка при отметке дайджеста чата %d: %w", chatID, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ошибка при отметке дайджеста чата %d: %w", chatID, err)
+	}
+	return n == 1, nil
+}
+
+// ReleaseDigest снимает отметку ClaimDigest за день day, если дайджест не удалось
+// сформировать или поставить в очередь: следующий запуск попробует снова.
+func ReleaseDigest(chatID int64, kind string, day time.Time) error {
+	column := "last_daily"
+	if kind == DigestWeekly {
+		column = "last_weekly"
+	}
+	_, err := db.GlobalDB.Exec(`
+		UPDATE digest_settings SET `+column+` = NULL
+		WHERE chat_id = $1 AND `+column+` = $2`, chatID, day.Format("2006-01-02"))
+	if err != nil {
+		return fmt.Errorf("ошибка при сбросе отметки дайджеста чата %d: %w", chatID, err)
+	}
+	return nil
+}
+
+// GetWatchedTickers возвращает тикеры, за которыми следит чат: из активных
+// оповещений и подписок на резкие изменения и гэпы.
+func GetWatchedTickers(chatID int64) ([]string, error) {
+	rows, err := db.GlobalDB.Query(`
+		SELECT ticker FROM alerts WHERE chat_id = $1 AND status = 'active' AND ticker <> ''
+		UNION
+		SELECT ticker FROM spike_subscriptions WHERE chat_id = $1
+		UNION
+		SELECT ticker FROM gap_subscriptions WHERE chat_id = $1 AND ticker <> ''
+		ORDER BY ticker`, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении тикеров чата %d: %w", chatID, err)
+	}
+	defer rows.Close()
+
+	var tickers []string
+	for rows.Next() {
+		var t string
+		if err := rows.Scan(&t); err != nil {
+			return nil, fmt.Errorf("ошибка при чтении тикера: %w", err)
+		}
+		tickers = append(tickers, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении тикеров чата %d: %w", chatID, err)
+	}
+	return tickers, nil
+}
+
+// PriceSummary represents a ticker's price statistics over a period
+type PriceSummary struct {
+	Ticker    string
+	Open      float64
+	Close     float64
+	Low       float64
+	High      float64
+	PrevClose float64 // Последняя цена до начала периода; 0 - неизвестна
+}
+
+// Change возвращает изменение цены за период в процентах: к закрытию
+// предыдущего периода, а если оно неизвестно - к первой цене периода.
+func (p PriceSummary) Change() float64 {
+	base := p.PrevClose
+	if base <= 0 {
+		base = p.Open
+	}
+	if base <= 0 { // Избегаем деления на ноль
+		return 0
+	}
+	return (p.Close - base) / base * 100
+}
+
+// GetPriceSummaries возвращает статистику цен тикеров за [from, to), собранную
+// из свечей (см. GetCandles), в порядке тикеров. Тикеры без цен за период
+// в результат не попадают.
+func GetPriceSummaries(tickers []string, from, to time.Time) ([]PriceSummary, error) {
+	// Часовые свечи годятся, если период начинается с начала часа (полночь по
+	// Москве), иначе свечи собираются из минутных.
+	bar := time.Hour
+	if !from.Truncate(bar).Equal(from) {
+		bar = time.Minute
+	}
+	sorted := slices.Clone(tickers)
+	slices.Sort(sorted)
+
+	var summaries []PriceSummary
+	for _, ticker := range sorted {
+		candles, err := GetCandles(ticker, bar, from, to)
+		if err != nil {
+			return nil, err
+		}
+		if len(candles) == 0 {
+			continue
+		}
+		p := PriceSummary{
+			Ticker: ticker,
+			Open:   candles[0].Open,
+			Close:  candles[len(candles)-1].Close,
+			Low:    candles[0].Low,
+			High:   candles[0].High,
+		}
+		for _, c := range candles[1:] {
+			p.Low, p.High = min(p.Low, c.Low), max(p.High, c.High)
+		}
+		prev, err := GetLastCandles(ticker, bar, 1, from)
+		if err != nil {
+			return nil, err
+		}
+		if len(prev) > 0 {
+			p.PrevClose = prev[0].Close
+		}
+		summaries = append(summaries, p)
+	}
+	return summaries, nil
+}
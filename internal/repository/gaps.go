@@ -0,0 +1,193 @@
+// TradeTGBot/internal/repository/gaps.go
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"TradeTGBot/internal/db"
+)
+
+// OpeningGap represents a ticker's opening gap relative to the previous close
+type OpeningGap struct {
+	Day       time.Time
+	Ticker    string
+	PrevClose float64 // Последняя цена предыдущего дня
+	Open      float64 // Первая цена после открытия основной сессии
+	Gap       float64 // Гэп в процентах; положительный - гэп вверх
+}
+
+// GapSubscription represents a chat's subscription to opening gap notifications
+type GapSubscription struct {
+	ID        int
+	ChatID    int64
+	Ticker    string  // GapReport - ежедневный отчёт по всем тикерам
+	MinGap    float64 // Минимальный гэп по модулю в процентах
+	CreatedAt time.Time
+}
+
+// GapReport - значение Ticker подписки на ежедневный отчёт о гэпах.
+const GapReport = ""
+
+// FindOpeningGaps вычисляет гэпы всех тикеров, у которых есть и цена до начала дня
+// dayStart (закрытие предыдущего дня, не старше недели), и цена в интервале [open, until).
+func FindOpeningGaps(dayStart, open, until time.Time) ([]OpeningGap, error) {
+	rows, err := db.GlobalDB.Query(`
+		SELECT c.ticker, c.price, o.price
+		FROM (
+			SELECT DISTINCT ON (ticker) ticker, price
+			FROM stock_prices
+			WHERE timestamp < $1 AND timestamp >= $1 - interval '7 days'
+			ORDER BY ticker, timestamp DESC
+		) c
+		JOIN (
+			SELECT DISTINCT ON (ticker) ticker, price
+			FROM stock_prices
+			WHERE timestamp >= $2 AND timestamp < $3
+			ORDER BY ticker, timestamp
+		) o ON o.ticker = c.ticker
+		ORDER BY c.ticker`, dayStart, open, until)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при вычислении гэпов открытия: %w", err)
+	}
+	defer rows.Close()
+
+	var gaps []OpeningGap
+	for rows.Next() {
+		g := OpeningGap{Day: dayStart}
+		if err := rows.Scan(&g.Ticker, &g.PrevClose, &g.Open); err != nil {
+			return nil, fmt.Errorf("ошибка при чтении гэпа открытия: %w", err)
+		}
+		if g.PrevClose <= 0 { // Избегаем деления на ноль
+			continue
+		}
+		g.Gap = (g.Open - g.PrevClose) / g.PrevClose * 100
+		gaps = append(gaps, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении гэпов открытия: %w", err)
+	}
+	return gaps, nil
+}
+
+// SaveOpeningGaps сохраняет гэпы дня. Возвращает false, если гэпы этого дня уже были
+// сохранены (например, до перезапуска) - тогда уведомления о них уже отправлены.
+func SaveOpeningGaps(day time.Time, gaps []OpeningGap) (bool, error) {
+	tx, err := db.GlobalDB.Begin()
+	if err != nil {
+		return false, fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Дата передаётся строкой, чтобы часовой пояс сервера БД не сдвинул день.
+	date := day.Format("2006-01-02")
+	var exists bool
+	err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM opening_gaps WHERE day = $1)`, date).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("ошибка при проверке гэпов за %s: %w", date, err)
+	}
+	if exists {
+		return false, nil
+	}
+	for _, g := range gaps {
+		_, err := tx.Exec(`
+			INSERT INTO opening_gaps (day, ticker, prev_close, open_price, gap)
+			VALUES ($1, $2, $3, $4, $5)`, date, g.Ticker, g.PrevClose, g.Open, g.Gap)
+		if err != nil {
+			return false, fmt.Errorf("ошибка при сохранении гэпа %s: %w", g.Ticker, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("ошибка при сохранении гэпов: %w", err)
+	}
+	return true, nil
+}
+
+// GetOpeningGaps возвращает сохранённые гэпы дня от наибольшего гэпа вверх к наибольшему вниз.
+func GetOpeningGaps(day time.Time) ([]OpeningGap, error) {
+	rows, err := db.GlobalDB.Query(`
+		SELECT day, ticker, prev_close, open_price, gap
+		FROM opening_gaps
+		WHERE day = $1
+		ORDER BY gap DESC`, day.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении гэпов за %s: %w", day.Format("2006-01-02"), err)
+	}
+	defer rows.Close()
+
+	var gaps []OpeningGap
+	for rows.Next() {
+		var g OpeningGap
+		if err := rows.Scan(&g.Day, &g.Ticker, &g.PrevClose, &g.Open, &g.Gap); err != nil {
+			return nil, fmt.Errorf("ошибка при чтении гэпа: %w", err)
+		}
+		gaps = append(gaps, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении гэпов: %w", err)
+	}
+	return gaps, nil
+}
+
+// SaveGapSubscription подписывает чат на гэпы тикера (или на ежедневный отчёт)
+// или обновляет минимальный гэп существующей подписки.
+func SaveGapSubscription(s GapSubscription) error {
+	_, err := db.GlobalDB.Exec(`
+		INSERT INTO gap_subscriptions (chat_id, ticker, min_gap)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (chat_id, ticker) DO UPDATE SET min_gap = EXCLUDED.min_gap`,
+		s.ChatID, s.Ticker, s.MinGap)
+	if err != nil {
+		return fmt.Errorf("ошибка при сохранении подписки чата %d на гэпы %s: %w", s.ChatID, s.Ticker, err)
+	}
+	return nil
+}
+
+// DeleteGapSubscription удаляет подписку чата на гэпы тикера (GapReport - на отчёт).
+// Возвращает число удалённых подписок.
+func DeleteGapSubscription(chatID int64, ticker string) (int, error) {
+	return deleteGapSubscriptions(`DELETE FROM gap_subscriptions WHERE chat_id = $1 AND ticker = $2`, chatID, ticker)
+}
+
+// DeleteAllGapSubscriptions удаляет все подписки чата на гэпы, включая отчёт.
+func DeleteAllGapSubscriptions(chatID int64) (int, error) {
+	return deleteGapSubscriptions(`DELETE FROM gap_subscriptions WHERE chat_id = $1`, chatID)
+}
+
+func deleteGapSubscriptions(query string, chatID int64, args ...interface{}) (int, error) {
+	res, err := db.GlobalDB.Exec(query, append([]interface{}{chatID}, args...)...)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при удалении подписок чата %d на гэпы: %w", chatID, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при удалении подписок чата %d на гэпы: %w", chatID, err)
+	}
+	return int(n), nil
+}
+
+// GetGapSubscriptions возвращает подписки чата на гэпы; если chatID равен 0 - всех чатов.
+func GetGapSubscriptions(chatID int64) ([]GapSubscription, error) {
+	rows, err := db.GlobalDB.Query(`
+		SELECT id, chat_id, ticker, min_gap, created_at
+		FROM gap_subscriptions
+		WHERE $1 = 0 OR chat_id = $1
+		ORDER BY ticker, chat_id`, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении подписок на гэпы: %w", err)
+	}
+	defer rows.Close()
+
+	var subs []GapSubscription
+	for rows.Next() {
+		var s GapSubscription
+		if err := rows.Scan(&s.ID, &s.ChatID, &s.Ticker, &s.MinGap, &s.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка при чтении подписки на гэпы: %w", err)
+		}
+		subs = append(subs, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении подписок на гэпы: %w", err)
+	}
+	return subs, nil
+}
@@ -0,0 +1,203 @@
+// TradeTGBot/internal/repository/history.go
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"TradeTGBot/internal/db"
+
+	"github.com/lib/pq"
+)
+
+// AlertTrigger represents a record of a fired alert in the alert_history table
+type AlertTrigger struct {
+	ID             int
+	AlertID        int
+	ChatID         int64
+	Ticker         string   // Пустой для составных условий
+	Tickers        []string // Все тикеры оповещения: для составных условий и спредов их несколько
+	Kind           string
+	Target         float64
+	Direction      string
+	Definition     string // Описание оповещения на момент срабатывания
+	Params         string
+	TriggerPrice   float64
+	TriggeredAt    time.Time
+	NotificationID int
+	DeliveryStatus string // Статус уведомления в outbox: pending, delivered, dead
+	Action         string // Действие пользователя по кнопке уведомления: ack, snooze, again, level
+	UserID         int64  // Создатель оповещения
+	UserName       string
+	Urgent         bool // Было ли оповещение срочным; сохраняется при повторном включении
+}
+
+// Действия пользователя с уведомлением о срабатывании.
+const (
+	TriggerActionAck    = "ack"
+	TriggerActionSnooze = "snooze"
+	TriggerActionAgain  = "again"
+	TriggerActionLevel  = "level"
+)
+
+// Alert восстанавливает определение сработавшего оповещения (без ID и состояния).
+func (t AlertTrigger) Alert() Alert {
+	return Alert{
+		Ticker:    t.Ticker,
+		Target:    t.Target,
+		ChatID:    t.ChatID,
+		Direction: t.Direction,
+		Kind:      t.Kind,
+		Params:    t.Params,
+		UserID:    t.UserID,
+		UserName:  t.UserName,
+		Urgent:    t.Urgent,
+	}
+}
+
+// triggerColumns - колонки выборки AlertTrigger (h - alert_history, o - notification_outbox).
+const triggerColumns = `
+	h.id, h.alert_id, h.chat_id, h.ticker, h.kind, h.target, h.direction, h.definition, h.params,
+	h.trigger_price, h.triggered_at, COALESCE(h.notification_id, 0), COALESCE(o.status, ''), h.action,
+	h.user_id, h.user_name, h.tickers, h.urgent`
+
+// scanAlertTriggers читает строки, выбранные с колонками triggerColumns.
+func scanAlertTriggers(rows *sql.Rows) ([]AlertTrigger, error) {
+	defer rows.Close()
+	var history []AlertTrigger
+	for rows.Next() {
+		var t AlertTrigger
+		err := rows.Scan(&t.ID, &t.AlertID, &t.ChatID, &t.Ticker, &t.Kind, &t.Target, &t.Direction, &t.Definition,
+			&t.Params, &t.TriggerPrice, &t.TriggeredAt, &t.NotificationID, &t.DeliveryStatus, &t.Action,
+			&t.UserID, &t.UserName, pq.Array(&t.Tickers), &t.Urgent)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при чтении истории оповещений: %w", err)
+		}
+		history = append(history, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении истории оповещений: %w", err)
+	}
+	return history, nil
+}
+
+// insertAlertTrigger записывает срабатывание в историю в рамках транзакции.
+func insertAlertTrigger(tx *sql.Tx, t AlertTrigger) error {
+	query := `
+		INSERT INTO alert_history (alert_id, chat_id, ticker, kind, target, direction, definition, params, trigger_price, notification_id,
+		                           user_id, user_name, tickers, urgent)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+	_, err := tx.Exec(query, t.AlertID, t.ChatID, t.Ticker, t.Kind, t.Target, t.Direction, t.Definition,
+		t.Params, t.TriggerPrice, t.NotificationID, t.UserID, t.UserName, pq.Array(t.Tickers), t.Urgent)
+	if err != nil {
+		return fmt.Errorf("ошибка при записи срабатывания оповещения %d в историю: %w", t.AlertID, err)
+	}
+	return nil
+}
+
+// GetAlertHistory возвращает страницу истории срабатываний чата (новые первыми)
+// и общее число записей. Если ticker не пуст, выбираются только записи оповещений,
+// в которых участвует этот тикер, включая составные условия и спреды.
+func GetAlertHistory(chatID int64, ticker string, limit, offset int) ([]AlertTrigger, int, error) {
+	var total int
+	err := db.GlobalDB.QueryRow(`
+		SELECT COUNT(*) FROM alert_history
+		WHERE chat_id = $1 AND ($2 = '' OR $2 = ANY(tickers))`, chatID, ticker).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("ошибка при подсчёте истории оповещений чата %d: %w", chatID, err)
+	}
+
+	query := `
+		SELECT` + triggerColumns + `
+		FROM alert_history h
+		LEFT JOIN notification_outbox o ON o.id = h.notification_id
+		WHERE h.chat_id = $1 AND ($2 = '' OR $2 = ANY(h.tickers))
+		ORDER BY h.triggered_at DESC, h.id DESC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := db.GlobalDB.Query(query, chatID, ticker, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("ошибка при получении истории оповещений чата %d: %w", chatID, err)
+	}
+	history, err := scanAlertTriggers(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return history, total, nil
+}
+
+// GetAlertTriggersBetween возвращает срабатывания чата за [from, to) в хронологическом порядке.
+func GetAlertTriggersBetween(chatID int64, from, to time.Time) ([]AlertTrigger, error) {
+	rows, err := db.GlobalDB.Query(`
+		SELECT`+triggerColumns+`
+		FROM alert_history h
+		LEFT JOIN notification_outbox o ON o.id = h.notification_id
+		WHERE h.chat_id = $1 AND h.triggered_at >= $2 AND h.triggered_at < $3
+		ORDER BY h.triggered_at, h.id`, chatID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении срабатываний чата %d: %w", chatID, err)
+	}
+	return scanAlertTriggers(rows)
+}
+
+// GetAlertTrigger возвращает запись истории срабатываний по ID.
+func GetAlertTrigger(id int) (AlertTrigger, error) {
+	rows, err := db.GlobalDB.Query(`
+		SELECT`+triggerColumns+`
+		FROM alert_history h
+		LEFT JOIN notification_outbox o ON o.id = h.notification_id
+		WHERE h.id = $1`, id)
+	if err != nil {
+		return AlertTrigger{}, fmt.Errorf("ошибка при получении записи истории %d: %w", id, err)
+	}
+	history, err := scanAlertTriggers(rows)
+	if err != nil {
+		return AlertTrigger{}, err
+	}
+	if len(history) == 0 {
+		return AlertTrigger{}, fmt.Errorf("запись истории %d не найдена", id)
+	}
+	return history[0], nil
+}
+
+// GetAlertTriggersByNotification возвращает срабатывания, о которых сообщает уведомление.
+func GetAlertTriggersByNotification(notificationID int) ([]AlertTrigger, error) {
+	rows, err := db.GlobalDB.Query(`
+		SELECT`+triggerColumns+`
+		FROM alert_history h
+		LEFT JOIN notification_outbox o ON o.id = h.notification_id
+		WHERE h.notification_id = $1
+		ORDER BY h.id`, notificationID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении срабатываний уведомления %d: %w", notificationID, err)
+	}
+	return scanAlertTriggers(rows)
+}
+
+// ClaimAlertTrigger атомарно фиксирует действие пользователя над срабатыванием.
+// Возвращает false, если по этой записи уже было выполнено действие
+// (защита от повторных нажатий кнопок).
+func ClaimAlertTrigger(id int, action string) (bool, error) {
+	res, err := db.GlobalDB.Exec(`
+		UPDATE alert_history SET action = $2, action_at = now()
+		WHERE id = $1 AND action = ''`, id, action)
+	if err != nil {
+		return false, fmt.Errorf("ошибка при сохранении действия по записи истории %d: %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ошибка при сохранении действия по записи истории %d: %w", id, err)
+	}
+	return n == 1, nil
+}
+
+// ReleaseAlertTrigger снимает отметку действия, если его не удалось выполнить.
+func ReleaseAlertTrigger(id int) error {
+	_, err := db.GlobalDB.Exec(`UPDATE alert_history SET action = '', action_at = NULL WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("ошибка при сбросе действия по записи истории %d: %w", id, err)
+	}
+	return nil
+}
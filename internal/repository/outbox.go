@@ -0,0 +1,188 @@
+// TradeTGBot/internal/repository/outbox.go
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"TradeTGBot/internal/db"
+)
+
+// Статусы уведомлений в таблице notification_outbox.
+const (
+	NotificationPending   = "pending"
+	NotificationDelivered = "delivered"
+	NotificationDead      = "dead"
+)
+
+// Notification represents a message waiting for delivery in the outbox
+type Notification struct {
+	ID            int
+	ChatID        int64
+	AlertID       int // 0, если уведомление не связано с оповещением
+	Text          string
+	ParseMode     string
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	CreatedAt     time.Time
+	Urgent        bool   // Доставляется и в тихие часы
+	Deferred      bool   // Было отложено из-за тихих часов
+	Channel       string // Канал доставки: telegram, email, webhook
+	RouteID       int    // Маршрут с адресом доставки; 0 для Telegram по умолчанию
+}
+
+// insertNotification добавляет уведомление в outbox в рамках транзакции - по одной
+// записи на каждый подходящий маршрут чата. Возвращает ID основной записи
+// (Telegram, если уведомление идёт в него).
+func insertNotification(tx *sql.Tx, n Notification) (int, error) {
+	routes, err := queryNotificationRoutes(tx, n.ChatID)
+	if err != nil {
+		return 0, err
+	}
+	query := `
+		INSERT INTO notification_outbox (chat_id, alert_id, text, parse_mode, urgent, channel, route_id)
+		VALUES ($1, NULLIF($2, 0), $3, $4, $5, $6, NULLIF($7, 0))
+		RETURNING id
+	`
+	var primary int
+	for i, r := range routeNotification(routes, n) {
+		var id int
+		err := tx.QueryRow(query, n.ChatID, n.AlertID, n.Text, n.ParseMode, n.Urgent, r.Channel, r.ID).Scan(&id)
+		if err != nil {
+			return 0, fmt.Errorf("ошибка при добавлении уведомления в outbox для чата %d: %w", n.ChatID, err)
+		}
+		if i == 0 {
+			primary = id
+		}
+	}
+	return primary, nil
+}
+
+// EnqueueNotification ставит уведомление в очередь на доставку.
+func EnqueueNotification(n Notification) (int, error) {
+	tx, err := db.GlobalDB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при открытии транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	id, err := insertNotification(tx, n)
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// TriggerAlert атомарно ставит уведомление о срабатывании в очередь, записывает
+// срабатывание в историю и переводит оповещение в статус triggered.
+// Оповещение удаляется только после доставки.
+func TriggerAlert(n Notification, trigger AlertTrigger) (int, error) {
+	tx, err := db.GlobalDB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при открытии транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	id, err := insertNotification(tx, n)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(`UPDATE alerts SET status = 'triggered' WHERE id = $1`, n.AlertID)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при обновлении статуса оповещения %d: %w", n.AlertID, err)
+	}
+	trigger.NotificationID = id
+	if err = insertAlertTrigger(tx, trigger); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// GetDueNotifications возвращает до limit ожидающих уведомлений, время попытки доставки которых наступило.
+func GetDueNotifications(limit int) ([]Notification, error) {
+	query := `
+		SELECT id, chat_id, COALESCE(alert_id, 0), text, parse_mode, status, attempts, next_attempt_at, last_error, created_at,
+		       urgent, deferred, channel, COALESCE(route_id, 0)
+		FROM notification_outbox
+		WHERE status = 'pending' AND next_attempt_at <= now()
+		ORDER BY id
+		LIMIT $1
+	`
+	rows, err := db.GlobalDB.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении уведомлений из outbox: %w", err)
+	}
+	defer rows.Close()
+
+	var notifications []Notification
+	for rows.Next() {
+		var n Notification
+		err := rows.Scan(&n.ID, &n.ChatID, &n.AlertID, &n.Text, &n.ParseMode, &n.Status,
+			&n.Attempts, &n.NextAttemptAt, &n.LastError, &n.CreatedAt, &n.Urgent, &n.Deferred,
+			&n.Channel, &n.RouteID)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при чтении уведомления из outbox: %w", err)
+		}
+		notifications = append(notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении уведомлений из outbox: %w", err)
+	}
+	return notifications, nil
+}
+
+// MarkNotificationDelivered отмечает уведомление доставленным и удаляет связанное
+// с ним оповещение - только теперь оно считается использованным.
+func MarkNotificationDelivered(n Notification) error {
+	tx, err := db.GlobalDB.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка при открытии транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE notification_outbox
+		SET status = 'delivered', attempts = attempts + 1, delivered_at = now(), last_error = ''
+		WHERE id = $1`, n.ID)
+	if err != nil {
+		return fmt.Errorf("ошибка при отметке доставки уведомления %d: %w", n.ID, err)
+	}
+	if n.AlertID != 0 {
+		if _, err = tx.Exec(`DELETE FROM alerts WHERE id = $1`, n.AlertID); err != nil {
+			return fmt.Errorf("ошибка при удалении оповещения %d: %w", n.AlertID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// MarkNotificationFailed фиксирует неудачную попытку доставки. Если dead равно true,
+// уведомление переводится в статус dead и больше не отправляется.
+func MarkNotificationFailed(id int, lastErr string, nextAttempt time.Time, dead bool) error {
+	status := NotificationPending
+	if dead {
+		status = NotificationDead
+	}
+	_, err := db.GlobalDB.Exec(`
+		UPDATE notification_outbox
+		SET status = $2, attempts = attempts + 1, next_attempt_at = $3, last_error = $4
+		WHERE id = $1`, id, status, nextAttempt, lastErr)
+	if err != nil {
+		return fmt.Errorf("ошибка при отметке неудачной доставки уведомления %d: %w", id, err)
+	}
+	return nil
+}
+
+// DeferNotification откладывает доставку уведомления до until без учёта попытки
+// (например, до окончания тихих часов чата).
+func DeferNotification(id int, until time.Time) error {
+	_, err := db.GlobalDB.Exec(`
+		UPDATE notification_outbox SET next_attempt_at = $2, deferred = true
+		WHERE id = $1`, id, until)
+	if err != nil {
+		return fmt.Errorf("ошибка при откладывании уведомления %d: %w", id, err)
+	}
+	return nil
+}
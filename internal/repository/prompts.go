@@ -0,0 +1,64 @@
+// TradeTGBot/internal/repository/prompts.go
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"TradeTGBot/internal/db"
+)
+
+// Действия, ожидающие ответа пользователя на сообщение бота.
+const (
+	PromptNewLevel = "level" // Новый уровень для сработавшего оповещения
+)
+
+// Prompt represents a bot message that waits for a reply with user input
+type Prompt struct {
+	ChatID    int64
+	MessageID int    // Сообщение бота, на которое нужно ответить
+	Action    string // Одно из Prompt*
+	TriggerID int    // Запись alert_history, к которой относится запрос
+	CreatedAt time.Time
+}
+
+// SavePrompt запоминает, какой ввод ожидается в ответ на сообщение бота.
+func SavePrompt(p Prompt) error {
+	_, err := db.GlobalDB.Exec(`
+		INSERT INTO bot_prompts (chat_id, message_id, action, trigger_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (chat_id, message_id) DO UPDATE SET
+			action = EXCLUDED.action, trigger_id = EXCLUDED.trigger_id, created_at = now()`,
+		p.ChatID, p.MessageID, p.Action, p.TriggerID)
+	if err != nil {
+		return fmt.Errorf("ошибка при сохранении запроса ввода в чате %d: %w", p.ChatID, err)
+	}
+	return nil
+}
+
+// GetPrompt возвращает запрос ввода по сообщению бота. Второе значение - false,
+// если сообщение не ожидает ответа.
+func GetPrompt(chatID int64, messageID int) (Prompt, bool, error) {
+	p := Prompt{ChatID: chatID, MessageID: messageID}
+	err := db.GlobalDB.QueryRow(`
+		SELECT action, trigger_id, created_at FROM bot_prompts
+		WHERE chat_id = $1 AND message_id = $2`, chatID, messageID).Scan(&p.Action, &p.TriggerID, &p.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return p, false, nil
+	}
+	if err != nil {
+		return p, false, fmt.Errorf("ошибка при получении запроса ввода в чате %d: %w", chatID, err)
+	}
+	return p, true, nil
+}
+
+// DeletePrompt удаляет выполненный запрос ввода.
+func DeletePrompt(chatID int64, messageID int) error {
+	_, err := db.GlobalDB.Exec(`DELETE FROM bot_prompts WHERE chat_id = $1 AND message_id = $2`, chatID, messageID)
+	if err != nil {
+		return fmt.Errorf("ошибка при удалении запроса ввода в чате %d: %w", chatID, err)
+	}
+	return nil
+}
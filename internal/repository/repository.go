@@ -25,6 +25,17 @@ type Alert struct {
 	Target    float64
 	ChatID    int64
 	Direction string // "up" or "down"
+	Kind      string // "price", "condition", "indicator", "trailing", "range", "spread"
+	Params    string // JSON with kind-specific parameters
+	Peak      float64
+	CreatedAt time.Time
+
+	SnoozedUntil time.Time // Нулевое значение - оповещение не отложено
+	Urgent       bool      // Уведомление доставляется и в тихие часы
+	DataWarned   bool      // Чат уже предупреждён, что истории для индикатора недостаточно
+
+	UserID   int64  // Участник чата, создавший оповещение (0 - неизвестен)
+	UserName string // Как упоминать создателя: @username или имя
 }
 
 // SaveStockPrice сохраняет цену акции в базе данных.
@@ -60,28 +71,154 @@ func GetAveragePrice(ticker string, duration time.Duration) (float64, error) {
 	return avgPrice.Float64, nil
 }
 
-// SaveAlert сохраняет новое оповещение пользователя в базе данных.
-// (В текущей реализации алерты еще в памяти, но эта функция для будущего расширения)
-func SaveAlert(alert Alert) error {
-	// Пока алерты хранятся в памяти в pkg/bot, эта функция просто заглушка или для будущего использования с БД
-	// Если бы алерты были в БД, тут был бы INSERT запрос
-	log.Printf("DEBUG: Алерт для %s@%d (%.2f) был бы сохранен в БД", alert.Ticker, alert.ChatID, alert.Target)
-	return nil
+// GetPriceHistory возвращает тики цены акции начиная с указанного момента в хронологическом порядке.
+func GetPriceHistory(ticker string, since time.Time) ([]StockPrice, error) {
+	query := `
+		SELECT id, ticker, price, timestamp
+		FROM stock_prices
+		WHERE ticker = $1 AND timestamp >= $2
+		ORDER BY timestamp
+	`
+	rows, err := db.GlobalDB.Query(query, ticker, since)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении истории цен для %s: %w", ticker, err)
+	}
+	defer rows.Close()
+
+	var history []StockPrice
+	for rows.Next() {
+		var sp StockPrice
+		if err := rows.Scan(&sp.ID, &sp.Ticker, &sp.Price, &sp.Timestamp); err != nil {
+			return nil, fmt.Errorf("ошибка при чтении истории цен для %s: %w", ticker, err)
+		}
+		history = append(history, sp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении истории цен для %s: %w", ticker, err)
+	}
+	return history, nil
 }
 
-// GetActiveAlerts получает все активные оповещения из базы данных.
-// (В текущей реализации алерты еще в памяти, но эта функция для будущего расширения)
+// GetPriceHistoryBetween возвращает историю цен тикера за период [from, to) в порядке возрастания времени.
+func GetPriceHistoryBetween(ticker string, from, to time.Time) ([]StockPrice, error) {
+	query := `
+		SELECT id, ticker, price, timestamp
+		FROM stock_prices
+		WHERE ticker = $1 AND timestamp >= $2 AND timestamp < $3
+		ORDER BY timestamp
+	`
+	rows, err := db.GlobalDB.Query(query, ticker, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении истории цен для %s: %w", ticker, err)
+	}
+	defer rows.Close()
+
+	var history []StockPrice
+	for rows.Next() {
+		var sp StockPrice
+		if err := rows.Scan(&sp.ID, &sp.Ticker, &sp.Price, &sp.Timestamp); err != nil {
+			return nil, fmt.Errorf("ошибка при чтении истории цен для %s: %w", ticker, err)
+		}
+		history = append(history, sp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении истории цен для %s: %w", ticker, err)
+	}
+	return history, nil
+}
+
+// SaveAlert сохраняет новое оповещение пользователя в базе данных и возвращает его ID.
+func SaveAlert(alert Alert) (int, error) {
+	query := `
+		INSERT INTO alerts (chat_id, kind, ticker, target, direction, params, peak, snoozed_until, urgent, user_id, user_name)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id
+	`
+	snoozedUntil := sql.NullTime{Time: alert.SnoozedUntil, Valid: !alert.SnoozedUntil.IsZero()}
+	var id int
+	err := db.GlobalDB.QueryRow(query, alert.ChatID, alert.Kind, alert.Ticker, alert.Target,
+		alert.Direction, alert.Params, alert.Peak, snoozedUntil, alert.Urgent, alert.UserID, alert.UserName).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при сохранении оповещения для %s: %w", alert.Ticker, err)
+	}
+	return id, nil
+}
+
+// GetActiveAlerts получает все активные (ещё не сработавшие) оповещения из базы данных.
 func GetActiveAlerts() ([]Alert, error) {
-	// Пока алерты хранятся в памяти в pkg/bot, эта функция просто заглушка
-	// и должна быть заменена реальным SELECT запросом из БД
-	log.Println("DEBUG: Получение активных алертов. Пока возвращаем пустой список, так как они в памяти.")
-	return []Alert{}, nil // Здесь должен быть SELECT * FROM alerts
+	query := `
+		SELECT id, chat_id, kind, ticker, target, direction, params, peak, created_at, snoozed_until, urgent,
+		       data_warned, user_id, user_name
+		FROM alerts
+		WHERE status = 'active'
+		ORDER BY id
+	`
+	rows, err := db.GlobalDB.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении оповещений: %w", err)
+	}
+	defer rows.Close()
+
+	var alerts []Alert
+	for rows.Next() {
+		var a Alert
+		var snoozedUntil sql.NullTime
+		err := rows.Scan(&a.ID, &a.ChatID, &a.Kind, &a.Ticker, &a.Target, &a.Direction, &a.Params, &a.Peak, &a.CreatedAt, &snoozedUntil, &a.Urgent,
+			&a.DataWarned, &a.UserID, &a.UserName)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при чтении оповещения: %w", err)
+		}
+		a.SnoozedUntil = snoozedUntil.Time
+		alerts = append(alerts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении оповещений: %w", err)
+	}
+	return alerts, nil
+}
+
+// UpdateAlertPeak сохраняет максимальную цену, достигнутую с момента создания трейлинг-оповещения.
+func UpdateAlertPeak(id int, peak float64) error {
+	_, err := db.GlobalDB.Exec(`UPDATE alerts SET peak = $1 WHERE id = $2`, peak, id)
+	if err != nil {
+		return fmt.Errorf("ошибка при обновлении пика оповещения %d: %w", id, err)
+	}
+	return nil
+}
+
+// UpdateAlertDirection сохраняет сторону, с которой цена подходит к уровню или коридору.
+func UpdateAlertDirection(id int, direction string) error {
+	_, err := db.GlobalDB.Exec(`UPDATE alerts SET direction = $1 WHERE id = $2`, direction, id)
+	if err != nil {
+		return fmt.Errorf("ошибка при обновлении направления оповещения %d: %w", id, err)
+	}
+	return nil
+}
+
+// MarkAlertDataWarned запоминает, что чат предупреждён о нехватке истории для
+// индикаторного оповещения, чтобы не повторять предупреждение после перезапуска.
+func MarkAlertDataWarned(id int) error {
+	_, err := db.GlobalDB.Exec(`UPDATE alerts SET data_warned = true WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("ошибка при обновлении оповещения %d: %w", id, err)
+	}
+	return nil
+}
+
+// SetAlertUrgent помечает оповещение срочным (или снимает отметку).
+func SetAlertUrgent(id int, urgent bool) error {
+	_, err := db.GlobalDB.Exec(`UPDATE alerts SET urgent = $1 WHERE id = $2`, urgent, id)
+	if err != nil {
+		return fmt.Errorf("ошибка при обновлении срочности оповещения %d: %w", id, err)
+	}
+	return nil
 }
 
 // DeleteAlert удаляет сработавший алерт из базы данных.
-// (В текущей реализации алерты еще в памяти, но эта функция для будущего расширения)
 func DeleteAlert(alert Alert) error {
-	// Если бы алерты были в БД, тут был бы DELETE запрос по ID или другим полям
-	log.Printf("DEBUG: Алерт для %s@%d (%.2f) был бы удален из БД", alert.Ticker, alert.ChatID, alert.Target)
+	_, err := db.GlobalDB.Exec(`DELETE FROM alerts WHERE id = $1`, alert.ID)
+	if err != nil {
+		return fmt.Errorf("ошибка при удалении оповещения %d: %w", alert.ID, err)
+	}
 	return nil
 }
@@ -0,0 +1,187 @@
+// TradeTGBot/internal/repository/routes.go
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"TradeTGBot/internal/db"
+)
+
+// Каналы доставки уведомлений.
+const (
+	ChannelTelegram = "telegram"
+	ChannelTopic    = "topic"   // Тема форума в этом же чате; Target - ID темы
+	ChannelPublish  = "channel" // Публикация в канал Telegram; Target - ID канала
+	ChannelEmail    = "email"
+	ChannelWebhook  = "webhook"
+)
+
+// Фильтры маршрутов: какие уведомления уходят в канал.
+const (
+	RouteAll    = "all"    // Все уведомления
+	RouteUrgent = "urgent" // Только срочные оповещения
+	RouteAlerts = "alerts" // Только срабатывания оповещений
+	RouteMarket = "market" // Только рыночные уведомления (резкие изменения цены и т.п.)
+	RouteOff    = "off"    // Ничего (имеет смысл для Telegram, который включён по умолчанию)
+)
+
+// RouteFilters - допустимые фильтры маршрутов.
+var RouteFilters = []string{RouteAll, RouteUrgent, RouteAlerts, RouteMarket, RouteOff}
+
+// NotificationRoute represents a chat's preference to deliver notifications to an extra channel
+type NotificationRoute struct {
+	ID        int
+	ChatID    int64
+	Channel   string
+	Target    string // Адрес email, URL вебхука, ID темы или канала; пустой для Telegram
+	Secret    string // Ключ HMAC-подписи вебхука
+	Filter    string
+	CreatedAt time.Time
+}
+
+// Matches сообщает, подходит ли уведомление под фильтр маршрута.
+func (r NotificationRoute) Matches(n Notification) bool {
+	switch r.Filter {
+	case RouteAll:
+		return true
+	case RouteUrgent:
+		return n.Urgent
+	case RouteAlerts:
+		return n.AlertID != 0
+	case RouteMarket:
+		return n.AlertID == 0
+	}
+	return false
+}
+
+// Validate проверяет адрес маршрута: ID темы - положительное число, ID канала -
+// ненулевое число. Адреса email и вебхуков проверяет команда /notify при разборе.
+func (r NotificationRoute) Validate() error {
+	switch r.Channel {
+	case ChannelTopic:
+		if id, err := strconv.Atoi(r.Target); err != nil || id <= 0 {
+			return fmt.Errorf("неверный ID темы %q", r.Target)
+		}
+	case ChannelPublish:
+		if id, err := strconv.ParseInt(r.Target, 10, 64); err != nil || id == 0 {
+			return fmt.Errorf("неверный ID канала %q", r.Target)
+		}
+	}
+	return nil
+}
+
+// routeNotification выбирает маршруты, по которым нужно доставить уведомление.
+// Telegram без явной настройки получает всё, что не ушло в тему форума этого чата.
+// Если ни один маршрут не подошёл, уведомление всё равно уходит в Telegram,
+// чтобы срабатывание не потерялось. Доставка в сам чат (или его тему) идёт первой.
+func routeNotification(routes []NotificationRoute, n Notification) []NotificationRoute {
+	telegram := NotificationRoute{ChatID: n.ChatID, Channel: ChannelTelegram, Filter: RouteAll}
+	var topics, extra []NotificationRoute
+	for _, r := range routes {
+		switch {
+		case r.Channel == ChannelTelegram:
+			telegram = r
+		case !r.Matches(n):
+		case r.Channel == ChannelTopic:
+			topics = append(topics, r)
+		default:
+			extra = append(extra, r)
+		}
+	}
+	selected := append(topics, extra...)
+	if (telegram.Matches(n) && len(topics) == 0) || len(selected) == 0 {
+		selected = append([]NotificationRoute{telegram}, selected...)
+	}
+	return selected
+}
+
+// routeColumns - колонки выборки NotificationRoute.
+const routeColumns = `id, chat_id, channel, target, secret, filter, created_at`
+
+func scanNotificationRoutes(rows *sql.Rows) ([]NotificationRoute, error) {
+	defer rows.Close()
+	var routes []NotificationRoute
+	for rows.Next() {
+		var r NotificationRoute
+		if err := rows.Scan(&r.ID, &r.ChatID, &r.Channel, &r.Target, &r.Secret, &r.Filter, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка при чтении маршрута уведомлений: %w", err)
+		}
+		routes = append(routes, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении маршрутов уведомлений: %w", err)
+	}
+	return routes, nil
+}
+
+// queryNotificationRoutes читает маршруты чата в рамках транзакции.
+func queryNotificationRoutes(tx *sql.Tx, chatID int64) ([]NotificationRoute, error) {
+	rows, err := tx.Query(`SELECT `+routeColumns+` FROM notification_routes WHERE chat_id = $1 ORDER BY id`, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении маршрутов уведомлений чата %d: %w", chatID, err)
+	}
+	return scanNotificationRoutes(rows)
+}
+
+// GetNotificationRoutes возвращает настроенные маршруты уведомлений чата.
+func GetNotificationRoutes(chatID int64) ([]NotificationRoute, error) {
+	rows, err := db.GlobalDB.Query(`SELECT `+routeColumns+` FROM notification_routes WHERE chat_id = $1 ORDER BY id`, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении маршрутов уведомлений чата %d: %w", chatID, err)
+	}
+	return scanNotificationRoutes(rows)
+}
+
+// GetNotificationRoute возвращает маршрут по ID. ok равно false, если маршрут удалён.
+func GetNotificationRoute(id int) (NotificationRoute, bool, error) {
+	var r NotificationRoute
+	err := db.GlobalDB.QueryRow(`SELECT `+routeColumns+` FROM notification_routes WHERE id = $1`, id).
+		Scan(&r.ID, &r.ChatID, &r.Channel, &r.Target, &r.Secret, &r.Filter, &r.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return r, false, nil
+	}
+	if err != nil {
+		return r, false, fmt.Errorf("ошибка при получении маршрута уведомлений %d: %w", id, err)
+	}
+	return r, true, nil
+}
+
+// SaveNotificationRoute создаёт маршрут или обновляет фильтр и секрет
+// существующего маршрута с тем же каналом и адресом.
+func SaveNotificationRoute(r NotificationRoute) (int, error) {
+	if err := r.Validate(); err != nil {
+		return 0, err
+	}
+	var id int
+	err := db.GlobalDB.QueryRow(`
+		INSERT INTO notification_routes (chat_id, channel, target, secret, filter)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (chat_id, channel, target) DO UPDATE
+		SET secret = EXCLUDED.secret, filter = EXCLUDED.filter
+		RETURNING id`,
+		r.ChatID, r.Channel, r.Target, r.Secret, r.Filter).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при сохранении маршрута уведомлений чата %d: %w", r.ChatID, err)
+	}
+	return id, nil
+}
+
+// DeleteNotificationRoutes удаляет маршруты канала; пустой target удаляет все адреса канала.
+// Возвращает число удалённых маршрутов.
+func DeleteNotificationRoutes(chatID int64, channel, target string) (int, error) {
+	res, err := db.GlobalDB.Exec(`
+		DELETE FROM notification_routes
+		WHERE chat_id = $1 AND channel = $2 AND ($3 = '' OR target = $3)`, chatID, channel, target)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при удалении маршрутов уведомлений чата %d: %w", chatID, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при удалении маршрутов уведомлений чата %d: %w", chatID, err)
+	}
+	return int(n), nil
+}
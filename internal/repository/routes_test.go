@@ -0,0 +1,117 @@
+// TradeTGBot/internal/repository/routes_test.go
+package repository
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRouteNotification(t *testing.T) {
+	const chatID = -100123
+	route := func(channel, target, filter string) NotificationRoute {
+		return NotificationRoute{ChatID: chatID, Channel: channel, Target: target, Filter: filter}
+	}
+	alert := Notification{ChatID: chatID, AlertID: 5}
+	urgent := Notification{ChatID: chatID, AlertID: 5, Urgent: true}
+	market := Notification{ChatID: chatID}
+
+	tests := []struct {
+		name   string
+		routes []NotificationRoute
+		n      Notification
+		want   []string // channel:target выбранных маршрутов по порядку
+	}{
+		{"без маршрутов - в чат", nil, alert, []string{"telegram:"}},
+		{"telegram выключен, email получает всё",
+			[]NotificationRoute{route(ChannelTelegram, "", RouteOff), route(ChannelEmail, "a@example.com", RouteAll)},
+			alert, []string{"email:a@example.com"}},
+		{"telegram выключен и ничего не подошло - всё равно в чат",
+			[]NotificationRoute{route(ChannelTelegram, "", RouteOff), route(ChannelWebhook, "https://hook", RouteMarket)},
+			alert, []string{"telegram:"}},
+		{"тема забирает уведомление у чата",
+			[]NotificationRoute{route(ChannelTopic, "17", RouteAll)},
+			alert, []string{"topic:17"}},
+		{"тема только для срочных - обычное в чат",
+			[]NotificationRoute{route(ChannelTopic, "17", RouteUrgent)},
+			alert, []string{"telegram:"}},
+		{"тема только для срочных - срочное в тему",
+			[]NotificationRoute{route(ChannelTopic, "17", RouteUrgent)},
+			urgent, []string{"topic:17"}},
+		{"чат первым, остальные в порядке маршрутов",
+			[]NotificationRoute{route(ChannelWebhook, "https://hook", RouteAlerts), route(ChannelPublish, "-100999", RouteAll)},
+			alert, []string{"telegram:", "webhook:https://hook", "channel:-100999"}},
+		{"тема первой, затем email",
+			[]NotificationRoute{route(ChannelEmail, "a@example.com", RouteUrgent), route(ChannelTopic, "17", RouteAll)},
+			urgent, []string{"topic:17", "email:a@example.com"}},
+		{"рыночное уведомление не уходит в маршрут оповещений",
+			[]NotificationRoute{route(ChannelWebhook, "https://hook", RouteAlerts), route(ChannelEmail, "a@example.com", RouteMarket)},
+			market, []string{"telegram:", "email:a@example.com"}},
+		{"telegram только для срочных, email для оповещений",
+			[]NotificationRoute{route(ChannelTelegram, "", RouteUrgent), route(ChannelEmail, "a@example.com", RouteAlerts)},
+			alert, []string{"email:a@example.com"}},
+		{"telegram только для срочных, срочное - и в чат",
+			[]NotificationRoute{route(ChannelTelegram, "", RouteUrgent), route(ChannelEmail, "a@example.com", RouteAlerts)},
+			urgent, []string{"telegram:", "email:a@example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, r := range routeNotification(tt.routes, tt.n) {
+				if r.ChatID != chatID {
+					t.Errorf("маршрут %s для чата %d, want %d", r.Channel, r.ChatID, chatID)
+				}
+				got = append(got, r.Channel+":"+r.Target)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("routeNotification = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotificationRouteMatches(t *testing.T) {
+	notifications := map[string]Notification{
+		"alert":  {AlertID: 1},
+		"urgent": {AlertID: 1, Urgent: true},
+		"market": {},
+	}
+	want := map[string][]string{
+		RouteAll:    {"alert", "urgent", "market"},
+		RouteUrgent: {"urgent"},
+		RouteAlerts: {"alert", "urgent"},
+		RouteMarket: {"market"},
+		RouteOff:    nil,
+	}
+	for _, filter := range RouteFilters {
+		var got []string
+		for _, kind := range []string{"alert", "urgent", "market"} {
+			if (NotificationRoute{Filter: filter}).Matches(notifications[kind]) {
+				got = append(got, kind)
+			}
+		}
+		if !reflect.DeepEqual(got, want[filter]) {
+			t.Errorf("фильтр %s: подходят %q, want %q", filter, got, want[filter])
+		}
+	}
+}
+
+func TestNotificationRouteValidate(t *testing.T) {
+	tests := []struct {
+		route NotificationRoute
+		ok    bool
+	}{
+		{NotificationRoute{Channel: ChannelTopic, Target: "45"}, true},
+		{NotificationRoute{Channel: ChannelTopic, Target: "0"}, false},
+		{NotificationRoute{Channel: ChannelTopic, Target: "тема"}, false},
+		{NotificationRoute{Channel: ChannelPublish, Target: "-1001234567890"}, true},
+		{NotificationRoute{Channel: ChannelPublish, Target: "@channel"}, false},
+		{NotificationRoute{Channel: ChannelPublish, Target: ""}, false},
+		{NotificationRoute{Channel: ChannelTelegram}, true},
+		{NotificationRoute{Channel: ChannelEmail, Target: "user@example.com"}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.route.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%s %q: Validate() = %v, want ok=%v", tt.route.Channel, tt.route.Target, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// TradeTGBot/internal/repository/settings.go
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"TradeTGBot/internal/db"
+)
+
+// Настройки чата по умолчанию.
+const (
+	DefaultTimezone         = "Europe/Moscow" // Время работы MOEX
+	DefaultSummaryThreshold = 5               // Больше стольких уведомлений за раз сворачиваются в сводку
+)
+
+// ChatSettings represents per-chat delivery preferences
+type ChatSettings struct {
+	ChatID       int64
+	Timezone     string // IANA, например Europe/Moscow
+	QuietEnabled bool
+	QuietStart   int // Начало тихих часов, минуты от полуночи по Timezone
+	QuietEnd     int // Окончание тихих часов, минуты от полуночи по Timezone
+
+	AlertsAdminOnly   bool // В группе оповещения создают только администраторы
+	SettingsAdminOnly bool // В группе настройки чата меняют только администраторы
+
+	SummaryThreshold int // Порог, сверх которого уведомления за проход доставки сводятся в краткую сводку
+}
+
+// GetChatSettings возвращает настройки чата или настройки по умолчанию, если они не заданы.
+func GetChatSettings(chatID int64) (ChatSettings, error) {
+	s := ChatSettings{ChatID: chatID, Timezone: DefaultTimezone, SummaryThreshold: DefaultSummaryThreshold}
+	err := db.GlobalDB.QueryRow(`
+		SELECT timezone, quiet_enabled, quiet_start, quiet_end, alerts_admin_only, settings_admin_only,
+		       summary_threshold
+		FROM chat_settings WHERE chat_id = $1`, chatID).Scan(&s.Timezone, &s.QuietEnabled, &s.QuietStart, &s.QuietEnd,
+		&s.AlertsAdminOnly, &s.SettingsAdminOnly, &s.SummaryThreshold)
+	if errors.Is(err, sql.ErrNoRows) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("ошибка при получении настроек чата %d: %w", chatID, err)
+	}
+	return s, nil
+}
+
+// SaveChatSettings создаёт или обновляет настройки чата.
+func SaveChatSettings(s ChatSettings) error {
+	_, err := db.GlobalDB.Exec(`
+		INSERT INTO chat_settings (chat_id, timezone, quiet_enabled, quiet_start, quiet_end, alerts_admin_only, settings_admin_only,
+		                           summary_threshold)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (chat_id) DO UPDATE
+		SET timezone = EXCLUDED.timezone, quiet_enabled = EXCLUDED.quiet_enabled,
+		    quiet_start = EXCLUDED.quiet_start, quiet_end = EXCLUDED.quiet_end,
+		    alerts_admin_only = EXCLUDED.alerts_admin_only, settings_admin_only = EXCLUDED.settings_admin_only,
+		    summary_threshold = EXCLUDED.summary_threshold`,
+		s.ChatID, s.Timezone, s.QuietEnabled, s.QuietStart, s.QuietEnd, s.AlertsAdminOnly, s.SettingsAdminOnly,
+		s.SummaryThreshold)
+	if err != nil {
+		return fmt.Errorf("ошибка при сохранении настроек чата %d: %w", s.ChatID, err)
+	}
+	return nil
+}
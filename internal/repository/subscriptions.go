@@ -0,0 +1,75 @@
+// TradeTGBot/internal/repository/subscriptions.go
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"TradeTGBot/internal/db"
+)
+
+// SpikeSubscription represents a chat's subscription to sharp price change notifications
+type SpikeSubscription struct {
+	ID        int
+	ChatID    int64
+	Ticker    string
+	Detector  string  // Детектор аномалий: pct, zscore, ewma, atr
+	Threshold float64 // Порог детектора, начиная с которого приходит уведомление
+	CreatedAt time.Time
+}
+
+// SaveSpikeSubscription подписывает чат на резкие изменения цены тикера
+// или обновляет детектор и порог существующей подписки.
+func SaveSpikeSubscription(s SpikeSubscription) error {
+	_, err := db.GlobalDB.Exec(`
+		INSERT INTO spike_subscriptions (chat_id, ticker, threshold, detector)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (chat_id, ticker) DO UPDATE SET threshold = EXCLUDED.threshold, detector = EXCLUDED.detector`,
+		s.ChatID, s.Ticker, s.Threshold, s.Detector)
+	if err != nil {
+		return fmt.Errorf("ошибка при сохранении подписки чата %d на %s: %w", s.ChatID, s.Ticker, err)
+	}
+	return nil
+}
+
+// DeleteSpikeSubscriptions отписывает чат от тикера; пустой ticker удаляет все подписки чата.
+// Возвращает число удалённых подписок.
+func DeleteSpikeSubscriptions(chatID int64, ticker string) (int, error) {
+	res, err := db.GlobalDB.Exec(`
+		DELETE FROM spike_subscriptions
+		WHERE chat_id = $1 AND ($2 = '' OR ticker = $2)`, chatID, ticker)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при удалении подписок чата %d: %w", chatID, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при удалении подписок чата %d: %w", chatID, err)
+	}
+	return int(n), nil
+}
+
+// GetSpikeSubscriptions возвращает подписки чата; если chatID равен 0 - подписки всех чатов.
+func GetSpikeSubscriptions(chatID int64) ([]SpikeSubscription, error) {
+	rows, err := db.GlobalDB.Query(`
+		SELECT id, chat_id, ticker, threshold, detector, created_at
+		FROM spike_subscriptions
+		WHERE $1 = 0 OR chat_id = $1
+		ORDER BY ticker, chat_id`, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении подписок: %w", err)
+	}
+	defer rows.Close()
+
+	var subs []SpikeSubscription
+	for rows.Next() {
+		var s SpikeSubscription
+		if err := rows.Scan(&s.ID, &s.ChatID, &s.Ticker, &s.Threshold, &s.Detector, &s.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка при чтении подписки: %w", err)
+		}
+		subs = append(subs, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении подписок: %w", err)
+	}
+	return subs, nil
+}
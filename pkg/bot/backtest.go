@@ -0,0 +1,337 @@
+// TradeTGBot/pkg/bot/backtest.go
+package bot
+
+import (
+	"TradeTGBot/internal/alertexpr"
+	"TradeTGBot/internal/analyzer"
+	"TradeTGBot/internal/backtest"
+	"TradeTGBot/internal/config"
+	"TradeTGBot/internal/indicators"
+	"TradeTGBot/internal/repository"
+	"TradeTGBot/pkg/stocks"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Параметры /backtest.
+const (
+	defaultBacktestPeriod = 30 * 24 * time.Hour
+	maxBacktestPeriod     = 180 * 24 * time.Hour
+	backtestRows          = 10 // Сколько последних срабатываний показывать в чате
+	maxBacktests          = 2  // Сколько бэктестов всех чатов может выполняться одновременно
+)
+
+const backtestUsage = "Формат: /backtest [ПЕРИОД] ПРАВИЛО (период по умолчанию 30d)\n" +
+	"Правила - как при создании оповещений: SBER 320, SBER trailing 3%, SBER outside 300-320, " +
+	"SBER > 320 AND GAZP < 150, indicator SBER rsi 14 30 70 15m\n" +
+	"Детектор резких изменений: /backtest 30d spike SBER [ПОРОГ] [pct|zscore|ewma|atr]"
+
+// handleBacktest обрабатывает /backtest: прогоняет правило оповещения или детектор
+// анализатора по сохранённой истории и показывает, когда и сколько раз он сработал бы.
+// Прогон длинного периода занимает время, поэтому выполняется в отдельной горутине,
+// чтобы не задерживать обработку остальных обновлений; в каждом чате - не больше
+// одного бэктеста одновременно.
+func (bs *BotService) handleBacktest(chatID int64, args []string) {
+	period := defaultBacktestPeriod
+	if len(args) > 1 {
+		if d, err := backtest.ParseDuration(args[0]); err == nil {
+			if d > maxBacktestPeriod {
+				bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Период не больше %s.", backtest.FormatDuration(maxBacktestPeriod))))
+				return
+			}
+			period, args = d, args[1:]
+		}
+	}
+	if len(args) == 0 {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, backtestUsage))
+		return
+	}
+
+	bs.backtestMu.Lock()
+	busy, full := bs.backtests[chatID], len(bs.backtests) >= maxBacktests
+	if !busy && !full {
+		bs.backtests[chatID] = true
+	}
+	bs.backtestMu.Unlock()
+	switch {
+	case busy:
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Бэктест в этом чате уже выполняется, дождитесь результата."))
+		return
+	case full:
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Сейчас выполняется слишком много бэктестов, попробуйте через минуту."))
+		return
+	}
+
+	bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Бэктест за %s запущен, результат придёт отдельным сообщением.",
+		backtest.FormatDuration(period))))
+	go func() {
+		defer func() {
+			bs.backtestMu.Lock()
+			delete(bs.backtests, chatID)
+			bs.backtestMu.Unlock()
+		}()
+		bs.runBacktest(chatID, period, args)
+	}()
+}
+
+// runBacktest выполняет бэктест для /backtest и отправляет отчёт или ошибку в чат.
+func (bs *BotService) runBacktest(chatID int64, period time.Duration, args []string) {
+	to := time.Now()
+	from := to.Add(-period)
+	var result backtest.Result
+	var err error
+	if strings.EqualFold(args[0], "spike") {
+		var settings config.AnalyzerTicker
+		if settings, err = ParseSpikeRule(args[1:]); err == nil {
+			result, err = analyzer.Backtest(settings, from, to, backtest.DefaultHorizons)
+		}
+	} else {
+		result, err = BacktestRule(strings.Join(args, " "), from, to, backtest.DefaultHorizons)
+	}
+	if err != nil {
+		log.Printf("Ошибка бэктеста %q: %v", strings.Join(args, " "), err)
+		bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Ошибка: %v.\n%s", err, backtestUsage)))
+		return
+	}
+
+	settings, err := repository.GetChatSettings(chatID)
+	if err != nil {
+		log.Printf("Ошибка получения настроек чата: %v", err)
+	}
+	loc, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		loc = time.Local
+	}
+	bs.bot.Send(tgbotapi.NewMessage(chatID, result.Report(loc, backtestRows)))
+}
+
+// ParseSpikeRule разбирает параметры детектора резких изменений: ТИКЕР [ПОРОГ] [ДЕТЕКТОР],
+// как в /subscribe. Окно и период опроса - по умолчанию для подписок.
+func ParseSpikeRule(args []string) (config.AnalyzerTicker, error) {
+	if len(args) == 0 || len(args) > 3 {
+		return config.AnalyzerTicker{}, fmt.Errorf("укажите тикер, порог и детектор")
+	}
+	ticker := strings.ToUpper(args[0])
+	if _, ok := stocks.Stocks[ticker]; !ok {
+		return config.AnalyzerTicker{}, fmt.Errorf("тикер %s не найден в базе", ticker)
+	}
+	settings := config.NewAnalyzerTicker(ticker)
+	threshold := 0.0
+	for _, arg := range args[1:] {
+		if _, ok := config.DetectorThresholds[strings.ToLower(arg)]; ok {
+			settings.Detector = strings.ToLower(arg)
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSuffix(arg, "%"), ",", "."), 64)
+		if err != nil || v <= 0 {
+			return config.AnalyzerTicker{}, fmt.Errorf("неверный порог %q", arg)
+		}
+		threshold = v
+	}
+	settings.Threshold = config.DetectorThresholds[settings.Detector]
+	if threshold > 0 {
+		settings.Threshold = threshold
+	}
+	return settings, nil
+}
+
+// parseBacktestRule разбирает правило оповещения в том же виде, в каком оно
+// создаётся в чате: "SBER 320", "SBER trailing 3%", "SBER outside 300-320",
+// составное условие или "indicator SBER rsi 14 15m". Спреды не поддерживаются:
+// их статистика считается от текущего момента.
+func parseBacktestRule(text string) (Alert, error) {
+	tokens := strings.Fields(text)
+	if len(tokens) == 0 {
+		return Alert{}, fmt.Errorf("пустое правило")
+	}
+	knownTicker := func(ticker string) bool {
+		_, ok := stocks.Stocks[ticker]
+		return ok
+	}
+	if strings.EqualFold(tokens[0], "indicator") {
+		ticker, spec, err := parseIndicatorSpec(tokens[1:])
+		if err != nil {
+			return Alert{}, err
+		}
+		if !knownTicker(ticker) {
+			return Alert{}, fmt.Errorf("тикер %s не найден в базе", ticker)
+		}
+		return Alert{Ticker: ticker, Indicator: &spec}, nil
+	}
+
+	ticker := strings.ToUpper(tokens[0])
+	switch {
+	case len(tokens) == 2:
+		target, err := strconv.ParseFloat(strings.ReplaceAll(tokens[1], ",", "."), 64)
+		if err != nil || target <= 0 {
+			return Alert{}, fmt.Errorf("неверная цена %q", tokens[1])
+		}
+		if !knownTicker(ticker) {
+			return Alert{}, fmt.Errorf("тикер %s не найден в базе", ticker)
+		}
+		return Alert{Ticker: ticker, Target: target}, nil // Направление - по первой цене периода
+	case len(tokens) == 3 && strings.EqualFold(tokens[1], "trailing"):
+		spec, err := parseTrailingSpec(tokens[2])
+		if err != nil {
+			return Alert{}, err
+		}
+		if !knownTicker(ticker) {
+			return Alert{}, fmt.Errorf("тикер %s не найден в базе", ticker)
+		}
+		return Alert{Ticker: ticker, Trailing: &spec}, nil
+	case len(tokens) >= 3 && (strings.EqualFold(tokens[1], rangeOutside) || strings.EqualFold(tokens[1], rangeInside)):
+		spec, err := parseRangeSpec(tokens[1:])
+		if err != nil {
+			return Alert{}, err
+		}
+		if !knownTicker(ticker) {
+			return Alert{}, fmt.Errorf("тикер %s не найден в базе", ticker)
+		}
+		return Alert{Ticker: ticker, Range: &spec}, nil
+	}
+	expr, err := alertexpr.Parse(text, knownTicker)
+	if err != nil {
+		return Alert{}, err
+	}
+	return Alert{Condition: expr}, nil
+}
+
+// backtestTick - тик одного из тикеров правила при прогоне истории.
+type backtestTick struct {
+	Ticker string
+	indicators.Point
+}
+
+// BacktestRule прогоняет правило оповещения (см. parseBacktestRule) по минутным
+// свечам за [from, to) через те же проверки, что и движок оповещений. Каждая свеча
+// воспроизводится как путь цены через открытие, экстремумы и закрытие (см. candlePath),
+// поэтому уровни, пробитые только тенью свечи, тоже срабатывают. В отличие от
+// живого оповещения, правило не удаляется после срабатывания: следующее срабатывание
+// засчитывается, когда условие перестанет и снова начнёт выполняться. Доходность
+// после сигнала считается по первому тикеру правила.
+func BacktestRule(rule string, from, to time.Time, horizons []time.Duration) (backtest.Result, error) {
+	alert, err := parseBacktestRule(rule)
+	if err != nil {
+		return backtest.Result{}, err
+	}
+	tickers := alert.indexTickers()
+	primary := tickers[0]
+
+	// История до from нужна индикаторам, после to - только для доходности.
+	var warmup time.Duration
+	if alert.Indicator != nil {
+		warmup = timeframes[alert.Indicator.Timeframe] * time.Duration(alert.Indicator.bars()+2)
+	}
+	var maxHorizon time.Duration
+	for _, h := range horizons {
+		maxHorizon = max(maxHorizon, h)
+	}
+	var ticks []backtestTick
+	var primaryTicks []indicators.Point
+	result := backtest.Result{Ticker: primary, From: from, To: to, Horizons: horizons}
+	for _, ticker := range tickers {
+		candles, err := repository.GetCandles(ticker, time.Minute, from.Add(-warmup), to.Add(maxHorizon))
+		if err != nil {
+			return backtest.Result{}, err
+		}
+		for _, c := range candles {
+			if ticker == primary && !c.Time.Before(from) && c.Time.Before(to) {
+				result.Bars++
+			}
+			for _, point := range candlePath(c, time.Minute) {
+				ticks = append(ticks, backtestTick{Ticker: ticker, Point: point})
+				if ticker == primary {
+					primaryTicks = append(primaryTicks, point)
+				}
+			}
+		}
+	}
+	sort.SliceStable(ticks, func(i, j int) bool { return ticks[i].Time.Before(ticks[j].Time) })
+
+	quotes := make(quoteSnapshot, len(tickers))
+	var bars []indicators.Point // Бары индикатора: последний - текущий, с ценой последнего тика
+	armed := false              // Условие не выполнялось с прошлого срабатывания
+	for _, tick := range ticks {
+		if !tick.Time.Before(to) {
+			break
+		}
+		quotes[tick.Ticker] = stocks.StockData{Name: stocks.Stocks[tick.Ticker].Name, Price: tick.Value}
+		if alert.Indicator != nil && tick.Ticker == primary {
+			bars = appendBar(bars, tick.Point, timeframes[alert.Indicator.Timeframe], alert.Indicator.bars()+2)
+		}
+		if tick.Time.Before(from) || len(quotes) < len(tickers) {
+			continue
+		}
+		result.Ticks++
+		price := quotes[primary].Price
+		// Направление зависит от цены при создании оповещения - берём первую цену периода.
+		// Сторону подхода к коридору checkRangeAlert определяет сам.
+		if alert.Direction == "" && alert.kind() == alertKindPrice {
+			alert.Direction = "up"
+			if price > alert.Target {
+				alert.Direction = "down"
+			}
+		}
+
+		var fired bool
+		if alert.Indicator != nil {
+			closes := make([]float64, len(bars))
+			for i, bar := range bars {
+				closes[i] = bar.Value
+			}
+			_, fired = indicatorSignal(&alert, quotes[primary].Name, closes)
+		} else {
+			_, fired = alert.check(quotes)
+		}
+		// Индикатор срабатывает только на смене состояния, поэтому засчитывается всегда.
+		if fired && (armed || alert.Indicator != nil) {
+			result.Triggers = append(result.Triggers, backtest.Trigger{Time: tick.Time, Price: price})
+		}
+		if fired && alert.Trailing != nil {
+			alert.Peak = price // Перевзводим трейлинг от цены срабатывания
+		}
+		armed = !fired
+	}
+	result.Rule = alert.describe()
+	result.ForwardReturns(primaryTicks)
+	return result, nil
+}
+
+// candlePath воспроизводит свечу длительностью bar как последовательность цен внутри
+// неё: открытие, ближний к открытию экстремум, дальний экстремум, закрытие. Для
+// растущей свечи сначала минимум, затем максимум, для падающей - наоборот.
+// Одинаковые соседние цены не повторяются.
+func candlePath(c indicators.Candle, bar time.Duration) []indicators.Point {
+	prices := []float64{c.Open, c.High, c.Low, c.Close}
+	if c.Close >= c.Open {
+		prices[1], prices[2] = c.Low, c.High
+	}
+	path := make([]indicators.Point, 0, len(prices))
+	for i, price := range prices {
+		if n := len(path); n > 0 && path[n-1].Value == price {
+			continue
+		}
+		path = append(path, indicators.Point{Time: c.Time.Add(bar * time.Duration(i) / time.Duration(len(prices))), Value: price})
+	}
+	return path
+}
+
+// appendBar добавляет тик к барам таймфрейма tf: обновляет закрытие текущего бара
+// или открывает новый. Хранится не больше limit последних баров.
+func appendBar(bars []indicators.Point, p indicators.Point, tf time.Duration, limit int) []indicators.Point {
+	start := p.Time.Truncate(tf)
+	if n := len(bars); n > 0 && bars[n-1].Time.Equal(start) {
+		bars[n-1].Value = p.Value
+		return bars
+	}
+	bars = append(bars, indicators.Point{Time: start, Value: p.Value})
+	if len(bars) > limit {
+		bars = bars[len(bars)-limit:]
+	}
+	return bars
+}
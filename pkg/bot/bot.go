@@ -2,38 +2,65 @@
 package bot
 
 import (
+	"TradeTGBot/internal/alertexpr"
+	"TradeTGBot/internal/notify"
+	"TradeTGBot/internal/pricebus"
+	"TradeTGBot/internal/repository"
 	"TradeTGBot/pkg/stocks"
 	"fmt"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"html"
 	"log"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
-
-	"github.com/gocolly/colly" // Импортируем colly, так как InitCollector возвращает *colly.Collector
 )
 
-// Alert - структура для оповещения (пока в памяти).
-// В будущем это может быть строка в БД, и мы будем использовать repository.Alert
+// Alert - структура для оповещения. Активные оповещения хранятся в памяти
+// в alertEngine и дублируются в таблице alerts (см. storage.go), чтобы переживать перезапуск.
 type Alert struct {
+	ID        int
 	Ticker    string
 	Target    float64
 	ChatID    int64
 	Direction string
+	Condition *alertexpr.Expr // Составное условие; если задано, Ticker/Target/Direction не используются
+	Indicator *IndicatorSpec  // Индикаторное оповещение по Ticker; Target/Direction не используются
+	Trailing  *TrailingSpec   // Трейлинг-стоп по Ticker; Target/Direction не используются
+	Range     *RangeSpec      // Коридор по Ticker; Direction - сторона подхода для режима inside
+	Spread    *SpreadSpec     // Спред между Ticker и Spread.Second
+	Peak      float64         // Максимальная цена с момента создания (для трейлинга)
+	CreatedAt time.Time
+
+	SnoozedUntil time.Time // До этого момента оповещение не проверяется (кнопка «Отложить»)
+	Urgent       bool      // Уведомление доставляется и в тихие часы
+	Owner        Owner     // Кто создал оповещение; в группах ему адресуется уведомление
+
+	indicatorState int  // Последнее наблюдавшееся состояние индикатора
+	stateKnown     bool // Было ли состояние индикатора уже вычислено
+	dataWarned     bool // Предупреждён ли чат, что истории для индикатора недостаточно
 }
 
-// bot.go будет управлять локальным списком алертов,
-// если они не хранятся в БД. Если будут в БД, то эту переменную убрать.
-var userAlerts []Alert // Переименовал, чтобы не конфликтовать с repository.Alert
+const (
+	alertCheckInterval = 30 * time.Second // Период опроса котировок тикеров с активными оповещениями
+	alertTickBuffer    = 256              // Буфер подписки движка оповещений на тики
+	webhookTimeout     = 10 * time.Second // Ограничение времени на запрос к вебхуку
+)
 
 // BotService инкапсулирует логику бота и зависимости.
 type BotService struct {
 	bot       *tgbotapi.BotAPI
-	collector *colly.Collector // Нужно передать, чтобы бот мог запрашивать данные
+	poller    *pricebus.Poller           // Общий источник котировок; тики раздаются через его шину
+	alerts    *alertEngine               // Активные оповещения, проиндексированные по тикерам
+	notifiers map[string]notify.Notifier // Каналы доставки уведомлений по имени канала
+
+	backtestMu sync.Mutex
+	backtests  map[int64]bool // Чаты, в которых сейчас выполняется /backtest
 }
 
 // NewBotService создает новый экземпляр BotService.
-func NewBotService(token string, collector *colly.Collector) (*BotService, error) {
+func NewBotService(token string, poller *pricebus.Poller) (*BotService, error) {
 	botAPI, err := tgbotapi.NewBotAPI(token)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка инициализации Telegram API: %w", err)
@@ -41,10 +68,24 @@ func NewBotService(token string, collector *colly.Collector) (*BotService, error
 	botAPI.Debug = false // Рекомендуется установить false для продакшена
 	log.Printf("Авторизован бот %s", botAPI.Self.UserName)
 
-	return &BotService{
-		bot:       botAPI,
-		collector: collector,
-	}, nil
+	bs := &BotService{
+		bot:    botAPI,
+		poller: poller,
+		notifiers: map[string]notify.Notifier{
+			repository.ChannelTelegram: notify.NewTelegram(botAPI),
+			repository.ChannelTopic:    notify.NewTelegram(botAPI),
+			repository.ChannelPublish:  notify.NewTelegram(botAPI),
+			repository.ChannelWebhook:  notify.NewWebhook(webhookTimeout),
+		},
+		backtests: make(map[int64]bool),
+	}
+	bs.alerts = newAlertEngine(bs.triggerAlert)
+	return bs, nil
+}
+
+// SetNotifier подключает канал доставки уведомлений (например, email при наличии настроек SMTP).
+func (bs *BotService) SetNotifier(channel string, n notify.Notifier) {
+	bs.notifiers[channel] = n
 }
 
 // StartPolling начинает опрос Telegram API на наличие новых обновлений.
@@ -53,11 +94,19 @@ func (bs *BotService) StartPolling() {
 	u.Timeout = 60
 	updates := bs.bot.GetUpdatesChan(u)
 
-	// Горутина для проверки пользовательских оповещений
-	go bs.checkUserAlerts()
+	// Восстанавливаем сохранённые оповещения и запускаем горутину их проверки
+	bs.loadAlerts()
+	// Движок оповещений получает тики из шины, поллер опрашивает тикеры с активными оповещениями.
+	go bs.alerts.Consume(bs.poller.Bus().Subscribe("alerts", alertTickBuffer, pricebus.DropOldest))
+	bs.poller.Watch(alertCheckInterval, bs.alerts.Tickers)
+	go bs.deliverOutbox()
 
 	// Основной цикл обработки обновлений от Telegram API
 	for update := range updates {
+		if update.CallbackQuery != nil {
+			bs.handleCallback(update.CallbackQuery)
+			continue
+		}
 		if update.Message == nil {
 			continue
 		}
@@ -68,7 +117,9 @@ func (bs *BotService) StartPolling() {
 // handleMessage обрабатывает входящие сообщения.
 func (bs *BotService) handleMessage(message *tgbotapi.Message) {
 	if message.IsCommand() {
-		bs.handleCommand(message)
+		if bs.addressedCommand(message) {
+			bs.handleCommand(message)
+		}
 	} else {
 		bs.handleText(message)
 	}
@@ -76,12 +127,40 @@ func (bs *BotService) handleMessage(message *tgbotapi.Message) {
 
 // handleCommand обрабатывает команды бота.
 func (bs *BotService) handleCommand(message *tgbotapi.Message) {
-	switch message.Command() {
+	command := message.Command()
+	if perm, ok := commandPermissions[command]; ok {
+		// Без аргументов команды настроек только показывают их, а /spread без условия -
+		// текущий спред; это доступно всем.
+		args := len(strings.Fields(message.CommandArguments()))
+		viewOnly := (perm == permSettings && args == 0) || (command == "spread" && args <= 2)
+		if !viewOnly && !bs.authorize(message, perm) {
+			return
+		}
+	}
+
+	switch command {
 	case "start":
 		msg := tgbotapi.NewMessage(message.Chat.ID,
 			"Привет! Введите тикер акции (например, LKOH или AEROFLOT) для запроса цены.\n"+
 				"Чтобы установить оповещение, отправьте сообщение в формате: ТИКЕР ЦЕНА\n"+
 				"Например: LKOH 7100.0\n"+
+				"Составное условие: /alert SBER > 320 AND GAZP < 150 или /alert LKOH / SIBN < 1.2\n"+
+				"Индикаторы: /indicator LKOH sma 20 5m, /indicator LKOH cross ema 9 21 1h,\n"+
+				"/indicator LKOH rsi 14 30 70 15m, /indicator LKOH bb 20 2 5m\n"+
+				"Трейлинг-стоп: LKOH trailing 3% или LKOH trailing 150\n"+
+				"Коридор: SBER outside 300-320 (выход) или SBER inside 300-320 (вход)\n"+
+				"Спред: /spread SBER SBERP, оповещение: /spread SBER SBERP ratio > 1.2\n"+
+				"Список ваших оповещений: /alerts, история срабатываний: /history [ТИКЕР] [СТРАНИЦА]\n"+
+				"Резкие изменения цены: /subscribe SBER GAZP [ПОРОГ] [pct|zscore|ewma|atr], /unsubscribe SBER\n"+
+				"Гэпы открытия: /gaps, отчёт после открытия: /gaps report, гэпы тикеров: /gaps SBER 2%\n"+
+				"График цены: /chart SBER 1w sma20\n"+
+				"Технический анализ: /ta SBER [1h]\n"+
+				"Проверка правила на истории: /backtest 30d SBER > 320, /backtest spike SBER 3 zscore\n"+
+				"Дайджест по рынку за день и неделю: /digest\n"+
+				"Тихие часы: /quiet 23:00-08:00, срочное оповещение: /urgent ID\n"+
+				"Порог краткой сводки при нескольких срабатываниях: /summary N\n"+
+				"Дублировать уведомления на email или вебхук: /notify\n"+
+				"В группе: обращайтесь к боту через @имя_бота, права участников - /group\n"+
 				"Чтобы получить список доступных тикеров, нажмите кнопку /list")
 		bs.bot.Send(msg)
 	case "list":
@@ -93,6 +172,44 @@ func (bs *BotService) handleCommand(message *tgbotapi.Message) {
 		msg := tgbotapi.NewMessage(message.Chat.ID, sb.String())
 		msg.ParseMode = "HTML"
 		bs.bot.Send(msg)
+	case "alert":
+		bs.addConditionAlert(message.Chat.ID, ownerOf(message.From), message.CommandArguments())
+	case "indicator":
+		bs.addIndicatorAlert(message.Chat.ID, ownerOf(message.From), message.CommandArguments())
+	case "trailing":
+		bs.addTrailingAlert(message.Chat.ID, ownerOf(message.From), strings.Fields(message.CommandArguments()))
+	case "range":
+		bs.addRangeAlert(message.Chat.ID, ownerOf(message.From), strings.Fields(message.CommandArguments()))
+	case "spread":
+		bs.handleSpread(message.Chat.ID, ownerOf(message.From), strings.Fields(message.CommandArguments()))
+	case "alerts":
+		bs.listAlerts(message.Chat.ID)
+	case "notify":
+		bs.handleNotify(message.Chat.ID, message.From, strings.Fields(message.CommandArguments()))
+	case "subscribe":
+		bs.handleSubscribe(message.Chat.ID, strings.Fields(message.CommandArguments()))
+	case "unsubscribe":
+		bs.handleUnsubscribe(message.Chat.ID, strings.Fields(message.CommandArguments()))
+	case "gaps":
+		bs.handleGaps(message.Chat.ID, strings.Fields(message.CommandArguments()))
+	case "chart":
+		bs.handleChart(message.Chat.ID, strings.Fields(message.CommandArguments()))
+	case "ta":
+		bs.handleTA(message.Chat.ID, strings.Fields(message.CommandArguments()))
+	case "backtest":
+		bs.handleBacktest(message.Chat.ID, strings.Fields(message.CommandArguments()))
+	case "digest":
+		bs.handleDigest(message.Chat.ID, strings.Fields(message.CommandArguments()))
+	case "summary":
+		bs.handleSummary(message.Chat.ID, strings.Fields(message.CommandArguments()))
+	case "quiet":
+		bs.handleQuiet(message.Chat.ID, strings.Fields(message.CommandArguments()))
+	case "urgent":
+		bs.handleUrgent(message.Chat.ID, strings.Fields(message.CommandArguments()))
+	case "group":
+		bs.handleGroup(message, strings.Fields(message.CommandArguments()))
+	case "history":
+		bs.showHistory(message.Chat.ID, strings.Fields(message.CommandArguments()))
 	default:
 		bs.bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Неизвестная команда."))
 	}
@@ -100,7 +217,18 @@ func (bs *BotService) handleCommand(message *tgbotapi.Message) {
 
 // handleText обрабатывает текстовые сообщения (запросы цен или установки алертов).
 func (bs *BotService) handleText(message *tgbotapi.Message) {
-	tokens := strings.Fields(message.Text)
+	if bs.handleNewLevelReply(message) { // Ответ на запрос нового уровня оповещения
+		return
+	}
+	text, ok := bs.addressedText(message)
+	if !ok {
+		return
+	}
+	tokens := strings.Fields(text)
+	if len(tokens) >= 2 && !bs.authorize(message, permAlerts) { // Всё, кроме запроса цены, создаёт оповещение
+		return
+	}
+	owner := ownerOf(message.From)
 
 	if len(tokens) == 2 { // Установка оповещения (ТИКЕР ЦЕНА)
 		ticker := strings.ToUpper(tokens[0])
@@ -109,12 +237,11 @@ func (bs *BotService) handleText(message *tgbotapi.Message) {
 			bs.bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Неверный формат цены. Попробуйте еще раз."))
 			return
 		}
-		info, ok := stocks.Stocks[ticker]
-		if !ok {
+		if _, ok := stocks.Stocks[ticker]; !ok {
 			bs.bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Тикер %s не найден в базе.", ticker)))
 			return
 		}
-		stock, err := stocks.FetchStockData(info.URL, bs.collector) // Используем collector из BotService
+		stock, err := bs.quotes().Get(ticker)
 		if err != nil {
 			bs.bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Ошибка получения данных для %s: %v", ticker, err)))
 			return
@@ -129,35 +256,47 @@ func (bs *BotService) handleText(message *tgbotapi.Message) {
 			return
 		}
 
-		// Добавляем алерт в локальный список (для текущей реализации)
-		userAlerts = append(userAlerts, Alert{
+		_, err = bs.saveAlert(Alert{
 			Ticker:    ticker,
 			Target:    target,
 			ChatID:    message.Chat.ID,
 			Direction: direction,
+			Owner:     owner,
 		})
-		// Если бы алерты были в БД, тут мы бы вызывали repository.SaveAlert
-		// err = repository.SaveAlert(repository.Alert{Ticker: ticker, Target: target, ChatID: message.Chat.ID, Direction: direction})
-		// if err != nil {
-		//    log.Printf("Ошибка сохранения алерта в БД: %v", err)
-		//    bs.bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Ошибка при сохранении оповещения."))
-		//    return
-		// }
+		if err != nil {
+			log.Printf("Ошибка сохранения алерта в БД: %v", err)
+			bs.bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Ошибка при сохранении оповещения."))
+			return
+		}
 
 		msgText := fmt.Sprintf("Оповещение установлено для %s: когда цена достигнет %.2f, вы получите уведомление.", stock.Name, target)
 		bs.bot.Send(tgbotapi.NewMessage(message.Chat.ID, msgText))
 		return
 	}
 
+	if len(tokens) == 3 && strings.EqualFold(tokens[1], "trailing") { // Трейлинг-стоп (ТИКЕР trailing 3%)
+		bs.addTrailingAlert(message.Chat.ID, owner, []string{tokens[0], tokens[2]})
+		return
+	}
+
+	if len(tokens) >= 3 && (strings.EqualFold(tokens[1], rangeOutside) || strings.EqualFold(tokens[1], rangeInside)) { // Коридор (ТИКЕР outside 300-320)
+		bs.addRangeAlert(message.Chat.ID, owner, tokens)
+		return
+	}
+
+	if len(tokens) > 2 { // Составное условие (SBER > 320 AND GAZP < 150)
+		bs.addConditionAlert(message.Chat.ID, owner, text)
+		return
+	}
+
 	if len(tokens) == 1 { // Запрос цены по тикеру
-		ticker := strings.ToUpper(strings.TrimSpace(message.Text))
-		info, ok := stocks.Stocks[ticker]
-		if !ok {
+		ticker := strings.ToUpper(strings.TrimSpace(text))
+		if _, ok := stocks.Stocks[ticker]; !ok {
 			bs.bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Тикер %s не найден в базе.", ticker)))
 			return
 		}
 
-		stock, err := stocks.FetchStockData(info.URL, bs.collector) // Используем collector из BotService
+		stock, err := bs.quotes().Get(ticker)
 		if err != nil {
 			bs.bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Ошибка получения данных для %s: %v", ticker, err)))
 			return
@@ -171,49 +310,197 @@ func (bs *BotService) handleText(message *tgbotapi.Message) {
 	bs.bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Неизвестный формат сообщения. Попробуйте ввести тикер или 'ТИКЕР ЦЕНА'."))
 }
 
-// checkUserAlerts проверяет пользовательские оповещения.
-func (bs *BotService) checkUserAlerts() {
-	for {
-		// Если алерты хранятся в БД, мы бы получили их здесь через repository.GetActiveAlerts()
-		// alertsFromDB, err := repository.GetActiveAlerts()
-		// if err != nil {
-		//    log.Printf("Ошибка получения алертов из БД: %v", err)
-		//    time.Sleep(30 * time.Second) // Задержка перед следующей попыткой
-		//    continue
-		// }
-		// for _, alert := range alertsFromDB { ... }
-
-		var remaining []Alert
-		for _, alert := range userAlerts { // Пока используем локальный список
-			stockInfo, ok := stocks.Stocks[alert.Ticker]
-			if !ok {
-				continue
-			}
-			stock, err := stocks.FetchStockData(stockInfo.URL, bs.collector) // Используем collector из BotService
-			if err != nil {
-				log.Printf("Ошибка проверки пользовательского оповещения для %s: %v", alert.Ticker, err)
-				remaining = append(remaining, alert)
-				continue
-			}
-			trigger := false
-			if alert.Direction == "up" && stock.Price >= alert.Target {
-				trigger = true
-			} else if alert.Direction == "down" && stock.Price <= alert.Target {
-				trigger = true
-			}
-			if trigger {
-				msgText := fmt.Sprintf("🔔 Оповещение сработало для %s: цена достигла %.2f (текущее значение: %.2f)", stock.Name, alert.Target, stock.Price)
-				bs.bot.Send(tgbotapi.NewMessage(alert.ChatID, msgText))
-				// Если бы алерты хранились в БД, мы бы удаляли сработавший алерт из БД через repository.DeleteAlert
-				// err = repository.DeleteAlert(repository.Alert{ID: alert.ID}) // Предполагая, что Alert имеет ID
-				// if err != nil {
-				//    log.Printf("Ошибка удаления алерта из БД: %v", err)
-				// }
-			} else {
-				remaining = append(remaining, alert)
-			}
-		}
-		userAlerts = remaining // Обновляем список алертов в памяти
-		time.Sleep(30 * time.Second)
+// addConditionAlert разбирает составное условие и добавляет оповещение по нему.
+func (bs *BotService) addConditionAlert(chatID int64, owner Owner, text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Укажите условие, например: /alert SBER > 320 AND GAZP < 150"))
+		return
+	}
+
+	expr, err := alertexpr.Parse(text, func(ticker string) bool {
+		_, ok := stocks.Stocks[ticker]
+		return ok
+	})
+	if err != nil {
+		msgText := fmt.Sprintf("Ошибка в условии: %v", err)
+		if exprErr, ok := err.(*alertexpr.Error); ok {
+			msgText += "\n<pre>" + html.EscapeString(exprErr.Pointer(text)) + "</pre>"
+		}
+		msg := tgbotapi.NewMessage(chatID, msgText)
+		msg.ParseMode = "HTML"
+		bs.bot.Send(msg)
+		return
+	}
+
+	_, err = bs.saveAlert(Alert{
+		ChatID:    chatID,
+		Condition: expr,
+		Owner:     owner,
+	})
+	if err != nil {
+		log.Printf("Ошибка сохранения алерта в БД: %v", err)
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при сохранении оповещения."))
+		return
+	}
+
+	bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Оповещение установлено: когда выполнится условие «%s», вы получите уведомление.", expr)))
+}
+
+// addIndicatorAlert разбирает аргументы /indicator и добавляет индикаторное оповещение.
+func (bs *BotService) addIndicatorAlert(chatID int64, owner Owner, args string) {
+	ticker, spec, err := parseIndicatorSpec(strings.Fields(args))
+	if err != nil {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Ошибка: %v.\nФормат: /indicator ТИКЕР sma|ema N [ТФ], "+
+			"/indicator ТИКЕР cross sma|ema БЫСТРАЯ МЕДЛЕННАЯ [ТФ], /indicator ТИКЕР rsi N [НИЗ ВЕРХ] [ТФ], "+
+			"/indicator ТИКЕР bb N [K] [ТФ]. Таймфреймы: 1m, 5m, 15m, 1h, 4h, 1d.", err)))
+		return
+	}
+	info, ok := stocks.Stocks[ticker]
+	if !ok {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Тикер %s не найден в базе.", ticker)))
+		return
+	}
+
+	_, err = bs.saveAlert(Alert{
+		Ticker:    ticker,
+		ChatID:    chatID,
+		Indicator: &spec,
+		Owner:     owner,
+	})
+	if err != nil {
+		log.Printf("Ошибка сохранения алерта в БД: %v", err)
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при сохранении оповещения."))
+		return
+	}
+
+	bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Индикаторное оповещение установлено для %s: %s. "+
+		"Для расчёта используется сохранённая история цен, первые сигналы появятся после накопления %d интервалов.",
+		info.Name, spec, spec.bars())))
+}
+
+// addTrailingAlert добавляет трейлинг-стоп: args - тикер и отступ ("3%" или "150").
+func (bs *BotService) addTrailingAlert(chatID int64, owner Owner, args []string) {
+	if len(args) != 2 {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Формат: /trailing ТИКЕР 3% или /trailing ТИКЕР 150"))
+		return
+	}
+	ticker := strings.ToUpper(args[0])
+	spec, err := parseTrailingSpec(args[1])
+	if err != nil {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Ошибка: %v.", err)))
+		return
+	}
+	if _, ok := stocks.Stocks[ticker]; !ok {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Тикер %s не найден в базе.", ticker)))
+		return
+	}
+	stock, err := bs.quotes().Get(ticker)
+	if err != nil {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Ошибка получения данных для %s: %v", ticker, err)))
+		return
+	}
+
+	_, err = bs.saveAlert(Alert{
+		Ticker:   ticker,
+		ChatID:   chatID,
+		Trailing: &spec,
+		Owner:    owner,
+		Peak:     stock.Price, // Отсчёт пика начинается с текущей цены
+	})
+	if err != nil {
+		log.Printf("Ошибка сохранения алерта в БД: %v", err)
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при сохранении оповещения."))
+		return
+	}
+
+	bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Трейлинг-стоп %s установлен для %s: текущий пик %.2f, уровень стопа %.2f.",
+		spec, stock.Name, stock.Price, spec.StopLevel(stock.Price))))
+}
+
+// listAlerts отправляет список активных оповещений чата.
+func (bs *BotService) listAlerts(chatID int64) {
+	var sb strings.Builder
+	for _, alert := range bs.alerts.ForChat(chatID) {
+		sb.WriteString(fmt.Sprintf("#%d %s", alert.ID, alert.describe()))
+		if alert.Urgent {
+			sb.WriteString(" ❗")
+		}
+		if isGroupChat(chatID) && alert.Owner.Name != "" {
+			sb.WriteString(" — " + alert.Owner.Name)
+		}
+		if time.Now().Before(alert.SnoozedUntil) {
+			sb.WriteString(fmt.Sprintf(" (отложено до %s)", alert.SnoozedUntil.Local().Format("02.01 15:04")))
+		}
+		sb.WriteString("\n")
+	}
+	if sb.Len() == 0 {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "У вас нет активных оповещений."))
+		return
+	}
+	bs.bot.Send(tgbotapi.NewMessage(chatID, "Активные оповещения:\n"+sb.String()))
+}
+
+// describe возвращает краткое описание оповещения для списка /alerts.
+func (a Alert) describe() string {
+	switch {
+	case a.Condition != nil:
+		return fmt.Sprintf("условие «%s»", a.Condition)
+	case a.Indicator != nil:
+		return fmt.Sprintf("%s: %s", a.Ticker, a.Indicator)
+	case a.Trailing != nil:
+		return fmt.Sprintf("%s: трейлинг %s, пик %.2f, стоп %.2f", a.Ticker, a.Trailing, a.Peak, a.Trailing.StopLevel(a.Peak))
+	case a.Range != nil:
+		return fmt.Sprintf("%s: %s", a.Ticker, a.Range)
+	case a.Spread != nil:
+		return fmt.Sprintf("спред %s/%s: %s", a.Ticker, a.Spread.Second, a.Spread)
+	case a.Direction == "up":
+		return fmt.Sprintf("%s: рост до %.2f", a.Ticker, a.Target)
+	}
+	return fmt.Sprintf("%s: снижение до %.2f", a.Ticker, a.Target)
+}
+
+// checkPriceAlert проверяет достижение целевой цены. Возвращает текст уведомления и true, если оповещение сработало.
+func checkPriceAlert(alert Alert, quotes quoteSource) (string, bool) {
+	stock, err := quotes.Get(alert.Ticker)
+	if err != nil {
+		log.Printf("Ошибка проверки пользовательского оповещения для %s: %v", alert.Ticker, err)
+		return "", false
+	}
+	trigger := false
+	if alert.Direction == "up" && stock.Price >= alert.Target {
+		trigger = true
+	} else if alert.Direction == "down" && stock.Price <= alert.Target {
+		trigger = true
+	}
+	if !trigger {
+		return "", false
+	}
+	msgText := fmt.Sprintf("🔔 Оповещение сработало для %s: цена достигла %.2f (текущее значение: %.2f)", stock.Name, alert.Target, stock.Price)
+	return msgText, true
+}
+
+// checkConditionAlert вычисляет составное условие на котировках текущего цикла
+// и формирует уведомление, если оно выполнено. Возвращает текст уведомления и true, если оповещение сработало.
+func checkConditionAlert(alert Alert, quotes quoteSource) (string, bool) {
+	prices, err := quotes.Prices(alert.Condition.Tickers())
+	if err != nil {
+		log.Printf("Ошибка проверки условия «%s»: %v", alert.Condition, err)
+		return "", false
+	}
+	ok, err := alert.Condition.Eval(prices)
+	if err != nil {
+		log.Printf("Ошибка вычисления условия «%s»: %v", alert.Condition, err)
+		return "", false
+	}
+	if !ok {
+		return "", false
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🔔 Условие «%s» выполнено.\nТекущие цены:", alert.Condition))
+	for _, ticker := range alert.Condition.Tickers() {
+		sb.WriteString(fmt.Sprintf("\n%s: %.2f", ticker, prices[ticker]))
 	}
+	return sb.String(), true
 }
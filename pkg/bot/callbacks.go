@@ -0,0 +1,293 @@
+// TradeTGBot/pkg/bot/callbacks.go
+package bot
+
+import (
+	"TradeTGBot/internal/repository"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Префиксы callback data кнопок уведомления. Кнопки ссылаются на запись
+// alert_history, поэтому продолжают работать после перезапуска бота.
+const (
+	callbackSnooze = "snz"   // snz:<id истории>:<длительность>
+	callbackAck    = "ack"   // ack:<id истории>
+	callbackAgain  = "again" // again:<id истории>
+	callbackLevel  = "lvl"   // lvl:<id истории>
+)
+
+// snoozeOptions - варианты откладывания в порядке отображения.
+var snoozeOptions = []struct {
+	Key      string
+	Label    string
+	Duration time.Duration
+}{
+	{"15m", "⏰ 15 мин", 15 * time.Minute},
+	{"1h", "⏰ 1 час", time.Hour},
+	{"1d", "⏰ 1 день", 24 * time.Hour},
+}
+
+// alertKeyboard строит кнопки для уведомления о срабатываниях.
+func alertKeyboard(triggers []repository.AlertTrigger) *tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, t := range triggers {
+		var snooze []tgbotapi.InlineKeyboardButton
+		for _, opt := range snoozeOptions {
+			snooze = append(snooze, tgbotapi.NewInlineKeyboardButtonData(opt.Label,
+				fmt.Sprintf("%s:%d:%s", callbackSnooze, t.ID, opt.Key)))
+		}
+		ackLabel := "✅ Принято"
+		if len(triggers) > 1 {
+			// В сводном уведомлении подписываем, к какому оповещению относится строка кнопок.
+			ackLabel = fmt.Sprintf("✅ #%d", t.AlertID)
+		}
+		actions := []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData(ackLabel, fmt.Sprintf("%s:%d", callbackAck, t.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("🔁 Ещё раз", fmt.Sprintf("%s:%d", callbackAgain, t.ID)),
+		}
+		if t.Kind == alertKindPrice {
+			actions = append(actions, tgbotapi.NewInlineKeyboardButtonData("✏️ Новый уровень", fmt.Sprintf("%s:%d", callbackLevel, t.ID)))
+		}
+		rows = append(rows, actions, snooze)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &keyboard
+}
+
+// handleCallback обрабатывает нажатия кнопок под уведомлениями о срабатывании.
+func (bs *BotService) handleCallback(cq *tgbotapi.CallbackQuery) {
+	parts := strings.Split(cq.Data, ":")
+	if len(parts) < 2 || cq.Message == nil {
+		bs.answerCallback(cq, "Кнопка устарела.")
+		return
+	}
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		bs.answerCallback(cq, "Кнопка устарела.")
+		return
+	}
+
+	trigger, err := repository.GetAlertTrigger(id)
+	if err != nil || trigger.ChatID != cq.Message.Chat.ID {
+		if err != nil {
+			log.Printf("Ошибка обработки кнопки %q: %v", cq.Data, err)
+		}
+		bs.answerCallback(cq, "Оповещение не найдено.")
+		return
+	}
+	// «Ещё раз» и «Новый уровень» создают новое оповещение.
+	creates := parts[0] == callbackAgain || parts[0] == callbackLevel
+	if !bs.mayManageTrigger(cq.Message.Chat.ID, cq.From, trigger, creates) {
+		bs.answerCallback(cq, "Это оповещение может изменить только его создатель или администратор.")
+		return
+	}
+
+	switch parts[0] {
+	case callbackAck:
+		bs.claimAndRun(cq, trigger, repository.TriggerActionAck, func() (string, error) {
+			return "Принято.", nil
+		})
+	case callbackSnooze:
+		var snooze time.Duration
+		for _, opt := range snoozeOptions {
+			if len(parts) == 3 && parts[2] == opt.Key {
+				snooze = opt.Duration
+			}
+		}
+		if snooze == 0 {
+			bs.answerCallback(cq, "Кнопка устарела.")
+			return
+		}
+		bs.claimAndRun(cq, trigger, repository.TriggerActionSnooze, func() (string, error) {
+			alert, err := bs.rearmAlert(trigger, snooze, 0)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Оповещение #%d отложено до %s.", alert.ID, alert.SnoozedUntil.Local().Format("02.01 15:04")), nil
+		})
+	case callbackAgain:
+		bs.claimAndRun(cq, trigger, repository.TriggerActionAgain, func() (string, error) {
+			alert, err := bs.rearmAlert(trigger, 0, 0)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Оповещение #%d снова активно: %s.", alert.ID, alert.describe()), nil
+		})
+	case callbackLevel:
+		if trigger.Action != "" {
+			bs.answerCallback(cq, "По этому оповещению уже выполнено действие.")
+			return
+		}
+		bs.answerCallback(cq, "")
+		msg := tgbotapi.NewMessage(cq.Message.Chat.ID, fmt.Sprintf(
+			"Новый уровень для %s (запись истории #%d): отправьте цену ответом на это сообщение.", trigger.Ticker, trigger.ID))
+		msg.ReplyMarkup = tgbotapi.ForceReply{ForceReply: true, Selective: true}
+		sent, err := bs.bot.Send(msg)
+		if err != nil {
+			log.Printf("Ошибка отправки запроса нового уровня: %v", err)
+			return
+		}
+		// Ответ на запрос связывается с записью истории по ID сообщения, а не по его тексту.
+		err = repository.SavePrompt(repository.Prompt{
+			ChatID:    sent.Chat.ID,
+			MessageID: sent.MessageID,
+			Action:    repository.PromptNewLevel,
+			TriggerID: trigger.ID,
+		})
+		if err != nil {
+			log.Printf("%v", err)
+			bs.bot.Send(tgbotapi.NewMessage(cq.Message.Chat.ID, "Ошибка, попробуйте позже."))
+		}
+	default:
+		bs.answerCallback(cq, "Кнопка устарела.")
+	}
+}
+
+// claimAndRun фиксирует действие над срабатыванием (однократно), выполняет его
+// и убирает кнопки у сообщения с одиночным уведомлением.
+func (bs *BotService) claimAndRun(cq *tgbotapi.CallbackQuery, trigger repository.AlertTrigger, action string, run func() (string, error)) {
+	claimed, err := repository.ClaimAlertTrigger(trigger.ID, action)
+	if err != nil {
+		log.Printf("Ошибка обработки кнопки %q: %v", cq.Data, err)
+		bs.answerCallback(cq, "Ошибка, попробуйте позже.")
+		return
+	}
+	if !claimed {
+		bs.answerCallback(cq, "По этому оповещению уже выполнено действие.")
+		return
+	}
+
+	text, err := run()
+	if err != nil {
+		log.Printf("Ошибка выполнения действия %s по записи истории %d: %v", action, trigger.ID, err)
+		if err := repository.ReleaseAlertTrigger(trigger.ID); err != nil {
+			log.Printf("%v", err)
+		}
+		bs.answerCallback(cq, fmt.Sprintf("Ошибка: %v", err))
+		return
+	}
+	bs.answerCallback(cq, text)
+	bs.dropKeyboard(cq.Message, trigger)
+}
+
+// dropKeyboard убирает кнопки у уведомления, если в нём одно срабатывание.
+func (bs *BotService) dropKeyboard(message *tgbotapi.Message, trigger repository.AlertTrigger) {
+	triggers, err := repository.GetAlertTriggersByNotification(trigger.NotificationID)
+	if err != nil || len(triggers) != 1 {
+		return
+	}
+	edit := tgbotapi.NewEditMessageReplyMarkup(message.Chat.ID, message.MessageID,
+		tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}})
+	if _, err := bs.bot.Request(edit); err != nil {
+		log.Printf("Ошибка удаления кнопок уведомления: %v", err)
+	}
+}
+
+// answerCallback отвечает на нажатие кнопки всплывающим текстом.
+func (bs *BotService) answerCallback(cq *tgbotapi.CallbackQuery, text string) {
+	if _, err := bs.bot.Request(tgbotapi.NewCallback(cq.ID, text)); err != nil {
+		log.Printf("Ошибка ответа на нажатие кнопки: %v", err)
+	}
+}
+
+// handleNewLevelReply обрабатывает ответ на запрос нового уровня: запрос находится
+// в bot_prompts по ID сообщения, на которое ответил пользователь. Возвращает false,
+// если сообщение не является таким ответом.
+func (bs *BotService) handleNewLevelReply(message *tgbotapi.Message) bool {
+	reply := message.ReplyToMessage
+	if reply == nil || reply.From == nil || reply.From.ID != bs.bot.Self.ID {
+		return false
+	}
+	prompt, ok, err := repository.GetPrompt(message.Chat.ID, reply.MessageID)
+	if err != nil {
+		log.Printf("%v", err)
+	}
+	if !ok || prompt.Action != repository.PromptNewLevel {
+		return false
+	}
+	id := prompt.TriggerID
+
+	level, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(message.Text), ",", "."), 64)
+	if err != nil || level <= 0 {
+		bs.bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Неверный формат цены. Ответьте на запрос ещё раз."))
+		return true
+	}
+	trigger, err := repository.GetAlertTrigger(id)
+	if err != nil || trigger.ChatID != message.Chat.ID {
+		bs.bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Оповещение не найдено."))
+		return true
+	}
+	if !bs.mayManageTrigger(message.Chat.ID, message.From, trigger, true) {
+		bs.bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Это оповещение может изменить только его создатель или администратор."))
+		return true
+	}
+	claimed, err := repository.ClaimAlertTrigger(id, repository.TriggerActionLevel)
+	if err != nil || !claimed {
+		bs.bot.Send(tgbotapi.NewMessage(message.Chat.ID, "По этому оповещению уже выполнено действие."))
+		return true
+	}
+	if err := repository.DeletePrompt(message.Chat.ID, reply.MessageID); err != nil {
+		log.Printf("%v", err)
+	}
+
+	alert, err := bs.rearmAlert(trigger, 0, level)
+	if err != nil {
+		if err := repository.ReleaseAlertTrigger(id); err != nil {
+			log.Printf("%v", err)
+		}
+		bs.bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Ошибка: %v", err)))
+		return true
+	}
+	bs.bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Оповещение #%d установлено: %s.", alert.ID, alert.describe())))
+	return true
+}
+
+// rearmAlert создаёт новое оповещение по определению сработавшего.
+// Состояние, зависящее от цены (направление, пик трейлинга, сторона входа в коридор),
+// пересчитывается по текущей котировке. Если level > 0, он заменяет целевую цену,
+// если snooze > 0 - оповещение не проверяется до истечения этого времени.
+func (bs *BotService) rearmAlert(trigger repository.AlertTrigger, snooze time.Duration, level float64) (Alert, error) {
+	alert, err := alertFromRecord(trigger.Alert())
+	if err != nil {
+		return alert, err
+	}
+	if level > 0 {
+		alert.Target = level
+	}
+	if snooze > 0 {
+		alert.SnoozedUntil = time.Now().Add(snooze)
+	}
+
+	if alert.Ticker != "" && alert.Condition == nil && alert.Spread == nil {
+		stock, err := bs.quotes().Get(alert.Ticker)
+		if err != nil {
+			return alert, fmt.Errorf("не удалось получить цену %s: %w", alert.Ticker, err)
+		}
+		switch {
+		case alert.Trailing != nil:
+			alert.Peak = stock.Price
+		case alert.Range != nil && alert.Range.Mode == rangeInside:
+			alert.Direction = "up"
+			if stock.Price > alert.Range.High {
+				alert.Direction = "down"
+			}
+		case alert.kind() == alertKindPrice:
+			if stock.Price == alert.Target {
+				return alert, fmt.Errorf("%s уже имеет цену %.2f", stock.Name, stock.Price)
+			}
+			alert.Direction = "up"
+			if stock.Price > alert.Target {
+				alert.Direction = "down"
+			}
+		}
+	}
+
+	return bs.saveAlert(alert)
+}
@@ -0,0 +1,159 @@
+// TradeTGBot/pkg/bot/chart.go
+package bot
+
+import (
+	"TradeTGBot/internal/chart"
+	"TradeTGBot/internal/repository"
+	"TradeTGBot/pkg/stocks"
+	"bytes"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Параметры графиков /chart.
+const (
+	defaultChartPeriod = 24 * time.Hour
+	maxChartPeriod     = 90 * 24 * time.Hour
+	maxChartBars       = 150 // Бар выбирается так, чтобы за период их было не больше
+	maxChartAverages   = 4
+)
+
+// chartBars - допустимые длительности бара графика по возрастанию.
+var chartBars = []time.Duration{
+	time.Minute, 5 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+	time.Hour, 4 * time.Hour, 24 * time.Hour,
+}
+
+const chartUsage = "Формат: /chart ТИКЕР [ПЕРИОД] [line|candles] [sma20] [ema50]\n" +
+	"Период: 1h, 4h, 1d, 5d, 1w, 30d (по умолчанию 1d). Пример: /chart SBER 1w sma20"
+
+// parseChartPeriod разбирает период графика: длительность Go (1h, 90m) или дни и недели (5d, 2w).
+func parseChartPeriod(s string) (time.Duration, bool) {
+	s = strings.ToLower(s)
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, err := strconv.Atoi(strings.TrimSuffix(s, suffix)); strings.HasSuffix(s, suffix) && err == nil && n > 0 {
+			return time.Duration(n) * unit, true
+		}
+	}
+	d, err := time.ParseDuration(s)
+	return d, err == nil && d > 0
+}
+
+// chartBar выбирает наименьший бар, при котором за период их не больше maxChartBars.
+func chartBar(period time.Duration) time.Duration {
+	for _, bar := range chartBars {
+		if period/bar <= maxChartBars {
+			return bar
+		}
+	}
+	return chartBars[len(chartBars)-1]
+}
+
+// formatChartDuration записывает длительность коротко: 15m, 4h, 5d.
+func formatChartDuration(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	default:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+}
+
+// handleChart обрабатывает /chart: строит график цены тикера по истории и отправляет его картинкой.
+func (bs *BotService) handleChart(chatID int64, args []string) {
+	if len(args) == 0 {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, chartUsage))
+		return
+	}
+	ticker := strings.ToUpper(args[0])
+	info, ok := stocks.Stocks[ticker]
+	if !ok {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Тикер %s не найден в базе.", ticker)))
+		return
+	}
+
+	period := defaultChartPeriod
+	opts := chart.Options{Style: chart.Candlestick}
+	for _, arg := range args[1:] {
+		lower := strings.ToLower(arg)
+		switch {
+		case lower == "line":
+			opts.Style = chart.Line
+		case lower == "candles":
+			opts.Style = chart.Candlestick
+		case strings.HasPrefix(lower, "sma") || strings.HasPrefix(lower, "ema"):
+			n, err := strconv.Atoi(lower[3:])
+			if err != nil || n < 2 || n > maxChartBars {
+				bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Неверный период средней %q.\n%s", arg, chartUsage)))
+				return
+			}
+			if len(opts.MAs) == maxChartAverages {
+				bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Можно добавить не больше %d средних.", maxChartAverages)))
+				return
+			}
+			opts.MAs = append(opts.MAs, chart.MA{Period: n, Exponential: lower[0] == 'e'})
+		default:
+			d, ok := parseChartPeriod(arg)
+			if !ok || d > maxChartPeriod {
+				bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Неверный период %q.\n%s", arg, chartUsage)))
+				return
+			}
+			period = d
+		}
+	}
+
+	bar := chartBar(period)
+	// Историю берём с запасом, чтобы скользящие средние начинались с левого края графика.
+	lookback := 0
+	for _, ma := range opts.MAs {
+		lookback = max(lookback, ma.Period)
+	}
+	now := time.Now()
+	from := now.Add(-period)
+	candles, err := repository.GetCandles(ticker, bar, from.Add(-bar*time.Duration(lookback)), now)
+	if err != nil {
+		log.Printf("Ошибка получения истории %s для графика: %v", ticker, err)
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении истории цен."))
+		return
+	}
+	// Бары до начала периода нужны только для расчёта средних.
+	warmup := 0
+	for warmup < len(candles) && candles[warmup].Time.Before(from.Truncate(bar)) {
+		warmup++
+	}
+	if len(candles)-warmup < 2 {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Недостаточно истории %s за %s для графика.", ticker, formatChartDuration(period))))
+		return
+	}
+
+	settings, err := repository.GetChatSettings(chatID)
+	if err != nil {
+		log.Printf("Ошибка получения настроек чата: %v", err)
+	}
+	if loc, err := time.LoadLocation(settings.Timezone); err == nil {
+		opts.Location = loc
+	}
+	opts.Warmup = warmup
+	opts.Title = fmt.Sprintf("%s  %s, bar %s", ticker, formatChartDuration(period), formatChartDuration(bar))
+
+	var buf bytes.Buffer
+	if err := chart.Render(&buf, candles, opts); err != nil {
+		log.Printf("Ошибка построения графика %s: %v", ticker, err)
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при построении графика."))
+		return
+	}
+	first, last := candles[warmup], candles[len(candles)-1]
+	change := (last.Close - first.Open) / first.Open * 100
+	photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileBytes{Name: ticker + ".png", Bytes: buf.Bytes()})
+	photo.Caption = fmt.Sprintf("%s (%s) за %s: %.2f → %.2f (%+.2f%%)\nОбъём - число обновлений цены за бар.",
+		ticker, info.Name, formatChartDuration(period), first.Open, last.Close, change)
+	if _, err := bs.bot.Send(photo); err != nil {
+		log.Printf("Ошибка отправки графика %s: %v", ticker, err)
+	}
+}
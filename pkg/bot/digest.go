@@ -0,0 +1,169 @@
+// TradeTGBot/pkg/bot/digest.go
+package bot
+
+import (
+	"TradeTGBot/internal/notify"
+	"TradeTGBot/internal/repository"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"html"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSummaryThreshold ограничивает порог сводки: кнопки всех срабатываний
+// полного дайджеста должны поместиться в лимит Telegram на клавиатуру.
+const maxSummaryThreshold = 10
+
+// deliverDigest отправляет несколько уведомлений чата (или одной темы его форума)
+// одним сообщением туда же, куда ушло бы первое из них. Пока их не больше
+// threshold, сообщение содержит полные тексты и кнопки каждого срабатывания;
+// сверх порога - краткую сводку по строке на срабатывание.
+func (bs *BotService) deliverDigest(notifications []repository.Notification, threshold int) {
+	first := notifications[0]
+	dest, ok := bs.destination(first)
+	if !ok {
+		// Неудача первого уже записана; остальные записываются каждое само по себе.
+		for _, n := range notifications[1:] {
+			bs.deliverNotification(n)
+		}
+		return
+	}
+	deferred := false
+	var triggers [][]repository.AlertTrigger
+	var all []repository.AlertTrigger
+	for _, n := range notifications {
+		deferred = deferred || n.Deferred
+		var t []repository.AlertTrigger
+		if n.AlertID != 0 {
+			var err error
+			if t, err = repository.GetAlertTriggersByNotification(n.ID); err != nil {
+				log.Printf("Ошибка получения срабатываний для уведомления %d: %v", n.ID, err)
+			}
+		}
+		triggers = append(triggers, t)
+		all = append(all, t...)
+	}
+
+	header := fmt.Sprintf("🔔 Уведомлений: %d", len(notifications))
+	if deferred {
+		header = fmt.Sprintf("🌙 За время тихих часов (%d):", len(notifications))
+	}
+
+	// Уведомления с упоминанием по ссылке размечены HTML - тогда остальные экранируются.
+	parseMode := ""
+	for _, n := range notifications {
+		if n.ParseMode == "HTML" {
+			parseMode = "HTML"
+		}
+	}
+
+	var text string
+	var keyboard *tgbotapi.InlineKeyboardMarkup
+	if len(notifications) <= threshold {
+		parts := []string{header}
+		for _, n := range notifications {
+			if parseMode == "HTML" && n.ParseMode == "" {
+				parts = append(parts, html.EscapeString(n.Text))
+				continue
+			}
+			parts = append(parts, n.Text)
+		}
+		text = strings.Join(parts, "\n\n")
+		keyboard = alertKeyboard(all)
+	}
+	if text == "" || len([]rune(text)) > telegramTextLimit {
+		text = summarizeDigest(header, notifications, triggers)
+		parseMode = "HTML"
+		keyboard = nil
+	}
+
+	msg := notify.Message{ChatID: first.ChatID, Text: text, ParseMode: parseMode, CreatedAt: time.Now()}
+	if keyboard != nil {
+		msg.ReplyMarkup = keyboard
+	}
+	if err := bs.notifiers[first.Channel].Send(dest, msg); err != nil {
+		for _, n := range notifications {
+			bs.markFailed(n, err)
+		}
+		return
+	}
+	for _, n := range notifications {
+		if err := repository.MarkNotificationDelivered(n); err != nil {
+			log.Printf("Ошибка отметки доставки уведомления %d: %v", n.ID, err)
+		}
+	}
+}
+
+// summarizeDigest строит краткую сводку в разметке HTML: строка на каждое срабатывание
+// (или первая строка уведомления, не связанного с оповещением). Строки,
+// не поместившиеся в сообщение, заменяются счётчиком.
+func summarizeDigest(header string, notifications []repository.Notification, triggers [][]repository.AlertTrigger) string {
+	var lines []string
+	for i, n := range notifications {
+		if len(triggers[i]) == 0 {
+			first, _, _ := strings.Cut(n.Text, "\n")
+			first = strings.Trim(first, "*_` ")
+			if n.ParseMode == "" {
+				first = html.EscapeString(first)
+			}
+			lines = append(lines, "• "+first)
+			continue
+		}
+		for _, t := range triggers[i] {
+			line := fmt.Sprintf("• #%d %s", t.AlertID, html.EscapeString(t.Definition))
+			if t.TriggerPrice > 0 {
+				line += fmt.Sprintf(" — %.2f", t.TriggerPrice)
+			}
+			if isGroupChat(t.ChatID) && t.UserName != "" {
+				line += " 👤 " + Owner{ID: t.UserID, Name: t.UserName}.mentionHTML()
+			}
+			lines = append(lines, line)
+		}
+	}
+	footer := "\nПодробности: /history"
+
+	var sb strings.Builder
+	sb.WriteString(header + "\n")
+	for i, line := range lines {
+		rest := fmt.Sprintf("… и ещё %d", len(lines)-i)
+		if len([]rune(sb.String()+line+rest+footer))+2 > telegramTextLimit {
+			sb.WriteString(rest + "\n")
+			break
+		}
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString(footer)
+	return sb.String()
+}
+
+// handleSummary обрабатывает /summary N: с какого числа уведомлений за проход
+// они сворачиваются в краткую сводку.
+func (bs *BotService) handleSummary(chatID int64, args []string) {
+	settings, err := repository.GetChatSettings(chatID)
+	if err != nil {
+		log.Printf("Ошибка получения настроек чата: %v", err)
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении настроек."))
+		return
+	}
+	if len(args) == 0 {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf(
+			"Несколько уведомлений подряд приходят одним сообщением; если их больше %d, - краткой сводкой.\n"+
+				"Изменить порог: /summary N (от 1 до %d).", settings.SummaryThreshold, maxSummaryThreshold)))
+		return
+	}
+	threshold, err := strconv.Atoi(args[0])
+	if err != nil || threshold < 1 || threshold > maxSummaryThreshold {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Порог должен быть числом от 1 до %d.", maxSummaryThreshold)))
+		return
+	}
+	settings.SummaryThreshold = threshold
+	if err := repository.SaveChatSettings(settings); err != nil {
+		log.Printf("Ошибка сохранения настроек чата: %v", err)
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при сохранении настроек."))
+		return
+	}
+	bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Больше %d уведомлений за раз будут приходить краткой сводкой.", threshold)))
+}
@@ -0,0 +1,300 @@
+// TradeTGBot/pkg/bot/engine.go
+package bot
+
+import (
+	"TradeTGBot/internal/pricebus"
+	"TradeTGBot/pkg/stocks"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// quoteSource - источник котировок для проверки оповещений.
+type quoteSource interface {
+	Get(ticker string) (stocks.StockData, error)
+	Prices(tickers []string) (map[string]float64, error)
+}
+
+// quoteSnapshot - последние известные котировки, передаваемые в проверку оповещений.
+type quoteSnapshot map[string]stocks.StockData
+
+// Get возвращает последнюю известную котировку тикера.
+func (qs quoteSnapshot) Get(ticker string) (stocks.StockData, error) {
+	data, ok := qs[ticker]
+	if !ok {
+		return stocks.StockData{}, fmt.Errorf("нет котировки для %s", ticker)
+	}
+	return data, nil
+}
+
+// Prices возвращает цены набора тикеров.
+func (qs quoteSnapshot) Prices(tickers []string) (map[string]float64, error) {
+	prices := make(map[string]float64, len(tickers))
+	for _, ticker := range tickers {
+		data, err := qs.Get(ticker)
+		if err != nil {
+			return nil, err
+		}
+		prices[ticker] = data.Price
+	}
+	return prices, nil
+}
+
+// tickerIndex - оповещения, которые нужно проверять при тике одного тикера.
+// Ценовые оповещения отсортированы по целевой цене, так что тик затрагивает
+// только те из них, чей уровень пересечён; остальные виды проверяются на каждом тике.
+type tickerIndex struct {
+	up    []*Alert // Рост до Target, по возрастанию Target
+	down  []*Alert // Снижение до Target, по убыванию Target
+	other []*Alert // Условия, индикаторы, трейлинги, коридоры, спреды
+}
+
+// crossed возвращает ценовые оповещения, уровень которых пересечён ценой, и остальные оповещения.
+func (ti *tickerIndex) crossed(price float64) []*Alert {
+	upTo := sort.Search(len(ti.up), func(i int) bool { return ti.up[i].Target > price })
+	downTo := sort.Search(len(ti.down), func(i int) bool { return ti.down[i].Target < price })
+	candidates := make([]*Alert, 0, upTo+downTo+len(ti.other))
+	candidates = append(candidates, ti.up[:upTo]...)
+	candidates = append(candidates, ti.down[:downTo]...)
+	return append(candidates, ti.other...)
+}
+
+func (ti *tickerIndex) empty() bool {
+	return len(ti.up) == 0 && len(ti.down) == 0 && len(ti.other) == 0
+}
+
+// alertEngine хранит активные оповещения, проиндексированные по тикерам,
+// и проверяет их по мере поступления котировок. Безопасен для одновременного
+// добавления и удаления оповещений из обработчиков команд.
+type alertEngine struct {
+	mu       sync.Mutex
+	byID     map[int]*Alert
+	byTicker map[string]*tickerIndex
+	last     map[string]stocks.StockData // Последние котировки по тикерам
+
+	// trigger вызывается для сработавшего оповещения; при false оповещение остаётся активным.
+	trigger func(alert Alert, text string, quotes quoteSource) bool
+}
+
+func newAlertEngine(trigger func(alert Alert, text string, quotes quoteSource) bool) *alertEngine {
+	return &alertEngine{
+		byID:     make(map[int]*Alert),
+		byTicker: make(map[string]*tickerIndex),
+		last:     make(map[string]stocks.StockData),
+		trigger:  trigger,
+	}
+}
+
+// indexTickers возвращает тикеры, по тикам которых нужно проверять оповещение.
+func (a *Alert) indexTickers() []string {
+	switch {
+	case a.Condition != nil:
+		return a.Condition.Tickers()
+	case a.Spread != nil:
+		return []string{a.Ticker, a.Spread.Second}
+	}
+	return []string{a.Ticker}
+}
+
+// Add добавляет оповещение в индекс.
+func (e *alertEngine) Add(alert Alert) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	a := &alert
+	e.byID[a.ID] = a
+	for _, ticker := range a.indexTickers() {
+		ti, ok := e.byTicker[ticker]
+		if !ok {
+			ti = &tickerIndex{}
+			e.byTicker[ticker] = ti
+		}
+		switch {
+		case a.kind() == alertKindPrice && a.Direction == "up":
+			i := sort.Search(len(ti.up), func(i int) bool { return ti.up[i].Target > a.Target })
+			ti.up = insertAt(ti.up, i, a)
+		case a.kind() == alertKindPrice:
+			i := sort.Search(len(ti.down), func(i int) bool { return ti.down[i].Target < a.Target })
+			ti.down = insertAt(ti.down, i, a)
+		default:
+			ti.other = append(ti.other, a)
+		}
+	}
+}
+
+// Remove удаляет оповещение из индекса. Возвращает false, если его там не было.
+func (e *alertEngine) Remove(id int) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	a, ok := e.byID[id]
+	if !ok {
+		return false
+	}
+	delete(e.byID, id)
+	for _, ticker := range a.indexTickers() {
+		ti, ok := e.byTicker[ticker]
+		if !ok {
+			continue
+		}
+		ti.up = removePtr(ti.up, a)
+		ti.down = removePtr(ti.down, a)
+		ti.other = removePtr(ti.other, a)
+		if ti.empty() {
+			delete(e.byTicker, ticker)
+		}
+	}
+	return true
+}
+
+// ForChat возвращает копии активных оповещений чата, упорядоченные по ID.
+func (e *alertEngine) ForChat(chatID int64) []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var alerts []Alert
+	for _, a := range e.byID {
+		if a.ChatID == chatID {
+			alerts = append(alerts, *a)
+		}
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].ID < alerts[j].ID })
+	return alerts
+}
+
+// Get возвращает копию активного оповещения по ID.
+func (e *alertEngine) Get(id int) (Alert, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	a, ok := e.byID[id]
+	if !ok {
+		return Alert{}, false
+	}
+	return *a, true
+}
+
+// SetUrgent меняет срочность активного оповещения.
+func (e *alertEngine) SetUrgent(id int, urgent bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if a, ok := e.byID[id]; ok {
+		a.Urgent = urgent
+	}
+}
+
+// Len возвращает число активных оповещений.
+func (e *alertEngine) Len() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.byID)
+}
+
+// Tickers возвращает тикеры, по которым есть активные оповещения.
+func (e *alertEngine) Tickers() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	tickers := make([]string, 0, len(e.byTicker))
+	for ticker := range e.byTicker {
+		tickers = append(tickers, ticker)
+	}
+	sort.Strings(tickers)
+	return tickers
+}
+
+// OnTick обрабатывает новую котировку: проверяет только оповещения этого тикера
+// (из ценовых - только с пересечённым уровнем). Составные условия и спреды
+// используют последние известные котировки остальных тикеров.
+func (e *alertEngine) OnTick(ticker string, data stocks.StockData) {
+	e.mu.Lock()
+	e.last[ticker] = data
+	ti, ok := e.byTicker[ticker]
+	if !ok {
+		e.mu.Unlock()
+		return
+	}
+	// Проверка может обращаться к БД, поэтому выполняется над копиями без блокировки.
+	var candidates []Alert
+	now := time.Now()
+	for _, a := range ti.crossed(data.Price) {
+		if now.Before(a.SnoozedUntil) {
+			continue
+		}
+		candidates = append(candidates, *a)
+	}
+	quotes := make(quoteSnapshot, len(e.last))
+	for t, d := range e.last {
+		quotes[t] = d
+	}
+	e.mu.Unlock()
+
+	for _, alert := range candidates {
+		text, fired := alert.check(quotes)
+		if fired && e.trigger(alert, text, quotes) {
+			e.Remove(alert.ID)
+			continue
+		}
+		if !fired {
+			e.updateState(alert)
+		}
+	}
+}
+
+// updateState сохраняет изменившееся при проверке состояние оповещения (пик трейлинга,
+// состояние индикатора, сторону подхода к коридору). Если проверка сработала, но уведомление записать не удалось,
+// состояние не обновляется, чтобы сигнал не был потерян в следующем цикле.
+func (e *alertEngine) updateState(alert Alert) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if a, ok := e.byID[alert.ID]; ok {
+		a.Peak = alert.Peak
+		a.indicatorState, a.stateKnown = alert.indicatorState, alert.stateKnown
+		a.dataWarned = alert.dataWarned
+		if a.Range != nil {
+			a.Direction = alert.Direction // Сторона подхода к коридору
+		}
+	}
+}
+
+// check проверяет оповещение на котировках и возвращает текст уведомления и true,
+// если оно сработало. Изменяемое состояние (пик, состояние индикатора, сторона коридора) обновляется в alert.
+func (a *Alert) check(quotes quoteSource) (string, bool) {
+	switch {
+	case a.Condition != nil:
+		return checkConditionAlert(*a, quotes)
+	case a.Indicator != nil:
+		return checkIndicatorAlert(a, quotes)
+	case a.Trailing != nil:
+		return checkTrailingAlert(a, quotes)
+	case a.Range != nil:
+		return checkRangeAlert(a, quotes)
+	case a.Spread != nil:
+		return checkSpreadAlert(*a, quotes)
+	}
+	return checkPriceAlert(*a, quotes)
+}
+
+// Consume проверяет оповещения по тикам из подписки на шину котировок.
+// Блокирует вызывающую горутину до отмены подписки.
+func (e *alertEngine) Consume(sub *pricebus.Subscription) {
+	for t := range sub.C {
+		e.OnTick(t.Ticker, stocks.StockData{Name: t.Name, Price: t.Price})
+	}
+}
+
+func insertAt(alerts []*Alert, i int, a *Alert) []*Alert {
+	alerts = append(alerts, nil)
+	copy(alerts[i+1:], alerts[i:])
+	alerts[i] = a
+	return alerts
+}
+
+func removePtr(alerts []*Alert, a *Alert) []*Alert {
+	for i, x := range alerts {
+		if x == a {
+			return append(alerts[:i], alerts[i+1:]...)
+		}
+	}
+	return alerts
+}
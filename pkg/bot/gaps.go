@@ -0,0 +1,151 @@
+// TradeTGBot/pkg/bot/gaps.go
+package bot
+
+import (
+	"TradeTGBot/internal/analyzer"
+	"TradeTGBot/internal/repository"
+	"TradeTGBot/pkg/stocks"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultMinGap - минимальный гэп подписки на тикер по умолчанию, в процентах.
+const defaultMinGap = 2.0
+
+// gapsUsage - подсказка по команде /gaps.
+const gapsUsage = "Отчёт после открытия: /gaps report [МИН%]\n" +
+	"Гэпы тикеров: /gaps SBER GAZP [МИН%] (по умолчанию от 2%)\n" +
+	"Отписаться: /gaps off SBER, /gaps off report или /gaps off all"
+
+// handleGaps обрабатывает /gaps: без аргументов показывает гэпы сегодняшнего
+// открытия и подписки чата, с аргументами - управляет подписками.
+func (bs *BotService) handleGaps(chatID int64, args []string) {
+	if len(args) == 0 {
+		bs.showGaps(chatID)
+		return
+	}
+	if strings.EqualFold(args[0], "off") {
+		bs.removeGapSubscriptions(chatID, args[1:])
+		return
+	}
+
+	minGap := -1.0
+	if last := strings.TrimSuffix(args[len(args)-1], "%"); len(args) > 1 {
+		if v, err := strconv.ParseFloat(strings.ReplaceAll(last, ",", "."), 64); err == nil {
+			if v < 0 {
+				bs.bot.Send(tgbotapi.NewMessage(chatID, "Минимальный гэп не может быть отрицательным."))
+				return
+			}
+			minGap = v
+			args = args[:len(args)-1]
+		}
+	}
+
+	if len(args) == 1 && strings.EqualFold(args[0], "report") {
+		minGap = max(minGap, 0)
+		err := repository.SaveGapSubscription(repository.GapSubscription{ChatID: chatID, Ticker: repository.GapReport, MinGap: minGap})
+		if err != nil {
+			log.Printf("Ошибка сохранения подписки на отчёт о гэпах: %v", err)
+			bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при сохранении подписки."))
+			return
+		}
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Отчёт о наибольших гэпах будет приходить после открытия торгов."))
+		return
+	}
+
+	if minGap < 0 {
+		minGap = defaultMinGap
+	}
+	var tickers []string
+	for _, arg := range args {
+		ticker := strings.ToUpper(arg)
+		if _, ok := stocks.Stocks[ticker]; !ok {
+			bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Тикер %s не найден в базе.\n%s", ticker, gapsUsage)))
+			return
+		}
+		tickers = append(tickers, ticker)
+	}
+	for _, ticker := range tickers {
+		err := repository.SaveGapSubscription(repository.GapSubscription{ChatID: chatID, Ticker: ticker, MinGap: minGap})
+		if err != nil {
+			log.Printf("Ошибка сохранения подписки на гэпы: %v", err)
+			bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при сохранении подписки."))
+			return
+		}
+	}
+	bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf(
+		"Подписка оформлена: %s - уведомление при гэпе на открытии от %.2f%% к закрытию предыдущего дня.",
+		strings.Join(tickers, ", "), minGap)))
+}
+
+// removeGapSubscriptions обрабатывает /gaps off ТИКЕР... | report | all.
+func (bs *BotService) removeGapSubscriptions(chatID int64, args []string) {
+	if len(args) == 0 {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, gapsUsage))
+		return
+	}
+	removed := 0
+	for _, arg := range args {
+		var n int
+		var err error
+		switch strings.ToLower(arg) {
+		case "all":
+			n, err = repository.DeleteAllGapSubscriptions(chatID)
+		case "report":
+			n, err = repository.DeleteGapSubscription(chatID, repository.GapReport)
+		default:
+			n, err = repository.DeleteGapSubscription(chatID, strings.ToUpper(arg))
+		}
+		if err != nil {
+			log.Printf("Ошибка удаления подписки на гэпы: %v", err)
+			bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при удалении подписки."))
+			return
+		}
+		removed += n
+	}
+	if removed == 0 {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Таких подписок нет. Текущие подписки: /gaps"))
+		return
+	}
+	bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Удалено подписок: %d.", removed)))
+}
+
+// showGaps показывает гэпы сегодняшнего открытия и подписки чата на гэпы.
+func (bs *BotService) showGaps(chatID int64) {
+	day := analyzer.MarketDay(time.Now())
+	gaps, err := repository.GetOpeningGaps(day)
+	if err != nil {
+		log.Printf("Ошибка получения гэпов: %v", err)
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении гэпов."))
+		return
+	}
+	subs, err := repository.GetGapSubscriptions(chatID)
+	if err != nil {
+		log.Printf("Ошибка получения подписок на гэпы: %v", err)
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении подписок."))
+		return
+	}
+
+	var sb strings.Builder
+	if len(gaps) == 0 {
+		sb.WriteString("Гэпы сегодняшнего открытия ещё не рассчитаны: отчёт появляется вскоре после открытия торгов.\n")
+	} else {
+		sb.WriteString(analyzer.FormatGapReport(day, gaps, 0) + "\n")
+	}
+	if len(subs) > 0 {
+		sb.WriteString("\nПодписки на гэпы:\n")
+		for _, s := range subs {
+			if s.Ticker == repository.GapReport {
+				sb.WriteString(fmt.Sprintf("отчёт после открытия - от %.2f%%\n", s.MinGap))
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("%s - от %.2f%%\n", s.Ticker, s.MinGap))
+		}
+	}
+	sb.WriteString("\n" + gapsUsage)
+	bs.bot.Send(tgbotapi.NewMessage(chatID, sb.String()))
+}
@@ -0,0 +1,224 @@
+// TradeTGBot/pkg/bot/group.go
+package bot
+
+import (
+	"TradeTGBot/internal/repository"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"html"
+	"log"
+	"strings"
+)
+
+// Owner - участник чата, создавший оповещение.
+type Owner struct {
+	ID   int64
+	Name string // @username, если он есть, иначе имя - так создатель упоминается в группе
+}
+
+// mentionHTML возвращает упоминание создателя для сообщения с разметкой HTML:
+// @username, если он известен, иначе ссылку tg://user?id на пользователя с его именем.
+func (o Owner) mentionHTML() string {
+	if strings.HasPrefix(o.Name, "@") || o.ID == 0 {
+		return html.EscapeString(o.Name)
+	}
+	return fmt.Sprintf(`<a href="tg://user?id=%d">%s</a>`, o.ID, html.EscapeString(o.Name))
+}
+
+// ownerOf возвращает создателя оповещения по отправителю сообщения.
+func ownerOf(u *tgbotapi.User) Owner {
+	if u == nil {
+		return Owner{}
+	}
+	if u.UserName != "" {
+		return Owner{ID: u.ID, Name: "@" + u.UserName}
+	}
+	return Owner{ID: u.ID, Name: strings.TrimSpace(u.FirstName + " " + u.LastName)}
+}
+
+// isGroupChat сообщает, относится ли ID к группе (у групп и каналов ID отрицательные).
+func isGroupChat(chatID int64) bool {
+	return chatID < 0
+}
+
+// permission - действие, которое администраторы группы могут ограничить.
+type permission int
+
+const (
+	permAlerts   permission = iota // Создание оповещений
+	permSettings                   // Изменение настроек чата
+)
+
+// commandPermissions - какие команды требуют разрешения в группе.
+var commandPermissions = map[string]permission{
+	"alert":       permAlerts,
+	"indicator":   permAlerts,
+	"trailing":    permAlerts,
+	"range":       permAlerts,
+	"spread":      permAlerts, // Только с условием; просмотр спреда доступен всем
+	"notify":      permSettings,
+	"quiet":       permSettings,
+	"urgent":      permSettings,
+	"summary":     permSettings,
+	"subscribe":   permSettings,
+	"unsubscribe": permSettings,
+	"gaps":        permSettings,
+	"digest":      permSettings,
+}
+
+// isChatAdmin проверяет, является ли пользователь администратором чата.
+func (bs *BotService) isChatAdmin(chatID, userID int64) bool {
+	member, err := bs.bot.GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: chatID, UserID: userID},
+	})
+	if err != nil {
+		log.Printf("Ошибка проверки прав пользователя %d в чате %d: %v", userID, chatID, err)
+		return false
+	}
+	return member.IsCreator() || member.IsAdministrator()
+}
+
+// senderIsAdmin проверяет, отправлено ли сообщение администратором чата.
+// Анонимные администраторы пишут от имени самой группы.
+func (bs *BotService) senderIsAdmin(message *tgbotapi.Message) bool {
+	if message.SenderChat != nil && message.SenderChat.ID == message.Chat.ID {
+		return true
+	}
+	return message.From != nil && bs.isChatAdmin(message.Chat.ID, message.From.ID)
+}
+
+// authorize проверяет, может ли отправитель выполнить действие в группе,
+// и сообщает об отказе. В личных чатах разрешено всё.
+func (bs *BotService) authorize(message *tgbotapi.Message, perm permission) bool {
+	if !isGroupChat(message.Chat.ID) {
+		return true
+	}
+	settings, err := repository.GetChatSettings(message.Chat.ID)
+	if err != nil {
+		log.Printf("Ошибка получения настроек чата: %v", err)
+		return false
+	}
+	restricted := (perm == permAlerts && settings.AlertsAdminOnly) || (perm == permSettings && settings.SettingsAdminOnly)
+	if !restricted || bs.senderIsAdmin(message) {
+		return true
+	}
+	text := "В этой группе оповещения создают только администраторы."
+	if perm == permSettings {
+		text = "В этой группе настройки меняют только администраторы."
+	}
+	reply := tgbotapi.NewMessage(message.Chat.ID, text)
+	reply.ReplyToMessageID = message.MessageID
+	bs.bot.Send(reply)
+	return false
+}
+
+// mayManageTrigger проверяет, может ли пользователь нажимать кнопки под уведомлением:
+// в группе это создатель оповещения или администратор, а для повторного создания
+// при ограничении из /group - только администратор.
+func (bs *BotService) mayManageTrigger(chatID int64, user *tgbotapi.User, trigger repository.AlertTrigger, creates bool) bool {
+	if !isGroupChat(chatID) {
+		return true
+	}
+	if user == nil {
+		return false
+	}
+	if creates {
+		settings, err := repository.GetChatSettings(chatID)
+		if err != nil {
+			log.Printf("Ошибка получения настроек чата: %v", err)
+			return false
+		}
+		if settings.AlertsAdminOnly {
+			return bs.isChatAdmin(chatID, user.ID)
+		}
+	}
+	if trigger.UserID == 0 || trigger.UserID == user.ID {
+		return true
+	}
+	return bs.isChatAdmin(chatID, user.ID)
+}
+
+// addressedCommand сообщает, адресована ли команда этому боту. Команды вида
+// /alerts@other_bot в группе с несколькими ботами игнорируются.
+func (bs *BotService) addressedCommand(message *tgbotapi.Message) bool {
+	_, mention, found := strings.Cut(message.CommandWithAt(), "@")
+	return !found || strings.EqualFold(mention, bs.bot.Self.UserName)
+}
+
+// addressedText возвращает текст сообщения, если он адресован боту. В группе
+// бот реагирует только на сообщения, начинающиеся с @имя_бота, и на ответы
+// на свои сообщения, которые ждут ввода (см. bot_prompts) или начинаются с "/",
+// чтобы не разбирать как оповещения обсуждение уведомлений в ответах на них.
+func (bs *BotService) addressedText(message *tgbotapi.Message) (string, bool) {
+	if !isGroupChat(message.Chat.ID) {
+		return message.Text, true
+	}
+	mention := "@" + bs.bot.Self.UserName
+	if len(message.Text) >= len(mention) && strings.EqualFold(message.Text[:len(mention)], mention) {
+		return strings.TrimSpace(message.Text[len(mention):]), true
+	}
+	reply := message.ReplyToMessage
+	if reply == nil || reply.From == nil || reply.From.ID != bs.bot.Self.ID {
+		return "", false
+	}
+	if strings.HasPrefix(message.Text, "/") {
+		return message.Text, true
+	}
+	_, ok, err := repository.GetPrompt(message.Chat.ID, reply.MessageID)
+	if err != nil {
+		log.Printf("%v", err)
+	}
+	return message.Text, ok
+}
+
+// handleGroup обрабатывает /group - настройки группового режима (только для администраторов):
+//
+//	/group                    - текущие ограничения
+//	/group alerts admins|all  - кто может создавать оповещения
+//	/group settings admins|all - кто может менять настройки чата
+func (bs *BotService) handleGroup(message *tgbotapi.Message, args []string) {
+	chatID := message.Chat.ID
+	if !isGroupChat(chatID) {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Команда доступна только в группах."))
+		return
+	}
+	if !bs.senderIsAdmin(message) {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Настраивать групповой режим могут только администраторы."))
+		return
+	}
+	settings, err := repository.GetChatSettings(chatID)
+	if err != nil {
+		log.Printf("Ошибка получения настроек чата: %v", err)
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении настроек."))
+		return
+	}
+
+	const usage = "Формат: /group alerts admins|all, /group settings admins|all"
+	switch {
+	case len(args) == 0:
+	case len(args) == 2 && (args[0] == "alerts" || args[0] == "settings") && (args[1] == "admins" || args[1] == "all"):
+		if args[0] == "alerts" {
+			settings.AlertsAdminOnly = args[1] == "admins"
+		} else {
+			settings.SettingsAdminOnly = args[1] == "admins"
+		}
+		if err := repository.SaveChatSettings(settings); err != nil {
+			log.Printf("Ошибка сохранения настроек чата: %v", err)
+			bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при сохранении настроек."))
+			return
+		}
+	default:
+		bs.bot.Send(tgbotapi.NewMessage(chatID, usage))
+		return
+	}
+
+	who := func(adminOnly bool) string {
+		if adminOnly {
+			return "только администраторы"
+		}
+		return "все участники"
+	}
+	text := fmt.Sprintf("Создавать оповещения: %s.\nМенять настройки (/quiet, /notify, /urgent): %s.\n%s",
+		who(settings.AlertsAdminOnly), who(settings.SettingsAdminOnly), usage)
+	bs.bot.Send(tgbotapi.NewMessage(chatID, text))
+}
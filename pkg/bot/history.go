@@ -0,0 +1,78 @@
+// TradeTGBot/pkg/bot/history.go
+package bot
+
+import (
+	"TradeTGBot/internal/repository"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log"
+	"strconv"
+	"strings"
+)
+
+const historyPageSize = 10
+
+// deliveryStatusNames - человекочитаемые статусы доставки уведомлений.
+var deliveryStatusNames = map[string]string{
+	repository.NotificationPending:   "⏳ в очереди",
+	repository.NotificationDelivered: "✅ доставлено",
+	repository.NotificationDead:      "❌ не доставлено",
+}
+
+// showHistory обрабатывает /history [ТИКЕР] [СТРАНИЦА]: выводит срабатывания
+// оповещений чата постранично, новые первыми.
+func (bs *BotService) showHistory(chatID int64, args []string) {
+	ticker, page := "", 1
+	for _, arg := range args {
+		if n, err := strconv.Atoi(arg); err == nil {
+			page = n
+			continue
+		}
+		ticker = strings.ToUpper(arg)
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	history, total, err := repository.GetAlertHistory(chatID, ticker, historyPageSize, (page-1)*historyPageSize)
+	if err != nil {
+		log.Printf("Ошибка получения истории оповещений: %v", err)
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении истории оповещений."))
+		return
+	}
+	if total == 0 {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Сработавших оповещений пока нет."))
+		return
+	}
+	pages := (total + historyPageSize - 1) / historyPageSize
+	if len(history) == 0 {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Страница %d не существует, всего страниц: %d.", page, pages)))
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("История срабатываний (страница %d из %d", page, pages))
+	if ticker != "" {
+		sb.WriteString(", тикер " + ticker)
+	}
+	sb.WriteString("):\n")
+	for _, t := range history {
+		status, ok := deliveryStatusNames[t.DeliveryStatus]
+		if !ok {
+			status = "статус неизвестен"
+		}
+		sb.WriteString(fmt.Sprintf("%s #%d %s", t.TriggeredAt.Local().Format("02.01.2006 15:04"), t.AlertID, t.Definition))
+		if t.TriggerPrice != 0 {
+			sb.WriteString(fmt.Sprintf(", цена %.2f", t.TriggerPrice))
+		}
+		sb.WriteString(" — " + status + "\n")
+	}
+	if page < pages {
+		next := fmt.Sprintf("/history %d", page+1)
+		if ticker != "" {
+			next = fmt.Sprintf("/history %s %d", ticker, page+1)
+		}
+		sb.WriteString("Следующая страница: " + next)
+	}
+	bs.bot.Send(tgbotapi.NewMessage(chatID, sb.String()))
+}
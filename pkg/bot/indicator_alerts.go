@@ -0,0 +1,396 @@
+// TradeTGBot/pkg/bot/indicator_alerts.go
+package bot
+
+import (
+	"TradeTGBot/internal/indicators"
+	"TradeTGBot/internal/repository"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Виды индикаторных оповещений.
+const (
+	indicatorSMA       = "sma"   // Цена пересекает SMA
+	indicatorEMA       = "ema"   // Цена пересекает EMA
+	indicatorCross     = "cross" // Быстрая MA пересекает медленную
+	indicatorRSI       = "rsi"   // RSI входит в зону перекупленности/перепроданности
+	indicatorBollinger = "bb"    // Закрытие за пределами полос Боллинджера
+)
+
+// timeframes - допустимые таймфреймы для расчёта индикаторов.
+var timeframes = map[string]time.Duration{
+	"1m":  time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"1h":  time.Hour,
+	"4h":  4 * time.Hour,
+	"1d":  24 * time.Hour,
+}
+
+const defaultTimeframe = "5m"
+
+// IndicatorSpec описывает индикаторное оповещение.
+type IndicatorSpec struct {
+	Kind       string
+	MA         string  // Тип средних для cross: sma или ema
+	Period     int     // Период индикатора (для cross - быстрая MA)
+	SlowPeriod int     // Период медленной MA для cross
+	Low, High  float64 // Уровни перепроданности/перекупленности для RSI
+	K          float64 // Ширина полос Боллинджера в стандартных отклонениях
+	Timeframe  string
+}
+
+// String возвращает краткое описание индикатора, например "SMA(20) 5m".
+func (s IndicatorSpec) String() string {
+	switch s.Kind {
+	case indicatorCross:
+		return fmt.Sprintf("%s(%d)/%s(%d) %s", strings.ToUpper(s.MA), s.Period, strings.ToUpper(s.MA), s.SlowPeriod, s.Timeframe)
+	case indicatorRSI:
+		return fmt.Sprintf("RSI(%d) %.0f/%.0f %s", s.Period, s.Low, s.High, s.Timeframe)
+	case indicatorBollinger:
+		return fmt.Sprintf("BB(%d, %.1f) %s", s.Period, s.K, s.Timeframe)
+	}
+	return fmt.Sprintf("%s(%d) %s", strings.ToUpper(s.Kind), s.Period, s.Timeframe)
+}
+
+// parseIndicatorSpec разбирает аргументы команды /indicator:
+//
+//	TICKER sma|ema N [TF]          - цена пересекает скользящую среднюю
+//	TICKER cross sma|ema FAST SLOW [TF] - пересечение быстрой и медленной MA
+//	TICKER rsi N [LOW HIGH] [TF]   - RSI входит в зону ниже LOW или выше HIGH (по умолчанию 30/70)
+//	TICKER bb N [K] [TF]           - закрытие за пределами полос Боллинджера (по умолчанию K=2)
+func parseIndicatorSpec(args []string) (string, IndicatorSpec, error) {
+	if len(args) < 3 {
+		return "", IndicatorSpec{}, fmt.Errorf("слишком мало аргументов")
+	}
+	ticker := strings.ToUpper(args[0])
+	spec := IndicatorSpec{Kind: strings.ToLower(args[1]), Timeframe: defaultTimeframe}
+	rest := args[2:]
+
+	// Таймфрейм, если указан, всегда идёт последним аргументом.
+	if tf := strings.ToLower(rest[len(rest)-1]); len(rest) > 1 {
+		if _, ok := timeframes[tf]; ok {
+			spec.Timeframe = tf
+			rest = rest[:len(rest)-1]
+		}
+	}
+
+	numbers := func(min, max int) ([]float64, error) {
+		if len(rest) < min || len(rest) > max {
+			return nil, fmt.Errorf("неверное число параметров для %s", spec.Kind)
+		}
+		values := make([]float64, len(rest))
+		for i, arg := range rest {
+			v, err := strconv.ParseFloat(arg, 64)
+			if err != nil || v <= 0 {
+				return nil, fmt.Errorf("неверный параметр %q", arg)
+			}
+			values[i] = v
+		}
+		return values, nil
+	}
+
+	switch spec.Kind {
+	case indicatorSMA, indicatorEMA:
+		values, err := numbers(1, 1)
+		if err != nil {
+			return "", spec, err
+		}
+		spec.Period = int(values[0])
+	case indicatorCross:
+		if len(rest) == 0 {
+			return "", spec, fmt.Errorf("укажите тип средних: sma или ema")
+		}
+		spec.MA = strings.ToLower(rest[0])
+		if spec.MA != indicatorSMA && spec.MA != indicatorEMA {
+			return "", spec, fmt.Errorf("неизвестный тип средних %q, ожидается sma или ema", rest[0])
+		}
+		rest = rest[1:]
+		values, err := numbers(2, 2)
+		if err != nil {
+			return "", spec, err
+		}
+		spec.Period, spec.SlowPeriod = int(values[0]), int(values[1])
+		if spec.Period >= spec.SlowPeriod {
+			return "", spec, fmt.Errorf("период быстрой средней должен быть меньше периода медленной")
+		}
+	case indicatorRSI:
+		values, err := numbers(1, 3)
+		if err != nil {
+			return "", spec, err
+		}
+		if len(values) == 2 {
+			return "", spec, fmt.Errorf("укажите оба уровня RSI: нижний и верхний")
+		}
+		spec.Period, spec.Low, spec.High = int(values[0]), 30, 70
+		if len(values) == 3 {
+			spec.Low, spec.High = values[1], values[2]
+		}
+		if spec.Low >= spec.High || spec.High >= 100 {
+			return "", spec, fmt.Errorf("уровни RSI должны удовлетворять 0 < LOW < HIGH < 100")
+		}
+	case indicatorBollinger:
+		values, err := numbers(1, 2)
+		if err != nil {
+			return "", spec, err
+		}
+		spec.Period, spec.K = int(values[0]), 2
+		if len(values) == 2 {
+			spec.K = values[1]
+		}
+	default:
+		return "", spec, fmt.Errorf("неизвестный индикатор %q", args[1])
+	}
+	if spec.Period < 2 {
+		return "", spec, fmt.Errorf("период должен быть не меньше 2")
+	}
+	return ticker, spec, nil
+}
+
+// bars возвращает число интервалов истории, необходимых для расчёта индикатора.
+func (s IndicatorSpec) bars() int {
+	switch s.Kind {
+	case indicatorEMA:
+		return 3 * s.Period
+	case indicatorCross:
+		if s.MA == indicatorEMA {
+			return 3 * s.SlowPeriod
+		}
+		return s.SlowPeriod
+	case indicatorRSI:
+		return 3*s.Period + 1
+	}
+	return s.Period
+}
+
+// indicatorReading - результат расчёта индикатора на текущих данных.
+type indicatorReading struct {
+	State  int    // Зона/положение: для пересечений ±1, для RSI и BB 0 - нейтральная зона
+	Values string // Значения индикатора для текста уведомления
+}
+
+// evaluate рассчитывает индикатор по ценам закрытия.
+func (s IndicatorSpec) evaluate(closes []float64) (indicatorReading, error) {
+	last := closes[len(closes)-1]
+	movingAverage := func(ma string, period int) (float64, error) {
+		if ma == indicatorEMA {
+			return indicators.EMA(closes, period)
+		}
+		return indicators.SMA(closes, period)
+	}
+
+	switch s.Kind {
+	case indicatorSMA, indicatorEMA:
+		ma, err := movingAverage(s.Kind, s.Period)
+		if err != nil {
+			return indicatorReading{}, err
+		}
+		return indicatorReading{
+			State:  sign(last - ma),
+			Values: fmt.Sprintf("цена %.2f, %s(%d) %.2f", last, strings.ToUpper(s.Kind), s.Period, ma),
+		}, nil
+	case indicatorCross:
+		fast, err := movingAverage(s.MA, s.Period)
+		if err != nil {
+			return indicatorReading{}, err
+		}
+		slow, err := movingAverage(s.MA, s.SlowPeriod)
+		if err != nil {
+			return indicatorReading{}, err
+		}
+		name := strings.ToUpper(s.MA)
+		return indicatorReading{
+			State:  sign(fast - slow),
+			Values: fmt.Sprintf("%s(%d) %.2f, %s(%d) %.2f, цена %.2f", name, s.Period, fast, name, s.SlowPeriod, slow, last),
+		}, nil
+	case indicatorRSI:
+		rsi, err := indicators.RSI(closes, s.Period)
+		if err != nil {
+			return indicatorReading{}, err
+		}
+		state := 0
+		if rsi >= s.High {
+			state = 1
+		} else if rsi <= s.Low {
+			state = -1
+		}
+		return indicatorReading{State: state, Values: fmt.Sprintf("RSI(%d) %.1f, цена %.2f", s.Period, rsi, last)}, nil
+	case indicatorBollinger:
+		middle, upper, lower, err := indicators.Bollinger(closes, s.Period, s.K)
+		if err != nil {
+			return indicatorReading{}, err
+		}
+		state := 0
+		if last > upper {
+			state = 1
+		} else if last < lower {
+			state = -1
+		}
+		return indicatorReading{
+			State:  state,
+			Values: fmt.Sprintf("цена %.2f, BB(%d, %.1f): верхняя %.2f, средняя %.2f, нижняя %.2f", last, s.Period, s.K, upper, middle, lower),
+		}, nil
+	}
+	return indicatorReading{}, fmt.Errorf("неизвестный индикатор %q", s.Kind)
+}
+
+// event описывает смену состояния индикатора для текста уведомления.
+func (s IndicatorSpec) event(state int) string {
+	switch s.Kind {
+	case indicatorSMA, indicatorEMA:
+		if state > 0 {
+			return fmt.Sprintf("цена пересекла %s(%d) снизу вверх", strings.ToUpper(s.Kind), s.Period)
+		}
+		return fmt.Sprintf("цена пересекла %s(%d) сверху вниз", strings.ToUpper(s.Kind), s.Period)
+	case indicatorCross:
+		if state > 0 {
+			return "быстрая средняя пересекла медленную снизу вверх"
+		}
+		return "быстрая средняя пересекла медленную сверху вниз"
+	case indicatorRSI:
+		if state > 0 {
+			return fmt.Sprintf("RSI вошёл в зону перекупленности (≥ %.0f)", s.High)
+		}
+		return fmt.Sprintf("RSI вошёл в зону перепроданности (≤ %.0f)", s.Low)
+	case indicatorBollinger:
+		if state > 0 {
+			return "закрытие выше верхней полосы Боллинджера"
+		}
+		return "закрытие ниже нижней полосы Боллинджера"
+	}
+	return "сигнал индикатора"
+}
+
+// closesKey - тикер и длительность бара, по которым кэшируются цены закрытия.
+type closesKey struct {
+	ticker string
+	bar    time.Duration
+}
+
+// closesCacheEntry - цены закрытия баров, закончившихся до Start.
+type closesCacheEntry struct {
+	Start  time.Time // Начало текущего (незакрытого) бара
+	Want   int       // Сколько баров запрашивалось; в истории их может быть меньше
+	Closes []float64
+}
+
+// closesCache хранит цены закрытия закрытых баров. Они меняются только на границе
+// бара, поэтому БД читается раз за бар на тикер и таймфрейм, а не на каждом тике
+// каждого индикаторного оповещения.
+var closesCache = struct {
+	sync.Mutex
+	entries map[closesKey]closesCacheEntry
+}{entries: make(map[closesKey]closesCacheEntry)}
+
+// closedCloses возвращает цены закрытия не больше n последних баров тикера,
+// закончившихся до начала текущего бара.
+func closedCloses(ticker string, bar time.Duration, n int, now time.Time) ([]float64, error) {
+	key := closesKey{ticker: ticker, bar: bar}
+	start := now.Truncate(bar)
+	closesCache.Lock()
+	entry, ok := closesCache.entries[key]
+	closesCache.Unlock()
+	if !ok || !entry.Start.Equal(start) || entry.Want < n {
+		candles, err := repository.GetLastCandles(ticker, bar, n, start)
+		if err != nil {
+			return nil, err
+		}
+		entry = closesCacheEntry{Start: start, Want: n, Closes: indicators.CandleCloses(candles)}
+		closesCache.Lock()
+		closesCache.entries[key] = entry
+		closesCache.Unlock()
+	}
+	closes := entry.Closes
+	if len(closes) > n {
+		closes = closes[len(closes)-n:]
+	}
+	return closes, nil
+}
+
+// loadCloses возвращает цены закрытия последних bars()+1 закрытых баров тикера,
+// дополненные текущей ценой price как закрытием ещё не закрытого бара. Бары отбираются
+// по числу, как при прогоне истории в бэктесте, поэтому ночи и выходные не уменьшают историю.
+func (s IndicatorSpec) loadCloses(ticker string, price float64) ([]float64, error) {
+	closed, err := closedCloses(ticker, timeframes[s.Timeframe], s.bars()+1, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	// Копия, чтобы не дописывать в срез из кэша.
+	closes := make([]float64, len(closed), len(closed)+1)
+	copy(closes, closed)
+	return append(closes, price), nil
+}
+
+// checkIndicatorAlert рассчитывает индикатор по сохранённой истории и формирует
+// уведомление, если индикатор перешёл в новое состояние. Первое вычисление
+// только запоминает исходное состояние. Возвращает текст уведомления и true, если оповещение сработало.
+func checkIndicatorAlert(alert *Alert, quotes quoteSource) (string, bool) {
+	// Текущая котировка служит закрытием незакрытого бара.
+	stock, err := quotes.Get(alert.Ticker)
+	if err != nil {
+		log.Printf("Ошибка проверки индикаторного оповещения для %s: %v", alert.Ticker, err)
+		return "", false
+	}
+	closes, err := alert.Indicator.loadCloses(alert.Ticker, stock.Price)
+	if err != nil {
+		log.Printf("Ошибка проверки индикаторного оповещения для %s: %v", alert.Ticker, err)
+		return "", false
+	}
+	if len(closes) < alert.Indicator.bars() && !alert.dataWarned {
+		// Один раз сообщаем, что оповещение пока не может быть рассчитано, а не молчим.
+		text := fmt.Sprintf("⏳ Оповещение #%d (%s: %s) пока не рассчитывается: в истории %d из %d нужных интервалов. "+
+			"Оповещение остаётся активным и заработает, когда данных станет достаточно.",
+			alert.ID, alert.Ticker, alert.Indicator, len(closes), alert.Indicator.bars())
+		if _, err := repository.EnqueueNotification(repository.Notification{ChatID: alert.ChatID, Text: text}); err != nil {
+			log.Printf("Ошибка при постановке предупреждения по оповещению #%d в очередь: %v", alert.ID, err)
+		} else {
+			alert.dataWarned = true
+			if alert.ID != 0 {
+				if err := repository.MarkAlertDataWarned(alert.ID); err != nil {
+					log.Printf("Ошибка сохранения состояния оповещения #%d: %v", alert.ID, err)
+				}
+			}
+		}
+	}
+	return indicatorSignal(alert, stock.Name, closes)
+}
+
+// indicatorSignal рассчитывает индикатор по ценам закрытия и формирует уведомление
+// о переходе в новое состояние. Состояние обновляется в alert.
+func indicatorSignal(alert *Alert, name string, closes []float64) (string, bool) {
+	if len(closes) < alert.Indicator.bars() {
+		// Недостаточно истории: ждём накопления данных.
+		return "", false
+	}
+	reading, err := alert.Indicator.evaluate(closes)
+	if err != nil {
+		log.Printf("Ошибка проверки индикаторного оповещения для %s: %v", alert.Ticker, err)
+		return "", false
+	}
+
+	prev, known := alert.indicatorState, alert.stateKnown
+	if reading.State != 0 || alert.Indicator.Kind == indicatorRSI || alert.Indicator.Kind == indicatorBollinger {
+		alert.indicatorState, alert.stateKnown = reading.State, true
+	}
+	if !known || reading.State == 0 || reading.State == prev {
+		return "", false
+	}
+
+	msgText := fmt.Sprintf("📈 %s [%s]: %s.\n%s",
+		name, alert.Indicator, alert.Indicator.event(reading.State), reading.Values)
+	return msgText, true
+}
+
+func sign(x float64) int {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	}
+	return 0
+}
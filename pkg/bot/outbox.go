@@ -0,0 +1,268 @@
+// TradeTGBot/pkg/bot/outbox.go
+package bot
+
+import (
+	"TradeTGBot/internal/notify"
+	"TradeTGBot/internal/repository"
+	"fmt"
+	"html"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Параметры доставки уведомлений из outbox.
+const (
+	outboxPollInterval = 5 * time.Second  // Как часто проверять очередь
+	outboxBatchSize    = 50               // Сколько уведомлений обрабатывать за проход
+	outboxMaxAttempts  = 8                // После стольких неудач уведомление уходит в dead
+	outboxBaseBackoff  = 10 * time.Second // Задержка после первой неудачи, далее удваивается
+	outboxMaxBackoff   = 30 * time.Minute
+	telegramTextLimit  = 4096 // Максимальная длина текста сообщения Telegram
+)
+
+// triggerAlert записывает уведомление о срабатывании в outbox. Оповещение
+// переводится в статус triggered и удаляется только после успешной доставки.
+// Вместе с уведомлением в историю пишется срабатывание с ценой из кэша цикла.
+// Возвращает false, если записать не удалось - тогда оповещение остаётся активным
+// и будет проверено снова в следующем цикле.
+func (bs *BotService) triggerAlert(alert Alert, text string, quotes quoteSource) bool {
+	record, err := alert.toRecord()
+	if err != nil {
+		log.Printf("Ошибка подготовки записи истории для оповещения %d: %v", alert.ID, err)
+		return false
+	}
+	trigger := repository.AlertTrigger{
+		AlertID:    alert.ID,
+		ChatID:     alert.ChatID,
+		Ticker:     alert.Ticker,
+		Tickers:    alert.indexTickers(),
+		Kind:       record.Kind,
+		Target:     alert.Target,
+		Direction:  alert.Direction,
+		Definition: alert.describe(),
+		Params:     record.Params,
+		UserID:     alert.Owner.ID,
+		UserName:   alert.Owner.Name,
+		Urgent:     alert.Urgent,
+	}
+	if alert.Ticker != "" {
+		if stock, err := quotes.Get(alert.Ticker); err == nil {
+			trigger.TriggerPrice = stock.Price
+		}
+	}
+
+	n := repository.Notification{
+		ChatID:  alert.ChatID,
+		AlertID: alert.ID,
+		Text:    text,
+		Urgent:  alert.Urgent,
+	}
+	if isGroupChat(alert.ChatID) && alert.Owner.Name != "" {
+		// В группе упоминаем создателя, чтобы было понятно, чьё оповещение сработало.
+		// Без @username упоминание - ссылка на пользователя, для неё нужна разметка HTML.
+		if strings.HasPrefix(alert.Owner.Name, "@") {
+			n.Text += "\n👤 " + alert.Owner.Name
+		} else {
+			n.Text = html.EscapeString(text) + "\n👤 " + alert.Owner.mentionHTML()
+			n.ParseMode = "HTML"
+		}
+	}
+
+	_, err = repository.TriggerAlert(n, trigger)
+	if err != nil {
+		log.Printf("Ошибка записи уведомления об оповещении %d в outbox: %v", alert.ID, err)
+		return false
+	}
+	return true
+}
+
+// deliverOutbox периодически отправляет ожидающие уведомления из outbox.
+// Уведомление отмечается доставленным только после успешного Send; при ошибке
+// попытка повторяется с экспоненциальной задержкой, после outboxMaxAttempts
+// неудач уведомление переводится в dead. В тихие часы чата несрочные
+// уведомления в сам чат и его темы откладываются до их окончания; накопившиеся
+// за проход уведомления чата или темы приходят одним сообщением (см. deliverDigest).
+func (bs *BotService) deliverOutbox() {
+	for {
+		notifications, err := repository.GetDueNotifications(outboxBatchSize)
+		if err != nil {
+			log.Printf("Ошибка чтения outbox: %v", err)
+		}
+
+		// Группируем по чатам, сохраняя порядок появления в очереди.
+		var chats []int64
+		byChat := make(map[int64][]repository.Notification)
+		for _, n := range notifications {
+			if _, ok := byChat[n.ChatID]; !ok {
+				chats = append(chats, n.ChatID)
+			}
+			byChat[n.ChatID] = append(byChat[n.ChatID], n)
+		}
+		for _, chatID := range chats {
+			bs.deliverChat(chatID, byChat[chatID])
+		}
+		time.Sleep(outboxPollInterval)
+	}
+}
+
+// deliverChat доставляет уведомления одного чата с учётом его тихих часов.
+func (bs *BotService) deliverChat(chatID int64, notifications []repository.Notification) {
+	settings, err := repository.GetChatSettings(chatID)
+	if err != nil {
+		// Без настроек доставляем как обычно: лучше разбудить, чем потерять.
+		log.Printf("Ошибка получения настроек чата %d: %v", chatID, err)
+	}
+
+	if end, quiet := quietUntil(settings, time.Now()); quiet {
+		// Тихие часы касаются сообщений в сам чат и его темы (их получают те же
+		// участники); каналы, email и вебхуки никого не будят и доставляются сразу.
+		for _, n := range notifications {
+			if n.Urgent || !inChat(n) {
+				bs.deliverNotification(n)
+				continue
+			}
+			if err := repository.DeferNotification(n.ID, end); err != nil {
+				log.Printf("Ошибка откладывания уведомления %d: %v", n.ID, err)
+			}
+		}
+		return
+	}
+
+	// Всё, что за проход накопилось для самого чата или одной его темы, уходит
+	// одним сообщением, чтобы пачка срабатываний не упиралась в ограничения
+	// Telegram на частоту.
+	var groups []int // Маршруты тем в порядке появления; 0 - сам чат
+	digests := make(map[int][]repository.Notification)
+	for _, n := range notifications {
+		if !inChat(n) || (n.ParseMode != "" && n.ParseMode != "HTML") {
+			bs.deliverNotification(n)
+			continue
+		}
+		key := 0
+		if n.Channel == repository.ChannelTopic {
+			key = n.RouteID
+		}
+		if _, ok := digests[key]; !ok {
+			groups = append(groups, key)
+		}
+		digests[key] = append(digests[key], n)
+	}
+	for _, key := range groups {
+		if digest := digests[key]; len(digest) == 1 {
+			bs.deliverNotification(digest[0])
+		} else {
+			bs.deliverDigest(digest, settings.SummaryThreshold)
+		}
+	}
+}
+
+// inChat сообщает, что уведомление доставляется в сам чат или в тему его форума.
+func inChat(n repository.Notification) bool {
+	return n.Channel == repository.ChannelTelegram || n.Channel == repository.ChannelTopic
+}
+
+// deliverNotification выполняет одну попытку доставки уведомления по его каналу.
+func (bs *BotService) deliverNotification(n repository.Notification) {
+	notifier, ok := bs.notifiers[n.Channel]
+	if !ok {
+		bs.markFailed(n, fmt.Errorf("канал доставки %q не настроен", n.Channel))
+		return
+	}
+	dest, ok := bs.destination(n)
+	if !ok {
+		return
+	}
+
+	msg := notify.Message{
+		NotificationID: n.ID,
+		ChatID:         n.ChatID,
+		AlertID:        n.AlertID,
+		Text:           n.Text,
+		ParseMode:      n.ParseMode,
+		Urgent:         n.Urgent,
+		CreatedAt:      n.CreatedAt,
+	}
+	if n.AlertID != 0 && (n.Channel == repository.ChannelTelegram || n.Channel == repository.ChannelTopic) {
+		// Кнопки «Отложить», «Принято», «Ещё раз» и «Новый уровень» для сработавшего оповещения.
+		triggers, err := repository.GetAlertTriggersByNotification(n.ID)
+		if err != nil {
+			log.Printf("Ошибка получения срабатываний для уведомления %d: %v", n.ID, err)
+		} else if keyboard := alertKeyboard(triggers); keyboard != nil {
+			msg.ReplyMarkup = keyboard
+		}
+	}
+	if err := notifier.Send(dest, msg); err != nil {
+		bs.markFailed(n, err)
+		return
+	}
+	if err := repository.MarkNotificationDelivered(n); err != nil {
+		// Сообщение уже отправлено; при повторе возможен дубль, но не потеря.
+		log.Printf("Ошибка отметки доставки уведомления %d: %v", n.ID, err)
+	}
+}
+
+// destination возвращает адрес доставки уведомления по его маршруту. Если адрес
+// получить не удалось, неудача уже записана в outbox и ok равно false.
+func (bs *BotService) destination(n repository.Notification) (notify.Destination, bool) {
+	dest := notify.Destination{ChatID: n.ChatID}
+	if n.RouteID == 0 {
+		return dest, true
+	}
+	route, ok, err := repository.GetNotificationRoute(n.RouteID)
+	if err != nil {
+		bs.markFailed(n, err)
+		return dest, false
+	}
+	if !ok {
+		// Пользователь удалил маршрут - доставлять больше некуда.
+		bs.markDead(n, "маршрут удалён")
+		return dest, false
+	}
+	if err := route.Validate(); err != nil {
+		// Повтор не поможет: адрес не станет верным сам по себе.
+		bs.markDead(n, err.Error())
+		return dest, false
+	}
+	switch route.Channel {
+	case repository.ChannelTopic:
+		dest.ThreadID, _ = strconv.Atoi(route.Target) // Проверено в Validate
+	case repository.ChannelPublish:
+		dest.ChatID, _ = strconv.ParseInt(route.Target, 10, 64)
+	default:
+		dest.Target, dest.Secret = route.Target, route.Secret
+	}
+	return dest, true
+}
+
+// markDead переводит уведомление в dead без повторных попыток.
+func (bs *BotService) markDead(n repository.Notification, reason string) {
+	log.Printf("Уведомление %d для чата %d не будет доставлено: %s", n.ID, n.ChatID, reason)
+	if err := repository.MarkNotificationFailed(n.ID, reason, time.Now(), true); err != nil {
+		log.Printf("Ошибка обновления outbox: %v", err)
+	}
+}
+
+// markFailed записывает неудачную попытку доставки и планирует следующую.
+func (bs *BotService) markFailed(n repository.Notification, sendErr error) {
+	attempts := n.Attempts + 1
+	dead := attempts >= outboxMaxAttempts
+	if dead {
+		log.Printf("Уведомление %d для чата %d не доставлено после %d попыток: %v", n.ID, n.ChatID, attempts, sendErr)
+	} else {
+		log.Printf("Ошибка доставки уведомления %d (попытка %d): %v", n.ID, attempts, sendErr)
+	}
+	if err := repository.MarkNotificationFailed(n.ID, sendErr.Error(), time.Now().Add(outboxBackoff(attempts)), dead); err != nil {
+		log.Printf("Ошибка обновления outbox: %v", err)
+	}
+}
+
+// outboxBackoff возвращает задержку перед следующей попыткой после attempts неудач.
+func outboxBackoff(attempts int) time.Duration {
+	delay := outboxBaseBackoff
+	for i := 1; i < attempts && delay < outboxMaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, outboxMaxBackoff)
+}
@@ -0,0 +1,158 @@
+// TradeTGBot/pkg/bot/quiet.go
+package bot
+
+import (
+	"TradeTGBot/internal/repository"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// quietUntil сообщает, действуют ли сейчас тихие часы чата, и если да -
+// возвращает момент их окончания. Окно может переходить через полночь (23:00-08:00).
+func quietUntil(s repository.ChatSettings, now time.Time) (time.Time, bool) {
+	if !s.QuietEnabled || s.QuietStart == s.QuietEnd {
+		return time.Time{}, false
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	minute := local.Hour()*60 + local.Minute()
+
+	var inQuiet bool
+	if s.QuietStart < s.QuietEnd {
+		inQuiet = minute >= s.QuietStart && minute < s.QuietEnd
+	} else {
+		inQuiet = minute >= s.QuietStart || minute < s.QuietEnd
+	}
+	if !inQuiet {
+		return time.Time{}, false
+	}
+
+	end := midnight.Add(time.Duration(s.QuietEnd) * time.Minute)
+	if !end.After(local) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end, true
+}
+
+// parseClock разбирает время суток "23:00" в минуты от полуночи.
+func parseClock(s string) (int, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("неверное время %q, ожидается ЧЧ:ММ", s)
+	}
+	h, errH := strconv.Atoi(parts[0])
+	m, errM := strconv.Atoi(parts[1])
+	if errH != nil || errM != nil || h < 0 || h > 23 || m < 0 || m > 59 {
+		return 0, fmt.Errorf("неверное время %q, ожидается ЧЧ:ММ", s)
+	}
+	return h*60 + m, nil
+}
+
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// handleQuiet обрабатывает /quiet:
+//
+//	/quiet                            - текущие настройки
+//	/quiet 23:00-08:00 [Europe/Moscow] - включить тихие часы (и задать часовой пояс)
+//	/quiet off                        - выключить тихие часы
+func (bs *BotService) handleQuiet(chatID int64, args []string) {
+	settings, err := repository.GetChatSettings(chatID)
+	if err != nil {
+		log.Printf("Ошибка получения настроек чата: %v", err)
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении настроек."))
+		return
+	}
+
+	if len(args) == 0 {
+		text := fmt.Sprintf("Тихие часы выключены. Часовой пояс: %s.", settings.Timezone)
+		if settings.QuietEnabled {
+			text = fmt.Sprintf("Тихие часы: %s-%s (%s). Несрочные уведомления в это время копятся и приходят одним сообщением по окончании.",
+				formatClock(settings.QuietStart), formatClock(settings.QuietEnd), settings.Timezone)
+		}
+		bs.bot.Send(tgbotapi.NewMessage(chatID, text+"\nФормат: /quiet 23:00-08:00 [Europe/Moscow] или /quiet off. "+
+			"Срочные оповещения (/urgent ID) приходят всегда."))
+		return
+	}
+
+	if strings.EqualFold(args[0], "off") {
+		settings.QuietEnabled = false
+	} else {
+		bounds := strings.SplitN(args[0], "-", 2)
+		if len(bounds) != 2 {
+			bs.bot.Send(tgbotapi.NewMessage(chatID, "Формат: /quiet 23:00-08:00 [Europe/Moscow] или /quiet off"))
+			return
+		}
+		start, err := parseClock(bounds[0])
+		if err == nil {
+			settings.QuietEnd, err = parseClock(bounds[1])
+		}
+		if err != nil {
+			bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Ошибка: %v.", err)))
+			return
+		}
+		if start == settings.QuietEnd {
+			bs.bot.Send(tgbotapi.NewMessage(chatID, "Начало и конец тихих часов должны различаться."))
+			return
+		}
+		settings.QuietStart, settings.QuietEnabled = start, true
+		if len(args) > 1 {
+			if _, err := time.LoadLocation(args[1]); err != nil {
+				bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Неизвестный часовой пояс %q, пример: Europe/Moscow.", args[1])))
+				return
+			}
+			settings.Timezone = args[1]
+		}
+	}
+
+	if err := repository.SaveChatSettings(settings); err != nil {
+		log.Printf("Ошибка сохранения настроек чата: %v", err)
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при сохранении настроек."))
+		return
+	}
+	if !settings.QuietEnabled {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Тихие часы выключены."))
+		return
+	}
+	bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Тихие часы установлены: %s-%s (%s).",
+		formatClock(settings.QuietStart), formatClock(settings.QuietEnd), settings.Timezone)))
+}
+
+// handleUrgent обрабатывает /urgent ID: переключает срочность оповещения.
+// Срочные оповещения доставляются и в тихие часы.
+func (bs *BotService) handleUrgent(chatID int64, args []string) {
+	if len(args) != 1 {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Формат: /urgent ID (номер из /alerts)"))
+		return
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+	if err != nil {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Неверный номер оповещения."))
+		return
+	}
+	alert, ok := bs.alerts.Get(id)
+	if !ok || alert.ChatID != chatID {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Оповещение #%d не найдено.", id)))
+		return
+	}
+	if err := repository.SetAlertUrgent(id, !alert.Urgent); err != nil {
+		log.Printf("Ошибка обновления срочности оповещения: %v", err)
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при сохранении оповещения."))
+		return
+	}
+	bs.alerts.SetUrgent(id, !alert.Urgent)
+	if alert.Urgent {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Оповещение #%d больше не срочное.", id)))
+		return
+	}
+	bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Оповещение #%d помечено срочным: оно придёт и в тихие часы.", id)))
+}
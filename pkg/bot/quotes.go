@@ -0,0 +1,46 @@
+// TradeTGBot/pkg/bot/quotes.go
+package bot
+
+import (
+	"TradeTGBot/internal/pricebus"
+	"TradeTGBot/pkg/stocks"
+	"fmt"
+	"time"
+)
+
+// quoteMaxAge - насколько старую котировку из шины можно отдать пользователю
+// вместо нового запроса к сайту.
+const quoteMaxAge = 15 * time.Second
+
+// pollerQuotes получает котировки через общий поллер: свежий тик берётся из шины,
+// устаревший запрашивается заново и публикуется всем подписчикам.
+type pollerQuotes struct {
+	poller *pricebus.Poller
+}
+
+// Get возвращает котировку тикера.
+func (pq pollerQuotes) Get(ticker string) (stocks.StockData, error) {
+	t, err := pq.poller.Quote(ticker, quoteMaxAge)
+	if err != nil {
+		return stocks.StockData{}, err
+	}
+	return stocks.StockData{Name: t.Name, Price: t.Price}, nil
+}
+
+// Prices возвращает цены для набора тикеров в виде map, пригодной для alertexpr.Quotes.
+func (pq pollerQuotes) Prices(tickers []string) (map[string]float64, error) {
+	prices := make(map[string]float64, len(tickers))
+	for _, ticker := range tickers {
+		data, err := pq.Get(ticker)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ticker, err)
+		}
+		prices[ticker] = data.Price
+	}
+	return prices, nil
+}
+
+// quotes возвращает источник котировок для обработчиков команд.
+func (bs *BotService) quotes() quoteSource {
+	return pollerQuotes{poller: bs.poller}
+}
@@ -0,0 +1,180 @@
+// TradeTGBot/pkg/bot/range_alerts.go
+package bot
+
+import (
+	"TradeTGBot/internal/repository"
+	"TradeTGBot/pkg/stocks"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Режимы оповещения по диапазону.
+const (
+	rangeOutside = "outside" // Цена выходит из диапазона
+	rangeInside  = "inside"  // Цена входит в диапазон
+)
+
+// RangeSpec описывает оповещение по ценовому коридору [Low, High].
+type RangeSpec struct {
+	Mode string  `json:"mode"`
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+}
+
+// String возвращает описание коридора, например "выход из 300.00–320.00".
+func (s RangeSpec) String() string {
+	if s.Mode == rangeInside {
+		return fmt.Sprintf("вход в %.2f–%.2f", s.Low, s.High)
+	}
+	return fmt.Sprintf("выход из %.2f–%.2f", s.Low, s.High)
+}
+
+// contains сообщает, находится ли цена внутри коридора (границы включительно).
+func (s RangeSpec) contains(price float64) bool {
+	return price >= s.Low && price <= s.High
+}
+
+// parseRangeSpec разбирает режим и границы: "outside 300-320" или "inside 300 320".
+func parseRangeSpec(args []string) (RangeSpec, error) {
+	if len(args) == 2 && strings.Contains(args[1], "-") {
+		args = append(args[:1:1], strings.SplitN(args[1], "-", 2)...)
+	}
+	if len(args) != 3 {
+		return RangeSpec{}, fmt.Errorf("укажите режим и границы коридора")
+	}
+	spec := RangeSpec{Mode: strings.ToLower(args[0])}
+	if spec.Mode != rangeOutside && spec.Mode != rangeInside {
+		return RangeSpec{}, fmt.Errorf("неизвестный режим %q, ожидается outside или inside", args[0])
+	}
+	low, errLow := strconv.ParseFloat(strings.ReplaceAll(args[1], ",", "."), 64)
+	high, errHigh := strconv.ParseFloat(strings.ReplaceAll(args[2], ",", "."), 64)
+	if errLow != nil || errHigh != nil {
+		return RangeSpec{}, fmt.Errorf("неверный формат границ коридора")
+	}
+	if low > high {
+		low, high = high, low
+	}
+	if low == high {
+		return RangeSpec{}, fmt.Errorf("границы коридора должны различаться")
+	}
+	spec.Low, spec.High = low, high
+	return spec, nil
+}
+
+// addRangeAlert добавляет оповещение по коридору: args - тикер, режим и границы.
+func (bs *BotService) addRangeAlert(chatID int64, owner Owner, args []string) {
+	if len(args) < 3 {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Формат: ТИКЕР outside 300-320 или ТИКЕР inside 300-320"))
+		return
+	}
+	ticker := strings.ToUpper(args[0])
+	spec, err := parseRangeSpec(args[1:])
+	if err != nil {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Ошибка: %v.\nФормат: ТИКЕР outside 300-320 или ТИКЕР inside 300-320", err)))
+		return
+	}
+	if _, ok := stocks.Stocks[ticker]; !ok {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Тикер %s не найден в базе.", ticker)))
+		return
+	}
+	stock, err := bs.quotes().Get(ticker)
+	if err != nil {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Ошибка получения данных для %s: %v", ticker, err)))
+		return
+	}
+
+	inside := spec.contains(stock.Price)
+	if spec.Mode == rangeOutside && !inside {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("%s уже вне коридора: текущая цена %.2f", stock.Name, stock.Price)))
+		return
+	}
+	if spec.Mode == rangeInside && inside {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("%s уже внутри коридора: текущая цена %.2f", stock.Name, stock.Price)))
+		return
+	}
+
+	// Для входа в коридор запоминаем, с какой стороны цена к нему приближается.
+	direction := ""
+	if spec.Mode == rangeInside {
+		direction = "up"
+		if stock.Price > spec.High {
+			direction = "down"
+		}
+	}
+
+	_, err = bs.saveAlert(Alert{
+		Ticker:    ticker,
+		ChatID:    chatID,
+		Direction: direction,
+		Owner:     owner,
+		Range:     &spec,
+	})
+	if err != nil {
+		log.Printf("Ошибка сохранения алерта в БД: %v", err)
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при сохранении оповещения."))
+		return
+	}
+
+	bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Оповещение установлено для %s: %s (текущая цена %.2f).",
+		stock.Name, spec, stock.Price)))
+}
+
+// checkRangeAlert проверяет выход цены из коридора или вход в него и сообщает,
+// какая граница пересечена. Для режима inside сторона подхода (Direction)
+// пересчитывается по каждой проверенной цене вне коридора и сохраняется в БД,
+// поэтому после разворота цены сообщается граница, которая действительно пересечена.
+// Возвращает текст уведомления и true, если оповещение сработало.
+func checkRangeAlert(alert *Alert, quotes quoteSource) (string, bool) {
+	stock, err := quotes.Get(alert.Ticker)
+	if err != nil {
+		log.Printf("Ошибка проверки оповещения по коридору для %s: %v", alert.Ticker, err)
+		return "", false
+	}
+
+	spec := alert.Range
+	side := "" // Сторона коридора, с которой сейчас цена: up - снизу, down - сверху
+	switch {
+	case stock.Price < spec.Low:
+		side = "up"
+	case stock.Price > spec.High:
+		side = "down"
+	}
+	var boundary string
+	switch {
+	case spec.Mode == rangeOutside && side == "down":
+		boundary = fmt.Sprintf("пробита верхняя граница %.2f", spec.High)
+	case spec.Mode == rangeOutside && side == "up":
+		boundary = fmt.Sprintf("пробита нижняя граница %.2f", spec.Low)
+	case spec.Mode == rangeOutside:
+		return "", false
+	case side == "" && alert.Direction == "down":
+		boundary = fmt.Sprintf("цена вошла в коридор через верхнюю границу %.2f", spec.High)
+	case side == "" && alert.Direction == "up":
+		boundary = fmt.Sprintf("цена вошла в коридор через нижнюю границу %.2f", spec.Low)
+	case side == "":
+		return "", false // Сторона подхода ещё неизвестна: цена не была вне коридора
+	case alert.Direction == "up" && side == "down":
+		// Между проверками цена перескочила коридор целиком.
+		boundary = fmt.Sprintf("цена прошла коридор снизу вверх через нижнюю %.2f и верхнюю %.2f границы", spec.Low, spec.High)
+	case alert.Direction == "down" && side == "up":
+		boundary = fmt.Sprintf("цена прошла коридор сверху вниз через верхнюю %.2f и нижнюю %.2f границы", spec.High, spec.Low)
+	default:
+		if alert.Direction != side {
+			alert.Direction = side
+			// Оповещение без ID не сохранено в БД (прогон истории в бэктесте).
+			if alert.ID != 0 {
+				if err := repository.UpdateAlertDirection(alert.ID, side); err != nil {
+					log.Printf("Ошибка сохранения стороны подхода оповещения по коридору: %v", err)
+				}
+			}
+		}
+		return "", false
+	}
+
+	msgText := fmt.Sprintf("📊 Оповещение по коридору %.2f–%.2f для %s: %s (текущее значение: %.2f)",
+		spec.Low, spec.High, stock.Name, boundary, stock.Price)
+	return msgText, true
+}
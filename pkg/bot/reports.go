@@ -0,0 +1,203 @@
+// TradeTGBot/pkg/bot/reports.go
+package bot
+
+import (
+	"TradeTGBot/internal/reports"
+	"TradeTGBot/internal/repository"
+	"TradeTGBot/pkg/stocks"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log"
+	"strings"
+	"time"
+)
+
+// digestUsage - подсказка по команде /digest.
+const digestUsage = "Ежедневный: /digest daily 19:00 или /digest daily off\n" +
+	"Еженедельный: /digest weekly пт 19:00 или /digest weekly off\n" +
+	"Разделы: /digest content prices alerts movers\n" +
+	"Тикеры: /digest tickers SBER GAZP или /digest tickers auto\n" +
+	"Прямо сейчас: /digest now [weekly]"
+
+// weekdays - названия дней недели для /digest weekly.
+var weekdays = map[string]time.Weekday{
+	"пн": time.Monday, "вт": time.Tuesday, "ср": time.Wednesday, "чт": time.Thursday,
+	"пт": time.Friday, "сб": time.Saturday, "вс": time.Sunday,
+	"mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday, "thu": time.Thursday,
+	"fri": time.Friday, "sat": time.Saturday, "sun": time.Sunday,
+}
+
+// weekdayNames - короткие названия дней недели для вывода.
+var weekdayNames = [...]string{"вс", "пн", "вт", "ср", "чт", "пт", "сб"}
+
+// digestSectionNames - названия разделов дайджеста для вывода.
+var digestSectionNames = map[string]string{
+	repository.DigestPrices: "цены",
+	repository.DigestAlerts: "оповещения",
+	repository.DigestMovers: "лидеры",
+}
+
+// handleDigest обрабатывает /digest - расписание и содержание дайджестов по рынку.
+// Без аргументов показывает настройки.
+func (bs *BotService) handleDigest(chatID int64, args []string) {
+	settings, err := repository.GetDigestSettings(chatID)
+	if err != nil {
+		log.Printf("Ошибка получения настроек дайджеста: %v", err)
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении настроек."))
+		return
+	}
+	if len(args) == 0 {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, describeDigest(settings)+"\n\n"+digestUsage))
+		return
+	}
+
+	var reply string
+	switch strings.ToLower(args[0]) {
+	case "now":
+		kind := repository.DigestDaily
+		if len(args) > 1 && strings.EqualFold(args[1], repository.DigestWeekly) {
+			kind = repository.DigestWeekly
+		}
+		bs.sendDigestNow(settings, kind)
+		return
+	case "daily":
+		if len(args) != 2 {
+			bs.bot.Send(tgbotapi.NewMessage(chatID, "Формат: /digest daily 19:00 или /digest daily off"))
+			return
+		}
+		if strings.EqualFold(args[1], "off") {
+			settings.DailyEnabled = false
+			reply = "Ежедневный дайджест выключен."
+			break
+		}
+		minutes, err := parseClock(args[1])
+		if err != nil {
+			bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Ошибка: %v.", err)))
+			return
+		}
+		settings.DailyEnabled, settings.DailyTime = true, minutes
+		reply = fmt.Sprintf("Ежедневный дайджест будет приходить по будням в %s.", formatClock(minutes))
+	case "weekly":
+		if len(args) < 2 {
+			bs.bot.Send(tgbotapi.NewMessage(chatID, "Формат: /digest weekly пт 19:00 или /digest weekly off"))
+			return
+		}
+		if strings.EqualFold(args[1], "off") {
+			settings.WeeklyEnabled = false
+			reply = "Еженедельный дайджест выключен."
+			break
+		}
+		for _, arg := range args[1:] {
+			if day, ok := weekdays[strings.ToLower(arg)]; ok {
+				settings.WeeklyDay = day
+				continue
+			}
+			minutes, err := parseClock(arg)
+			if err != nil {
+				bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Ошибка: %v.", err)))
+				return
+			}
+			settings.WeeklyTime = minutes
+		}
+		settings.WeeklyEnabled = true
+		reply = fmt.Sprintf("Еженедельный дайджест будет приходить: %s в %s.",
+			weekdayNames[settings.WeeklyDay], formatClock(settings.WeeklyTime))
+	case "content":
+		var sections []string
+		for _, arg := range args[1:] {
+			section := strings.ToLower(arg)
+			if _, ok := digestSectionNames[section]; !ok {
+				bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Неизвестный раздел %q. Разделы: %s.",
+					arg, strings.Join(repository.DigestSections, ", "))))
+				return
+			}
+			sections = append(sections, section)
+		}
+		if len(sections) == 0 {
+			bs.bot.Send(tgbotapi.NewMessage(chatID, "Укажите разделы: /digest content prices alerts movers"))
+			return
+		}
+		settings.Sections = sections
+		reply = "Разделы дайджеста: " + describeSections(settings) + "."
+	case "tickers":
+		if len(args) == 2 && strings.EqualFold(args[1], "auto") {
+			settings.Tickers = nil
+			reply = "В дайджест попадают тикеры ваших оповещений и подписок."
+			break
+		}
+		var tickers []string
+		for _, arg := range args[1:] {
+			ticker := strings.ToUpper(arg)
+			if _, ok := stocks.Stocks[ticker]; !ok {
+				bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Тикер %s не найден в базе.", ticker)))
+				return
+			}
+			tickers = append(tickers, ticker)
+		}
+		if len(tickers) == 0 {
+			bs.bot.Send(tgbotapi.NewMessage(chatID, "Укажите тикеры: /digest tickers SBER GAZP или /digest tickers auto"))
+			return
+		}
+		settings.Tickers = tickers
+		reply = "Тикеры дайджеста: " + strings.Join(tickers, ", ") + "."
+	default:
+		bs.bot.Send(tgbotapi.NewMessage(chatID, digestUsage))
+		return
+	}
+
+	if err := repository.SaveDigestSettings(settings); err != nil {
+		log.Printf("Ошибка сохранения настроек дайджеста: %v", err)
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при сохранении настроек."))
+		return
+	}
+	bs.bot.Send(tgbotapi.NewMessage(chatID, reply))
+}
+
+// sendDigestNow формирует дайджест на текущий момент и сразу отправляет его в чат.
+func (bs *BotService) sendDigestNow(settings repository.DigestSettings, kind string) {
+	text, err := reports.Build(settings, kind, time.Now())
+	if err != nil {
+		log.Printf("Ошибка формирования дайджеста: %v", err)
+		bs.bot.Send(tgbotapi.NewMessage(settings.ChatID, "Ошибка при формировании дайджеста."))
+		return
+	}
+	if text == "" {
+		text = "Пока нечего показать: нет цен по вашим тикерам и сработавших оповещений. Тикеры задаются командой /digest tickers."
+	}
+	bs.bot.Send(tgbotapi.NewMessage(settings.ChatID, text))
+}
+
+// describeDigest описывает настройки дайджеста чата.
+func describeDigest(s repository.DigestSettings) string {
+	var sb strings.Builder
+	sb.WriteString("Дайджесты по рынку:\n")
+	if s.DailyEnabled {
+		sb.WriteString(fmt.Sprintf("ежедневный - по будням в %s\n", formatClock(s.DailyTime)))
+	} else {
+		sb.WriteString("ежедневный - выключен\n")
+	}
+	if s.WeeklyEnabled {
+		sb.WriteString(fmt.Sprintf("еженедельный - %s в %s\n", weekdayNames[s.WeeklyDay], formatClock(s.WeeklyTime)))
+	} else {
+		sb.WriteString("еженедельный - выключен\n")
+	}
+	sb.WriteString("разделы: " + describeSections(s) + "\n")
+	if len(s.Tickers) == 0 {
+		sb.WriteString("тикеры: из оповещений и подписок\n")
+	} else {
+		sb.WriteString("тикеры: " + strings.Join(s.Tickers, ", ") + "\n")
+	}
+	sb.WriteString("Время - по часовому поясу чата (/quiet).")
+	return sb.String()
+}
+
+// describeSections перечисляет включённые разделы дайджеста.
+func describeSections(s repository.DigestSettings) string {
+	var names []string
+	for _, section := range repository.DigestSections {
+		if s.Has(section) {
+			names = append(names, digestSectionNames[section])
+		}
+	}
+	return strings.Join(names, ", ")
+}
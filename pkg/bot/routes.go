@@ -0,0 +1,305 @@
+// TradeTGBot/pkg/bot/routes.go
+package bot
+
+import (
+	"TradeTGBot/internal/notify"
+	"TradeTGBot/internal/repository"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// routeFilterNames - описания фильтров маршрутов для пользователя.
+var routeFilterNames = map[string]string{
+	repository.RouteAll:    "все уведомления",
+	repository.RouteUrgent: "только срочные",
+	repository.RouteAlerts: "только срабатывания оповещений",
+	repository.RouteMarket: "только рыночные (резкие изменения цены)",
+	repository.RouteOff:    "ничего",
+}
+
+const notifyUsage = "Маршруты уведомлений:\n" +
+	"/notify - текущие маршруты\n" +
+	"/notify email ADDR [ФИЛЬТР] - дублировать на email\n" +
+	"/notify webhook URL [ФИЛЬТР] - отправлять JSON на вебхук (с HMAC-подписью)\n" +
+	"/notify telegram ФИЛЬТР - что присылать в этот чат\n" +
+	"/notify topic ID|ССЫЛКА [ФИЛЬТР] - присылать в тему форума этой группы\n" +
+	"/notify channel @канал [ФИЛЬТР] - публиковать в канал (бот - администратор), по умолчанию market\n" +
+	"/notify off email|webhook|topic|channel [АДРЕС] - удалить маршрут\n" +
+	"Фильтры: all, urgent, alerts, market, off."
+
+// webhookSignatureHelp объясняет получателю вебхука, как проверить подпись.
+const webhookSignatureHelp = "Запросы подписываются заголовком X-TradeTGBot-Signature: " +
+	"sha256=HMAC-SHA256(секрет, X-TradeTGBot-Timestamp + \".\" + тело)."
+
+// webhookLookupTimeout ограничивает проверку адреса вебхука при его настройке.
+const webhookLookupTimeout = 5 * time.Second
+
+// handleNotify обрабатывает /notify: настройку каналов, куда уходят уведомления чата.
+func (bs *BotService) handleNotify(chatID int64, from *tgbotapi.User, args []string) {
+	if len(args) == 0 {
+		bs.listRoutes(chatID)
+		return
+	}
+
+	channel := strings.ToLower(args[0])
+	if channel == "off" {
+		if len(args) < 2 || !slices.Contains(removableChannels, args[1]) {
+			bs.bot.Send(tgbotapi.NewMessage(chatID, "Формат: /notify off email|webhook|topic|channel [АДРЕС]"))
+			return
+		}
+		target := ""
+		if len(args) > 2 {
+			var err error
+			if target, err = bs.routeTarget(args[1], args[2]); err != nil {
+				bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Ошибка: %v.", err)))
+				return
+			}
+		}
+		n, err := repository.DeleteNotificationRoutes(chatID, args[1], target)
+		if err != nil {
+			log.Printf("Ошибка удаления маршрута уведомлений: %v", err)
+			bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при удалении маршрута."))
+			return
+		}
+		bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Удалено маршрутов: %d.", n)))
+		return
+	}
+
+	route := repository.NotificationRoute{ChatID: chatID, Channel: channel, Filter: repository.RouteAll}
+	filterArg := 2
+	switch channel {
+	case repository.ChannelTelegram:
+		filterArg = 1
+	case repository.ChannelEmail:
+		if _, ok := bs.notifiers[repository.ChannelEmail]; !ok {
+			bs.bot.Send(tgbotapi.NewMessage(chatID, "Отправка email не настроена на сервере (SMTP_HOST)."))
+			return
+		}
+		if len(args) < 2 {
+			bs.bot.Send(tgbotapi.NewMessage(chatID, "Формат: /notify email ADDR [ФИЛЬТР]"))
+			return
+		}
+		addr, err := mail.ParseAddress(args[1])
+		if err != nil {
+			bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Неверный адрес email %q.", args[1])))
+			return
+		}
+		route.Target = addr.Address
+	case repository.ChannelWebhook:
+		if len(args) < 2 {
+			bs.bot.Send(tgbotapi.NewMessage(chatID, "Формат: /notify webhook URL [ФИЛЬТР]"))
+			return
+		}
+		u, err := url.Parse(args[1])
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Неверный URL вебхука %q, ожидается http(s)://...", args[1])))
+			return
+		}
+		// Вебхук не должен позволять обращаться от имени бота к внутренним сервисам.
+		ctx, cancel := context.WithTimeout(context.Background(), webhookLookupTimeout)
+		err = notify.CheckWebhookHost(ctx, u.Hostname())
+		cancel()
+		if err != nil {
+			bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Вебхук должен указывать на публичный адрес: %v.", err)))
+			return
+		}
+		route.Target = u.String()
+		if route.Secret, err = newWebhookSecret(); err != nil {
+			log.Printf("Ошибка генерации секрета вебхука: %v", err)
+			bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при сохранении маршрута."))
+			return
+		}
+	case repository.ChannelTopic:
+		if !isGroupChat(chatID) {
+			bs.bot.Send(tgbotapi.NewMessage(chatID, "Темы есть только в супергруппах с форумом."))
+			return
+		}
+		if len(args) < 2 {
+			bs.bot.Send(tgbotapi.NewMessage(chatID, "Формат: /notify topic ID|ССЫЛКА [ФИЛЬТР]. ID темы - последнее число в ссылке на неё."))
+			return
+		}
+		target, err := bs.routeTarget(channel, args[1])
+		if err != nil {
+			bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Ошибка: %v.", err)))
+			return
+		}
+		route.Target = target
+	case repository.ChannelPublish:
+		if len(args) < 2 {
+			bs.bot.Send(tgbotapi.NewMessage(chatID, "Формат: /notify channel @канал [ФИЛЬТР]"))
+			return
+		}
+		target, err := bs.publishTarget(args[1], from)
+		if err != nil {
+			bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Ошибка: %v.", err)))
+			return
+		}
+		route.Target = target
+		route.Filter = repository.RouteMarket // В канал по умолчанию уходят только рыночные сводки
+	default:
+		bs.bot.Send(tgbotapi.NewMessage(chatID, notifyUsage))
+		return
+	}
+
+	if len(args) > filterArg {
+		route.Filter = strings.ToLower(args[filterArg])
+	} else if channel == repository.ChannelTelegram {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Формат: /notify telegram all|urgent|alerts|market|off"))
+		return
+	}
+	if !slices.Contains(repository.RouteFilters, route.Filter) {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Неизвестный фильтр %q. Доступны: %s.", route.Filter, strings.Join(repository.RouteFilters, ", "))))
+		return
+	}
+
+	// Секрет подписи в группе увидели бы все участники, поэтому он уходит
+	// в личные сообщения тому, кто настроил вебхук, ещё до сохранения маршрута.
+	secretPrivately := channel == repository.ChannelWebhook && isGroupChat(chatID)
+	if secretPrivately {
+		if from == nil {
+			bs.bot.Send(tgbotapi.NewMessage(chatID, "Не удалось определить, кому отправить секрет вебхука."))
+			return
+		}
+		text := fmt.Sprintf("Секрет подписи вебхука %s для группы %d: %s\n%s", route.Target, chatID, route.Secret, webhookSignatureHelp)
+		if _, err := bs.bot.Send(tgbotapi.NewMessage(from.ID, text)); err != nil {
+			log.Printf("Ошибка отправки секрета вебхука пользователю %d: %v", from.ID, err)
+			bs.bot.Send(tgbotapi.NewMessage(chatID, "Не удалось отправить секрет подписи в личные сообщения. "+
+				"Напишите боту в личном чате и повторите команду."))
+			return
+		}
+	}
+
+	if _, err := repository.SaveNotificationRoute(route); err != nil {
+		log.Printf("Ошибка сохранения маршрута уведомлений: %v", err)
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при сохранении маршрута."))
+		return
+	}
+
+	text := fmt.Sprintf("Маршрут сохранён: %s - %s.", routeTitle(route), routeFilterNames[route.Filter])
+	switch {
+	case secretPrivately:
+		text += "\nСекрет подписи отправлен вам в личные сообщения."
+	case channel == repository.ChannelWebhook:
+		text += fmt.Sprintf("\nСекрет подписи: %s\n%s", route.Secret, webhookSignatureHelp)
+	}
+	bs.bot.Send(tgbotapi.NewMessage(chatID, text))
+}
+
+// listRoutes показывает, куда уходят уведомления чата.
+func (bs *BotService) listRoutes(chatID int64) {
+	routes, err := repository.GetNotificationRoutes(chatID)
+	if err != nil {
+		log.Printf("Ошибка получения маршрутов уведомлений: %v", err)
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении маршрутов."))
+		return
+	}
+
+	var sb strings.Builder
+	telegram := repository.RouteAll
+	for _, r := range routes {
+		if r.Channel == repository.ChannelTelegram {
+			telegram = r.Filter
+		}
+	}
+	sb.WriteString(fmt.Sprintf("Telegram: %s\n", routeFilterNames[telegram]))
+	for _, r := range routes {
+		if r.Channel != repository.ChannelTelegram {
+			sb.WriteString(fmt.Sprintf("%s: %s\n", routeTitle(r), routeFilterNames[r.Filter]))
+		}
+	}
+	sb.WriteString("\n" + notifyUsage)
+	bs.bot.Send(tgbotapi.NewMessage(chatID, sb.String()))
+}
+
+func routeTitle(r repository.NotificationRoute) string {
+	switch {
+	case r.Target == "":
+		return r.Channel
+	case r.Channel == repository.ChannelTopic:
+		return "тема #" + r.Target
+	case r.Channel == repository.ChannelPublish:
+		return "канал " + r.Target
+	}
+	return fmt.Sprintf("%s %s", r.Channel, r.Target)
+}
+
+// removableChannels - каналы, маршруты которых удаляет /notify off.
+var removableChannels = []string{
+	repository.ChannelEmail, repository.ChannelWebhook, repository.ChannelTopic, repository.ChannelPublish,
+}
+
+// routeTarget приводит адрес маршрута к виду, в котором он хранится: ID темы
+// из ссылки вида https://t.me/c/123/45, ID канала из @имени.
+func (bs *BotService) routeTarget(channel, arg string) (string, error) {
+	switch channel {
+	case repository.ChannelTopic:
+		id, err := strconv.Atoi(arg[strings.LastIndex(arg, "/")+1:])
+		if err != nil || id <= 0 {
+			return "", fmt.Errorf("неверный ID темы %q", arg)
+		}
+		return strconv.Itoa(id), nil
+	case repository.ChannelPublish:
+		chat, err := bs.lookupChat(arg)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(chat.ID, 10), nil
+	}
+	return arg, nil
+}
+
+// lookupChat находит чат по @имени или числовому ID.
+func (bs *BotService) lookupChat(arg string) (tgbotapi.Chat, error) {
+	cfg := tgbotapi.ChatInfoConfig{}
+	if id, err := strconv.ParseInt(arg, 10, 64); err == nil {
+		cfg.ChatID = id
+	} else {
+		cfg.SuperGroupUsername = "@" + strings.TrimPrefix(arg, "@")
+	}
+	chat, err := bs.bot.GetChat(cfg)
+	if err != nil {
+		return chat, fmt.Errorf("канал %s не найден или бот не добавлен в него", arg)
+	}
+	return chat, nil
+}
+
+// publishTarget проверяет, что в канал можно публиковать: бот - администратор с
+// правом публикации, а настраивающий пользователь - администратор канала.
+func (bs *BotService) publishTarget(arg string, from *tgbotapi.User) (string, error) {
+	chat, err := bs.lookupChat(arg)
+	if err != nil {
+		return "", err
+	}
+	if !chat.IsChannel() {
+		return "", fmt.Errorf("%s - не канал", arg)
+	}
+	self, err := bs.bot.GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: chat.ID, UserID: bs.bot.Self.ID},
+	})
+	if err != nil || !(self.IsAdministrator() && self.CanPostMessages) {
+		return "", fmt.Errorf("сделайте бота администратором канала %s с правом публикации", arg)
+	}
+	if from == nil || !bs.isChatAdmin(chat.ID, from.ID) {
+		return "", fmt.Errorf("настроить публикацию может только администратор канала %s", arg)
+	}
+	return strconv.FormatInt(chat.ID, 10), nil
+}
+
+// newWebhookSecret генерирует случайный ключ подписи вебхука.
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,298 @@
+// TradeTGBot/pkg/bot/spread.go
+package bot
+
+import (
+	"TradeTGBot/internal/indicators"
+	"TradeTGBot/internal/repository"
+	"TradeTGBot/pkg/stocks"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Виды спреда между двумя инструментами.
+const (
+	spreadDiff   = "diff"   // Разница цен A - B
+	spreadRatio  = "ratio"  // Отношение цен A / B
+	spreadZScore = "zscore" // Z-оценка отношения A / B за окно Lookback
+)
+
+// spreadLookbacks - допустимые окна истории для спреда.
+var spreadLookbacks = map[string]time.Duration{
+	"1h": time.Hour,
+	"4h": 4 * time.Hour,
+	"1d": 24 * time.Hour,
+	"1w": 7 * 24 * time.Hour,
+}
+
+const (
+	defaultSpreadLookback = "1d"
+	spreadBar             = time.Minute // Шаг выравнивания истории двух ног
+	minSpreadPoints       = 10          // Минимум совпавших точек для z-оценки
+)
+
+// SpreadSpec описывает оповещение по спреду между Ticker оповещения и Second.
+type SpreadSpec struct {
+	Second   string  `json:"second"`
+	Mode     string  `json:"mode"`
+	Op       string  `json:"op"` // ">" или "<"
+	Value    float64 `json:"value"`
+	Lookback string  `json:"lookback"`
+}
+
+// String возвращает описание условия, например "zscore(1d) > 2".
+func (s SpreadSpec) String() string {
+	if s.Mode == spreadZScore {
+		return fmt.Sprintf("%s(%s) %s %g", s.Mode, s.Lookback, s.Op, s.Value)
+	}
+	return fmt.Sprintf("%s %s %g", s.Mode, s.Op, s.Value)
+}
+
+// parseSpreadSpec разбирает условие спреда: "ratio > 1.2", "diff < 500", "zscore > 2 1w".
+func parseSpreadSpec(second string, args []string) (SpreadSpec, error) {
+	if len(args) != 3 && len(args) != 4 {
+		return SpreadSpec{}, fmt.Errorf("укажите вид спреда, сравнение и значение")
+	}
+	spec := SpreadSpec{Second: second, Mode: strings.ToLower(args[0]), Op: args[1], Lookback: defaultSpreadLookback}
+	if spec.Mode != spreadDiff && spec.Mode != spreadRatio && spec.Mode != spreadZScore {
+		return SpreadSpec{}, fmt.Errorf("неизвестный вид спреда %q, ожидается diff, ratio или zscore", args[0])
+	}
+	if spec.Op != ">" && spec.Op != "<" {
+		return SpreadSpec{}, fmt.Errorf("неизвестное сравнение %q, ожидается > или <", args[1])
+	}
+	value, err := strconv.ParseFloat(strings.ReplaceAll(args[2], ",", "."), 64)
+	if err != nil {
+		return SpreadSpec{}, fmt.Errorf("неверное значение %q", args[2])
+	}
+	spec.Value = value
+	if len(args) == 4 {
+		if _, ok := spreadLookbacks[strings.ToLower(args[3])]; !ok {
+			return SpreadSpec{}, fmt.Errorf("неизвестное окно %q, допустимы 1h, 4h, 1d, 1w", args[3])
+		}
+		spec.Lookback = strings.ToLower(args[3])
+	}
+	return spec, nil
+}
+
+// spreadStats - статистика спреда по выровненной истории двух ног.
+type spreadStats struct {
+	Points                 int
+	MinDiff, MaxDiff       float64
+	MinRatio, MaxRatio     float64
+	RatioMean, RatioStdDev float64
+}
+
+// spreadKey - пара ног и окно, по которым считается статистика спреда.
+type spreadKey struct {
+	first, second string
+	lookback      time.Duration
+}
+
+// spreadCacheEntry - статистика спреда, посчитанная до начала бара Bar.
+type spreadCacheEntry struct {
+	Bar   time.Time
+	Stats spreadStats
+}
+
+// spreadCache хранит статистику спреда, посчитанную по закрытым минутным барам.
+// Закрытые бары меняются только на границе бара, поэтому статистика пересчитывается
+// не чаще раза за бар, а не на каждом тике каждого оповещения.
+var spreadCache = struct {
+	sync.Mutex
+	entries map[spreadKey]spreadCacheEntry
+}{entries: make(map[spreadKey]spreadCacheEntry)}
+
+// cachedSpreadStats возвращает статистику спреда за окно lookback до начала текущего бара.
+func cachedSpreadStats(first, second string, lookback time.Duration, now time.Time) (spreadStats, error) {
+	key := spreadKey{first: first, second: second, lookback: lookback}
+	bar := now.Truncate(spreadBar)
+	spreadCache.Lock()
+	entry, ok := spreadCache.entries[key]
+	spreadCache.Unlock()
+	if ok && entry.Bar.Equal(bar) {
+		return entry.Stats, nil
+	}
+
+	stats, err := loadSpreadStats(first, second, bar.Add(-lookback), bar)
+	if err != nil {
+		return spreadStats{}, err
+	}
+	spreadCache.Lock()
+	defer spreadCache.Unlock()
+	// Заодно забываем пары, которые больше не проверяются (например, удалённые оповещения).
+	for k, e := range spreadCache.entries {
+		if e.Bar.Before(bar.Add(-spreadBar)) {
+			delete(spreadCache.entries, k)
+		}
+	}
+	spreadCache.entries[key] = spreadCacheEntry{Bar: bar, Stats: stats}
+	return stats, nil
+}
+
+// loadSpreadStats загружает минутные свечи обеих ног за [from, to) и считает статистику
+// по закрытиям баров, в которых есть цены обеих ног.
+func loadSpreadStats(first, second string, from, to time.Time) (spreadStats, error) {
+	legs := make([]map[time.Time]float64, 2)
+	for i, ticker := range []string{first, second} {
+		candles, err := repository.GetCandles(ticker, spreadBar, from, to)
+		if err != nil {
+			return spreadStats{}, err
+		}
+		legs[i] = make(map[time.Time]float64, len(candles))
+		for _, c := range candles {
+			legs[i][c.Time] = c.Close
+		}
+	}
+
+	var stats spreadStats
+	var ratios []float64
+	for t, a := range legs[0] {
+		b, ok := legs[1][t]
+		if !ok || b == 0 {
+			continue
+		}
+		diff, ratio := a-b, a/b
+		if stats.Points == 0 {
+			stats.MinDiff, stats.MaxDiff, stats.MinRatio, stats.MaxRatio = diff, diff, ratio, ratio
+		}
+		stats.MinDiff, stats.MaxDiff = min(stats.MinDiff, diff), max(stats.MaxDiff, diff)
+		stats.MinRatio, stats.MaxRatio = min(stats.MinRatio, ratio), max(stats.MaxRatio, ratio)
+		ratios = append(ratios, ratio)
+		stats.Points++
+	}
+	stats.RatioMean, stats.RatioStdDev = indicators.MeanStdDev(ratios)
+	return stats, nil
+}
+
+// zScore возвращает z-оценку текущего отношения относительно истории.
+func (st spreadStats) zScore(ratio float64) (float64, error) {
+	if st.Points < minSpreadPoints || st.RatioStdDev == 0 {
+		return 0, fmt.Errorf("недостаточно совместной истории для z-оценки (%d точек)", st.Points)
+	}
+	return (ratio - st.RatioMean) / st.RatioStdDev, nil
+}
+
+// value вычисляет значение спреда для текущих цен обеих ног.
+func (s SpreadSpec) value(a, b float64, first string) (float64, error) {
+	if b == 0 {
+		return 0, fmt.Errorf("нулевая цена %s", s.Second)
+	}
+	switch s.Mode {
+	case spreadDiff:
+		return a - b, nil
+	case spreadRatio:
+		return a / b, nil
+	}
+	stats, err := cachedSpreadStats(first, s.Second, spreadLookbacks[s.Lookback], time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return stats.zScore(a / b)
+}
+
+// handleSpread обрабатывает /spread ТИКЕР1 ТИКЕР2 [ВИД СРАВНЕНИЕ ЗНАЧЕНИЕ [ОКНО]]:
+// без условия показывает текущий спред и его диапазон, с условием - создаёт оповещение.
+func (bs *BotService) handleSpread(chatID int64, owner Owner, args []string) {
+	if len(args) < 2 {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Формат: /spread SBER SBERP - текущий спред,\n"+
+			"/spread SBER SBERP ratio > 1.2, /spread LKOH ROSN diff < 500, /spread SBER SBERP zscore > 2 1w - оповещение"))
+		return
+	}
+	first, second := strings.ToUpper(args[0]), strings.ToUpper(args[1])
+	for _, ticker := range []string{first, second} {
+		if _, ok := stocks.Stocks[ticker]; !ok {
+			bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Тикер %s не найден в базе.", ticker)))
+			return
+		}
+	}
+	if first == second {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Укажите два разных тикера."))
+		return
+	}
+
+	// Обе ноги запрашиваются через общий поллер, тики попадают в историю через шину.
+	prices, err := bs.quotes().Prices([]string{first, second})
+	if err != nil {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Ошибка получения данных: %v", err)))
+		return
+	}
+	a, b := prices[first], prices[second]
+
+	if len(args) == 2 {
+		bs.sendSpreadSummary(chatID, first, second, a, b)
+		return
+	}
+
+	spec, err := parseSpreadSpec(second, args[2:])
+	if err != nil {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Ошибка: %v.", err)))
+		return
+	}
+	_, err = bs.saveAlert(Alert{
+		Ticker: first,
+		ChatID: chatID,
+		Spread: &spec,
+		Owner:  owner,
+	})
+	if err != nil {
+		log.Printf("Ошибка сохранения алерта в БД: %v", err)
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при сохранении оповещения."))
+		return
+	}
+	bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Оповещение по спреду %s/%s установлено: %s.", first, second, spec)))
+}
+
+// sendSpreadSummary отправляет текущие разницу и отношение цен и их диапазон за сутки.
+func (bs *BotService) sendSpreadSummary(chatID int64, first, second string, a, b float64) {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Спред %s/%s\n%s: %.2f, %s: %.2f\nРазница: %.2f\n", first, second, first, a, second, b, a-b))
+	if b != 0 {
+		sb.WriteString(fmt.Sprintf("Отношение: %.4f\n", a/b))
+	}
+
+	stats, err := cachedSpreadStats(first, second, spreadLookbacks[defaultSpreadLookback], time.Now())
+	switch {
+	case err != nil:
+		log.Printf("Ошибка загрузки истории спреда %s/%s: %v", first, second, err)
+		sb.WriteString("История спреда недоступна.")
+	case stats.Points == 0:
+		sb.WriteString("Совместной истории за сутки пока нет.")
+	default:
+		sb.WriteString(fmt.Sprintf("За сутки (%d точек):\nразница %.2f … %.2f\nотношение %.4f … %.4f",
+			stats.Points, stats.MinDiff, stats.MaxDiff, stats.MinRatio, stats.MaxRatio))
+		if b != 0 {
+			if z, err := stats.zScore(a / b); err == nil {
+				sb.WriteString(fmt.Sprintf("\nz-оценка отношения: %.2f", z))
+			}
+		}
+	}
+	bs.bot.Send(tgbotapi.NewMessage(chatID, sb.String()))
+}
+
+// checkSpreadAlert проверяет условие по спреду на котировках обеих ног из одного цикла.
+// Возвращает текст уведомления и true, если оповещение сработало.
+func checkSpreadAlert(alert Alert, quotes quoteSource) (string, bool) {
+	spec := alert.Spread
+	prices, err := quotes.Prices([]string{alert.Ticker, spec.Second})
+	if err != nil {
+		log.Printf("Ошибка проверки оповещения по спреду %s/%s: %v", alert.Ticker, spec.Second, err)
+		return "", false
+	}
+	a, b := prices[alert.Ticker], prices[spec.Second]
+	value, err := spec.value(a, b, alert.Ticker)
+	if err != nil {
+		log.Printf("Ошибка расчёта спреда %s/%s: %v", alert.Ticker, spec.Second, err)
+		return "", false
+	}
+	if (spec.Op == ">" && value <= spec.Value) || (spec.Op == "<" && value >= spec.Value) {
+		return "", false
+	}
+
+	msgText := fmt.Sprintf("⚖️ Оповещение по спреду %s/%s: %s (текущее значение: %.4f)\n%s: %.2f, %s: %.2f",
+		alert.Ticker, spec.Second, spec, value, alert.Ticker, a, spec.Second, b)
+	return msgText, true
+}
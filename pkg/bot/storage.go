@@ -0,0 +1,165 @@
+// TradeTGBot/pkg/bot/storage.go
+package bot
+
+import (
+	"TradeTGBot/internal/alertexpr"
+	"TradeTGBot/internal/repository"
+	"TradeTGBot/pkg/stocks"
+	"encoding/json"
+	"fmt"
+	"log"
+)
+
+// Виды оповещений, сохраняемые в колонке alerts.kind.
+const (
+	alertKindPrice     = "price"
+	alertKindCondition = "condition"
+	alertKindIndicator = "indicator"
+	alertKindTrailing  = "trailing"
+	alertKindRange     = "range"
+	alertKindSpread    = "spread"
+)
+
+// alertParams - параметры оповещения, специфичные для его вида (колонка alerts.params).
+type alertParams struct {
+	Condition string         `json:"condition,omitempty"`
+	Indicator *IndicatorSpec `json:"indicator,omitempty"`
+	Trailing  *TrailingSpec  `json:"trailing,omitempty"`
+	Range     *RangeSpec     `json:"range,omitempty"`
+	Spread    *SpreadSpec    `json:"spread,omitempty"`
+}
+
+// kind возвращает вид оповещения.
+func (a Alert) kind() string {
+	switch {
+	case a.Condition != nil:
+		return alertKindCondition
+	case a.Indicator != nil:
+		return alertKindIndicator
+	case a.Trailing != nil:
+		return alertKindTrailing
+	case a.Range != nil:
+		return alertKindRange
+	case a.Spread != nil:
+		return alertKindSpread
+	}
+	return alertKindPrice
+}
+
+// toRecord преобразует оповещение в строку таблицы alerts.
+func (a Alert) toRecord() (repository.Alert, error) {
+	params := alertParams{Indicator: a.Indicator, Trailing: a.Trailing, Range: a.Range, Spread: a.Spread}
+	if a.Condition != nil {
+		params.Condition = a.Condition.Source
+	}
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return repository.Alert{}, fmt.Errorf("ошибка кодирования параметров оповещения: %w", err)
+	}
+	return repository.Alert{
+		ID:        a.ID,
+		Ticker:    a.Ticker,
+		Target:    a.Target,
+		ChatID:    a.ChatID,
+		Direction: a.Direction,
+		Kind:      a.kind(),
+		Params:    string(raw),
+		Peak:      a.Peak,
+		CreatedAt: a.CreatedAt,
+
+		SnoozedUntil: a.SnoozedUntil,
+		Urgent:       a.Urgent,
+		UserID:       a.Owner.ID,
+		UserName:     a.Owner.Name,
+	}, nil
+}
+
+// alertFromRecord восстанавливает оповещение из строки таблицы alerts.
+func alertFromRecord(r repository.Alert) (Alert, error) {
+	a := Alert{
+		ID:        r.ID,
+		Ticker:    r.Ticker,
+		Target:    r.Target,
+		ChatID:    r.ChatID,
+		Direction: r.Direction,
+		Peak:      r.Peak,
+		CreatedAt: r.CreatedAt,
+
+		SnoozedUntil: r.SnoozedUntil,
+		Urgent:       r.Urgent,
+		Owner:        Owner{ID: r.UserID, Name: r.UserName},
+
+		dataWarned: r.DataWarned,
+	}
+	var params alertParams
+	if err := json.Unmarshal([]byte(r.Params), &params); err != nil {
+		return a, fmt.Errorf("оповещение %d: неверные параметры: %w", r.ID, err)
+	}
+	switch r.Kind {
+	case alertKindCondition:
+		expr, err := alertexpr.Parse(params.Condition, func(ticker string) bool {
+			_, ok := stocks.Stocks[ticker]
+			return ok
+		})
+		if err != nil {
+			return a, fmt.Errorf("оповещение %d: %w", r.ID, err)
+		}
+		a.Condition = expr
+	case alertKindIndicator:
+		if params.Indicator == nil {
+			return a, fmt.Errorf("оповещение %d: нет параметров индикатора", r.ID)
+		}
+		a.Indicator = params.Indicator
+	case alertKindTrailing:
+		if params.Trailing == nil {
+			return a, fmt.Errorf("оповещение %d: нет параметров трейлинга", r.ID)
+		}
+		a.Trailing = params.Trailing
+	case alertKindRange:
+		if params.Range == nil {
+			return a, fmt.Errorf("оповещение %d: нет параметров коридора", r.ID)
+		}
+		a.Range = params.Range
+	case alertKindSpread:
+		if params.Spread == nil {
+			return a, fmt.Errorf("оповещение %d: нет параметров спреда", r.ID)
+		}
+		a.Spread = params.Spread
+	case alertKindPrice:
+	default:
+		return a, fmt.Errorf("оповещение %d: неизвестный вид %q", r.ID, r.Kind)
+	}
+	return a, nil
+}
+
+// loadAlerts загружает сохранённые оповещения из БД при запуске бота.
+func (bs *BotService) loadAlerts() {
+	records, err := repository.GetActiveAlerts()
+	if err != nil {
+		log.Printf("Ошибка загрузки оповещений из БД: %v", err)
+		return
+	}
+	for _, r := range records {
+		alert, err := alertFromRecord(r)
+		if err != nil {
+			log.Printf("Пропуск оповещения при загрузке: %v", err)
+			continue
+		}
+		bs.alerts.Add(alert)
+	}
+	log.Printf("Загружено оповещений из БД: %d", bs.alerts.Len())
+}
+
+// saveAlert сохраняет новое оповещение в БД и добавляет его в список активных.
+func (bs *BotService) saveAlert(alert Alert) (Alert, error) {
+	record, err := alert.toRecord()
+	if err != nil {
+		return alert, err
+	}
+	alert.ID, err = repository.SaveAlert(record)
+	if err != nil {
+		return alert, err
+	}
+	bs.alerts.Add(alert)
+	return alert, nil
+}
@@ -0,0 +1,128 @@
+// TradeTGBot/pkg/bot/subscriptions.go
+package bot
+
+import (
+	"TradeTGBot/internal/config"
+	"TradeTGBot/internal/repository"
+	"TradeTGBot/pkg/stocks"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// detectorNames - детекторы аномалий в порядке показа пользователю.
+var detectorNames = []string{config.DetectorPercent, config.DetectorZScore, config.DetectorEWMA, config.DetectorATR}
+
+// detectorUnits - единицы порога детекторов для сообщений.
+var detectorUnits = map[string]string{
+	config.DetectorPercent: "%",
+	config.DetectorZScore:  "σ",
+	config.DetectorEWMA:    "σ",
+	config.DetectorATR:     " ATR",
+}
+
+// handleSubscribe обрабатывает /subscribe [ТИКЕР...] [ПОРОГ] [ДЕТЕКТОР]: подписку чата
+// на уведомления о резком изменении цены. Без аргументов показывает подписки.
+func (bs *BotService) handleSubscribe(chatID int64, args []string) {
+	if len(args) == 0 {
+		bs.listSubscriptions(chatID)
+		return
+	}
+
+	detector := config.DetectorPercent
+	if name := strings.ToLower(args[len(args)-1]); len(args) > 1 {
+		if _, ok := config.DetectorThresholds[name]; ok {
+			detector = name
+			args = args[:len(args)-1]
+		}
+	}
+	threshold := config.DetectorThresholds[detector]
+	if last := strings.TrimSuffix(args[len(args)-1], "%"); len(args) > 1 || strings.HasSuffix(args[0], "%") {
+		if v, err := strconv.ParseFloat(strings.ReplaceAll(last, ",", "."), 64); err == nil {
+			if v <= 0 {
+				bs.bot.Send(tgbotapi.NewMessage(chatID, "Порог должен быть положительным числом."))
+				return
+			}
+			threshold = v
+			args = args[:len(args)-1]
+		}
+	}
+	if len(args) == 0 {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Укажите тикеры, например: /subscribe SBER GAZP 0.5 или /subscribe SBER 3 zscore"))
+		return
+	}
+
+	var tickers []string
+	for _, arg := range args {
+		ticker := strings.ToUpper(arg)
+		if _, ok := stocks.Stocks[ticker]; !ok {
+			bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Тикер %s не найден в базе.", ticker)))
+			return
+		}
+		tickers = append(tickers, ticker)
+	}
+	for _, ticker := range tickers {
+		err := repository.SaveSpikeSubscription(repository.SpikeSubscription{
+			ChatID: chatID, Ticker: ticker, Detector: detector, Threshold: threshold,
+		})
+		if err != nil {
+			log.Printf("Ошибка сохранения подписки: %v", err)
+			bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при сохранении подписки."))
+			return
+		}
+	}
+	bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf(
+		"Подписка оформлена: %s - уведомление, когда детектор %s покажет %.2f%s и более. "+
+			"Уведомления начнут приходить в течение минуты.", strings.Join(tickers, ", "), detector, threshold, detectorUnits[detector])))
+}
+
+// handleUnsubscribe обрабатывает /unsubscribe ТИКЕР... или /unsubscribe all.
+func (bs *BotService) handleUnsubscribe(chatID int64, args []string) {
+	if len(args) == 0 {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Формат: /unsubscribe SBER [GAZP ...] или /unsubscribe all"))
+		return
+	}
+	if strings.EqualFold(args[0], "all") {
+		args = []string{""}
+	}
+	removed := 0
+	for _, arg := range args {
+		n, err := repository.DeleteSpikeSubscriptions(chatID, strings.ToUpper(arg))
+		if err != nil {
+			log.Printf("Ошибка удаления подписки: %v", err)
+			bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при удалении подписки."))
+			return
+		}
+		removed += n
+	}
+	if removed == 0 {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Таких подписок нет. Текущие подписки: /subscribe"))
+		return
+	}
+	bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Удалено подписок: %d.", removed)))
+}
+
+// listSubscriptions показывает подписки чата на резкие изменения.
+func (bs *BotService) listSubscriptions(chatID int64) {
+	subs, err := repository.GetSpikeSubscriptions(chatID)
+	if err != nil {
+		log.Printf("Ошибка получения подписок: %v", err)
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении подписок."))
+		return
+	}
+	usage := "Подписаться: /subscribe SBER GAZP [ПОРОГ] [ДЕТЕКТОР], отписаться: /unsubscribe SBER или /unsubscribe all\n" +
+		"Детекторы: " + strings.Join(detectorNames, ", ") + " (по умолчанию pct - отклонение от средней в %)"
+	if len(subs) == 0 {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Подписок на резкие изменения цены нет.\n"+usage))
+		return
+	}
+	var sb strings.Builder
+	sb.WriteString("Подписки на резкие изменения цены:\n")
+	for _, s := range subs {
+		sb.WriteString(fmt.Sprintf("%s - %s от %.2f%s\n", s.Ticker, s.Detector, s.Threshold, detectorUnits[s.Detector]))
+	}
+	sb.WriteString(usage)
+	bs.bot.Send(tgbotapi.NewMessage(chatID, sb.String()))
+}
@@ -0,0 +1,235 @@
+// TradeTGBot/pkg/bot/ta.go
+package bot
+
+import (
+	"TradeTGBot/internal/indicators"
+	"TradeTGBot/internal/repository"
+	"TradeTGBot/pkg/stocks"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log"
+	"strings"
+	"time"
+)
+
+// Параметры сводки /ta.
+const (
+	defaultTABar    = time.Hour
+	taLookbackBars  = 300                  // За сколько баров (по календарю) берётся история
+	maxTALookback   = 365 * 24 * time.Hour // Но не дальше года
+	tradingDays     = 252                  // Торговых дней в году - для годовой волатильности
+	tradingDayHours = 14                   // Примерная длина торгового дня MOEX с вечерней сессией
+)
+
+const taUsage = "Формат: /ta ТИКЕР [БАР]\n" +
+	"Бар: 5m, 15m, 30m, 1h, 4h, 1d (по умолчанию 1h). Пример: /ta SBER 15m"
+
+// taSignal - направление сигнала индикатора.
+type taSignal int
+
+const (
+	taNeutral taSignal = iota
+	taBullish
+	taBearish
+)
+
+func (s taSignal) String() string {
+	switch s {
+	case taBullish:
+		return "▲ бычий"
+	case taBearish:
+		return "▼ медвежий"
+	default:
+		return "• нейтр."
+	}
+}
+
+// taRow - строка таблицы /ta: индикатор, его значение и сигнал.
+type taRow struct {
+	Name, Value string
+	Signal      taSignal
+}
+
+// handleTA обрабатывает /ta: считает индикаторы по барам тикера и показывает
+// текущие значения с простыми бычьими и медвежьими сигналами.
+func (bs *BotService) handleTA(chatID int64, args []string) {
+	if len(args) == 0 || len(args) > 2 {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, taUsage))
+		return
+	}
+	ticker := strings.ToUpper(args[0])
+	info, ok := stocks.Stocks[ticker]
+	if !ok {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Тикер %s не найден в базе.", ticker)))
+		return
+	}
+	bar := defaultTABar
+	if len(args) == 2 {
+		d, ok := parseChartPeriod(args[1])
+		if !ok || !isChartBar(d) || d < 5*time.Minute {
+			bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Неверный бар %q.\n%s", args[1], taUsage)))
+			return
+		}
+		bar = d
+	}
+
+	lookback := min(bar*taLookbackBars, maxTALookback)
+	now := time.Now()
+	candles, err := repository.GetCandles(ticker, bar, now.Add(-lookback), now)
+	if err != nil {
+		log.Printf("Ошибка получения истории %s для /ta: %v", ticker, err)
+		bs.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении истории цен."))
+		return
+	}
+	if len(candles) < 2 {
+		bs.bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Недостаточно истории %s для расчёта индикаторов.", ticker)))
+		return
+	}
+
+	rows := taReadings(candles, bar)
+	var bullish, bearish int
+	for _, row := range rows {
+		switch row.Signal {
+		case taBullish:
+			bullish++
+		case taBearish:
+			bearish++
+		}
+	}
+	verdict := "нейтральный"
+	switch {
+	case bullish > bearish:
+		verdict = "бычий"
+	case bearish > bullish:
+		verdict = "медвежий"
+	}
+
+	var sb strings.Builder
+	last := candles[len(candles)-1]
+	sb.WriteString(fmt.Sprintf("<b>%s</b> (%s), бар %s, баров: %d\nЦена: %.2f\n\n",
+		ticker, info.Name, formatChartDuration(bar), len(candles), last.Close))
+	sb.WriteString("<pre>")
+	sb.WriteString(fmt.Sprintf("%-12s %-16s %s\n", "Индикатор", "Значение", "Сигнал"))
+	for _, row := range rows {
+		sb.WriteString(fmt.Sprintf("%-12s %-16s %s\n", row.Name, row.Value, row.Signal))
+	}
+	sb.WriteString("</pre>")
+	sb.WriteString(fmt.Sprintf("\nИтог: %s (бычьих %d, медвежьих %d). Не является инвестиционной рекомендацией.",
+		verdict, bullish, bearish))
+
+	msg := tgbotapi.NewMessage(chatID, sb.String())
+	msg.ParseMode = "HTML"
+	bs.bot.Send(msg)
+}
+
+// isChartBar проверяет, что d - одна из допустимых длительностей бара.
+func isChartBar(d time.Duration) bool {
+	for _, bar := range chartBars {
+		if bar == d {
+			return true
+		}
+	}
+	return false
+}
+
+// taReadings рассчитывает индикаторы по барам. Если для индикатора не хватает
+// баров, его значение - "нет данных", а сигнал нейтральный.
+func taReadings(candles []indicators.Candle, bar time.Duration) []taRow {
+	closes := indicators.CandleCloses(candles)
+	price := closes[len(closes)-1]
+	noData := func(name string) taRow { return taRow{Name: name, Value: "нет данных"} }
+	var rows []taRow
+
+	// Цена выше средней - бычий сигнал, ниже - медвежий.
+	for _, ma := range []struct {
+		name string
+		calc func([]float64, int) (float64, error)
+		n    int
+	}{
+		{"SMA20", indicators.SMA, 20},
+		{"SMA50", indicators.SMA, 50},
+		{"EMA20", indicators.EMA, 20},
+	} {
+		v, err := ma.calc(closes, ma.n)
+		if err != nil {
+			rows = append(rows, noData(ma.name))
+			continue
+		}
+		row := taRow{Name: ma.name, Value: fmt.Sprintf("%.2f", v)}
+		if price > v {
+			row.Signal = taBullish
+		} else if price < v {
+			row.Signal = taBearish
+		}
+		rows = append(rows, row)
+	}
+
+	// RSI ниже 30 - перепроданность (бычий), выше 70 - перекупленность (медвежий).
+	if rsi, err := indicators.RSI(closes, 14); err != nil {
+		rows = append(rows, noData("RSI14"))
+	} else {
+		row := taRow{Name: "RSI14", Value: fmt.Sprintf("%.1f", rsi)}
+		if rsi < 30 {
+			row.Signal = taBullish
+		} else if rsi > 70 {
+			row.Signal = taBearish
+		}
+		rows = append(rows, row)
+	}
+
+	// MACD: знак гистограммы (MACD выше или ниже сигнальной линии).
+	if macd, signal, hist, err := indicators.MACD(closes, 12, 26, 9); err != nil {
+		rows = append(rows, noData("MACD"))
+	} else {
+		row := taRow{Name: "MACD", Value: fmt.Sprintf("%.2f/%.2f", macd, signal)}
+		if hist > 0 {
+			row.Signal = taBullish
+		} else if hist < 0 {
+			row.Signal = taBearish
+		}
+		rows = append(rows, row)
+	}
+
+	// Боллинджер: цена ниже нижней полосы - бычий, выше верхней - медвежий.
+	if _, upper, lower, err := indicators.Bollinger(closes, 20, 2); err != nil {
+		rows = append(rows, noData("BB20"))
+	} else {
+		row := taRow{Name: "BB20", Value: fmt.Sprintf("%.2f–%.2f", lower, upper)}
+		if price < lower {
+			row.Signal = taBullish
+		} else if price > upper {
+			row.Signal = taBearish
+		}
+		rows = append(rows, row)
+	}
+
+	// Стохастик: %K ниже 20 - бычий, выше 80 - медвежий.
+	if k, d, err := indicators.Stochastic(candles, 14, 3); err != nil {
+		rows = append(rows, noData("Stoch14"))
+	} else {
+		row := taRow{Name: "Stoch14", Value: fmt.Sprintf("%.1f/%.1f", k, d)}
+		if k < 20 {
+			row.Signal = taBullish
+		} else if k > 80 {
+			row.Signal = taBearish
+		}
+		rows = append(rows, row)
+	}
+
+	// ATR и волатильность направления не показывают - только для справки.
+	if atr, err := indicators.ATR(candles, 14); err != nil {
+		rows = append(rows, noData("ATR14"))
+	} else {
+		rows = append(rows, taRow{Name: "ATR14", Value: fmt.Sprintf("%.2f (%.2f%%)", atr, atr/price*100)})
+	}
+	perYear := float64(tradingDays)
+	if bar < 24*time.Hour {
+		perYear *= float64(tradingDayHours*time.Hour) / float64(bar)
+	}
+	if hv, err := indicators.HistoricalVolatility(closes, 20, perYear); err != nil {
+		rows = append(rows, noData("HV20"))
+	} else {
+		rows = append(rows, taRow{Name: "HV20", Value: fmt.Sprintf("%.1f%% год.", hv)})
+	}
+	return rows
+}
@@ -0,0 +1,80 @@
+// TradeTGBot/pkg/bot/trailing.go
+package bot
+
+import (
+	"TradeTGBot/internal/repository"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// TrailingSpec описывает трейлинг-стоп: оповещение срабатывает, когда цена
+// опускается ниже максимума с момента создания на Percent процентов или на Amount рублей.
+type TrailingSpec struct {
+	Percent float64 `json:"percent,omitempty"`
+	Amount  float64 `json:"amount,omitempty"`
+}
+
+// String возвращает размер отступа стопа, например "3%" или "150.00".
+func (s TrailingSpec) String() string {
+	if s.Percent > 0 {
+		return strconv.FormatFloat(s.Percent, 'f', -1, 64) + "%"
+	}
+	return fmt.Sprintf("%.2f", s.Amount)
+}
+
+// StopLevel возвращает уровень стопа для заданного пика.
+func (s TrailingSpec) StopLevel(peak float64) float64 {
+	if s.Percent > 0 {
+		return peak * (1 - s.Percent/100)
+	}
+	return peak - s.Amount
+}
+
+// parseTrailingSpec разбирает отступ трейлинга: "3%" - в процентах, "150" - в рублях.
+func parseTrailingSpec(arg string) (TrailingSpec, error) {
+	arg = strings.ReplaceAll(arg, ",", ".")
+	if strings.HasSuffix(arg, "%") {
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(arg, "%"), 64)
+		if err != nil || percent <= 0 || percent >= 100 {
+			return TrailingSpec{}, fmt.Errorf("процент трейлинга должен быть числом от 0 до 100")
+		}
+		return TrailingSpec{Percent: percent}, nil
+	}
+	amount, err := strconv.ParseFloat(arg, 64)
+	if err != nil || amount <= 0 {
+		return TrailingSpec{}, fmt.Errorf("отступ трейлинга должен быть положительным числом или процентом (например, 3%%)")
+	}
+	return TrailingSpec{Amount: amount}, nil
+}
+
+// checkTrailingAlert обновляет пик трейлинг-оповещения и формирует уведомление,
+// если цена опустилась до уровня стопа. Новый пик сохраняется в БД,
+// чтобы перезапуск бота не сбрасывал его. Возвращает текст уведомления и true, если оповещение сработало.
+func checkTrailingAlert(alert *Alert, quotes quoteSource) (string, bool) {
+	stock, err := quotes.Get(alert.Ticker)
+	if err != nil {
+		log.Printf("Ошибка проверки трейлинг-оповещения для %s: %v", alert.Ticker, err)
+		return "", false
+	}
+
+	if stock.Price > alert.Peak {
+		alert.Peak = stock.Price
+		// Оповещение без ID не сохранено в БД (прогон истории в бэктесте).
+		if alert.ID != 0 {
+			if err := repository.UpdateAlertPeak(alert.ID, alert.Peak); err != nil {
+				log.Printf("Ошибка сохранения пика трейлинг-оповещения: %v", err)
+			}
+		}
+	}
+
+	stop := alert.Trailing.StopLevel(alert.Peak)
+	if stock.Price > stop {
+		return "", false
+	}
+
+	msgText := fmt.Sprintf("🔻 Трейлинг-стоп %s сработал для %s: цена %.2f опустилась до уровня стопа %.2f (пик %.2f)",
+		alert.Trailing, stock.Name, stock.Price, stop, alert.Peak)
+	return msgText, true
+}
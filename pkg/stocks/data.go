@@ -18,6 +18,7 @@ var Stocks = map[string]StockInfo{
 	"T":        {"T", "https://ru.investing.com/equities/tcs-group-holding-plc", "TCS Group Holding Plc"},
 	"MAGN":     {"MAGN", "https://ru.investing.com/equities/mmk_rts", "ММК"},
 	"SBER":     {"SBER", "https://ru.investing.com/equities/sberbank_rts", "Сбербанк"},
+	"SBERP":    {"SBERP", "https://ru.investing.com/equities/sberbank-p_rts", "Сбербанк (прив.)"},
 	"YDEX":     {"YDEX", "https://ru.investing.com/equities/yandex", "Яндекс"},
 	"MSTT":     {"MSTT", "https://ru.investing.com/equities/mostotrest_rts", "Мостотрест"},
 	"APTK":     {"APTK", "https://ru.investing.com/equities/apteka-36-6_rts", "Аптека-36.6"},
@@ -27,5 +28,6 @@ var Stocks = map[string]StockInfo{
 	"CHMF":     {"CHMF", "https://ru.investing.com/equities/severstal_rts", "Северсталь"},
 	"GAZP":     {"GAZP", "https://ru.investing.com/equities/gazprom_rts", "Газпром"},
 	"SIBN":     {"SIBN", "https://ru.investing.com/equities/gazprom-neft_rts", "Газпром нефть"},
+	"ROSN":     {"ROSN", "https://ru.investing.com/equities/rosneft_rts", "Роснефть"},
 	"BLNG":     {"BLNG", "https://ru.investing.com/equities/belon_rts", "Белон"},
 }